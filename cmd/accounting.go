@@ -0,0 +1,111 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// the resources used by the process and its finished commands, see
+// currentUsage
+type resourceUsage struct {
+	cpu time.Duration
+	// blocks read from and written to the disks
+	diskBlocks int64
+}
+
+// returns the resources used since the earlier usage
+func (u resourceUsage) since(earlier resourceUsage) resourceUsage {
+	return resourceUsage{cpu: u.cpu - earlier.cpu, diskBlocks: u.diskBlocks - earlier.diskBlocks}
+}
+
+// logs the checks which used the most cpu time in the debug output,
+// accounting.top of them
+func logTopChecks(results []checkResult) {
+	top := viper.GetInt("accounting.top")
+	if top <= 0 {
+		return
+	}
+
+	sorted := make([]checkResult, 0, len(results))
+	for _, r := range results {
+		if r.Status != "SKIPPED" && !r.Cached {
+			sorted = append(sorted, r)
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].CpuTime > sorted[j].CpuTime })
+	if len(sorted) > top {
+		sorted = sorted[:top]
+	}
+
+	var lines []string
+	for _, r := range sorted {
+		lines = append(lines, fmt.Sprintf("%s/%s cpu %s wall %s disk blocks %d", r.Category, r.Name, r.CpuTime, r.Duration, r.DiskBlocks))
+	}
+	if len(lines) > 0 {
+		log.Debug("Checks using the most resources:", strings.Join(lines, ", "))
+	}
+}
+
+// writes the wall time, cpu time and disk blocks of the checks as prometheus
+// gauges if accounting.metrics is set. The results of a check with the same
+// category, like the findings of the diagnostics, are summed up, a series
+// must only be written once.
+func writeCheckMetrics(w io.Writer, results []checkResult) {
+	if !viper.GetBool("accounting.metrics") {
+		return
+	}
+
+	var summed []checkResult
+	index := map[string]int{}
+	for _, r := range results {
+		if r.Status == "SKIPPED" || r.Cached {
+			continue
+		}
+		key := r.Category + "/" + r.Name
+		i, ok := index[key]
+		if !ok {
+			index[key] = len(summed)
+			summed = append(summed, checkResult{Name: r.Name, Category: r.Category})
+			i = len(summed) - 1
+		}
+		summed[i].Duration += r.Duration
+		summed[i].CpuTime += r.CpuTime
+		summed[i].DiskBlocks += r.DiskBlocks
+	}
+
+	metrics := []struct {
+		name  string
+		help  string
+		value func(r checkResult) string
+	}{
+		{"openshift_monitoring_check_duration_seconds", "Wall time of the check in the latest run.", func(r checkResult) string { return fmt.Sprint(r.Duration.Seconds()) }},
+		{"openshift_monitoring_check_cpu_seconds", "CPU time of the check and its commands in the latest run.", func(r checkResult) string { return fmt.Sprint(r.CpuTime.Seconds()) }},
+		{"openshift_monitoring_check_disk_blocks", "Blocks the check and its commands read from and wrote to the disks in the latest run.", func(r checkResult) string { return fmt.Sprint(r.DiskBlocks) }},
+	}
+	for _, m := range metrics {
+		fmt.Fprintf(w, "# HELP %s %s\n", m.name, m.help)
+		fmt.Fprintf(w, "# TYPE %s gauge\n", m.name)
+		for _, r := range summed {
+			fmt.Fprintf(w, "%s{node=%q,check=%q,category=%q} %s\n", m.name, nodeName(), r.Name, r.Category, m.value(r))
+		}
+	}
+}
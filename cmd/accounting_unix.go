@@ -0,0 +1,38 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !windows
+// +build !windows
+
+package cmd
+
+import (
+	"syscall"
+	"time"
+)
+
+// returns the resources used so far, the checks run one after the other so
+// the difference around a check is what it used
+func currentUsage() resourceUsage {
+	var self, children syscall.Rusage
+	syscall.Getrusage(syscall.RUSAGE_SELF, &self)
+	syscall.Getrusage(syscall.RUSAGE_CHILDREN, &children)
+
+	var usage resourceUsage
+	for _, r := range []syscall.Rusage{self, children} {
+		usage.cpu += time.Duration(r.Utime.Nano() + r.Stime.Nano())
+		usage.diskBlocks += int64(r.Inblock + r.Oublock)
+	}
+	return usage
+}
@@ -0,0 +1,71 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/oscp/openshift-monitoring-cli/probes"
+	"github.com/spf13/viper"
+)
+
+// the latest backup of a backup check and the counts of its objects
+type latestBackup struct {
+	backup probes.Backup
+	counts map[string]int
+}
+
+// runs a backup check like etcd-backup with the settings below key, e.g.
+// etcdBackup.path. The latest backup is looked up once and its age is
+// compared with the major and minor thresholds of the check in hours. The
+// objects of the backup are counted if objects is set.
+func evalBackup(ctx context.Context, name string, key string, label string, objects bool) {
+	minSize := int64(viper.GetInt(key+".minSize")) * 1024 * 1024
+
+	evalLevels(ctx, levelCheck{
+		name:      name,
+		threshold: thresholdDisplay(name, "h"),
+		measure: func(ctx context.Context) (interface{}, error) {
+			var latest latestBackup
+			var err error
+			switch {
+			case len(viper.GetString(key+".url")) > 0:
+				latest.backup, err = probes.RemoteBackup(ctx, viper.GetString(key+".url"), viper.GetString(key+".token"))
+			case len(viper.GetString(key+".path")) > 0:
+				latest.backup, err = probes.LatestBackup(viper.GetString(key + ".path"))
+			default:
+				err = fmt.Errorf("No %s backup location, set %s.path or %s.url", label, key, key)
+			}
+			if err == nil && objects {
+				latest.counts, err = probes.CountBackupObjects(ctx, latest.backup.Location, viper.GetString(key+".token"))
+			}
+			return latest, err
+		},
+		evaluate: func(ctx context.Context, measurement interface{}, category string) error {
+			latest, ok := measurement.(latestBackup)
+			if !ok {
+				return unexpectedMeasurement(name, measurement)
+			}
+			maxAge := time.Duration(threshold(name, category)) * time.Hour
+			if err := probes.CheckBackup(label, latest.backup, maxAge, minSize); err != nil || !objects {
+				return err
+			}
+			return probes.CheckBackupObjects(label, latest.backup.Location, latest.counts, viper.GetInt(key+".minObjects"), strings.Split(viper.GetString(key+".kinds"), ","))
+		},
+	})
+}
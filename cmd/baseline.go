@@ -0,0 +1,122 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/oscp/openshift-monitoring-cli/probes"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var baselinePath string
+
+var baselineCmd = &cobra.Command{
+	Use:   "baseline",
+	Short: "Saves and checks a known-good snapshot of the host configuration.",
+	Long: `The baseline contains the installed packages, the sysctls of baseline.sysctls, the
+mounted file systems and the fingerprints of the certificates of baseline.certificates.
+With baseline.enabled the check baseline-drift compares the host with it on every run.`,
+}
+
+var baselineSaveCmd = &cobra.Command{
+	Use:   "save",
+	Short: "Saves the current state of the host as baseline.",
+	RunE:  runBaselineSave,
+}
+
+var baselineCheckCmd = &cobra.Command{
+	Use:   "check",
+	Short: "Lists the differences of the host to the baseline.",
+	RunE:  runBaselineCheck,
+}
+
+func init() {
+	rootCmd.AddCommand(baselineCmd)
+	baselineCmd.AddCommand(baselineSaveCmd)
+	baselineCmd.AddCommand(baselineCheckCmd)
+
+	baselineCmd.PersistentFlags().StringVar(&baselinePath, "path", "", "baseline file (default baseline.path or baseline.json in store.path)")
+}
+
+// returns the path of the baseline from --path, baseline.path or store.path
+func baselineFile() (string, error) {
+	switch {
+	case len(baselinePath) > 0:
+		return baselinePath, nil
+	case len(viper.GetString("baseline.path")) > 0:
+		return viper.GetString("baseline.path"), nil
+	case len(viper.GetString("store.path")) > 0:
+		return filepath.Join(viper.GetString("store.path"), "baseline.json"), nil
+	}
+	return "", errors.New("No baseline file, set --path, baseline.path or store.path.")
+}
+
+func baselineOptions() probes.BaselineOptions {
+	return probes.BaselineOptions{
+		Sysctls:      strings.Split(viper.GetString("baseline.sysctls"), ","),
+		Certificates: strings.Split(viper.GetString("baseline.certificates"), ","),
+	}
+}
+
+func runBaselineSave(cmd *cobra.Command, args []string) error {
+	path, err := baselineFile()
+	if err != nil {
+		return err
+	}
+
+	baseline, err := probes.CollectBaseline(context.Background(), baselineOptions())
+	if err != nil {
+		return err
+	}
+	if err := probes.SaveBaseline(path, baseline); err != nil {
+		return err
+	}
+
+	fmt.Println("Saved baseline to", path)
+	return nil
+}
+
+func runBaselineCheck(cmd *cobra.Command, args []string) error {
+	path, err := baselineFile()
+	if err != nil {
+		return err
+	}
+
+	baseline, err := probes.LoadBaseline(path)
+	if err != nil {
+		return err
+	}
+	current, err := probes.CollectBaseline(context.Background(), baselineOptions())
+	if err != nil {
+		return err
+	}
+
+	drift := probes.BaselineDrift(baseline, current)
+	for _, d := range drift {
+		fmt.Println(d)
+	}
+	if len(drift) > 0 {
+		return fmt.Errorf("Host drifted from the baseline of %s in %d fact(s).", baseline.Time.Format("2006-01-02"), len(drift))
+	}
+
+	fmt.Println("No drift from the baseline of", baseline.Time.Format("2006-01-02"))
+	return nil
+}
@@ -0,0 +1,200 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/oscp/openshift-monitoring-cli/probes"
+	"github.com/spf13/viper"
+)
+
+// the circuit of a check which keeps failing in the daemon. A check which
+// failed for breaker.openAfter minutes opens its circuit: it is only
+// probed again after breaker.backoff minutes, doubled after every failed
+// probe up to breaker.maxBackoff, and its last failure is reported in the
+// runs in between. Its event is added once per breaker.reportWindow, so a
+// decommissioned dependency doesn't add the same event every run. The runs
+// in between add an INFO event listing such checks instead.
+type circuit struct {
+	FailingSince time.Time `json:"failing_since"`
+	// zero while the circuit is closed
+	OpenSince    time.Time `json:"open_since,omitempty"`
+	Probes       int       `json:"probes,omitempty"`
+	NextProbe    time.Time `json:"next_probe,omitempty"`
+	LastReported time.Time `json:"last_reported,omitempty"`
+	// the failure of the last probe
+	MessageID string `json:"message_id,omitempty"`
+	Summary   string `json:"summary"`
+}
+
+// the circuits of the failing checks by category and name
+var circuits map[string]*circuit
+
+// set by the daemon, the circuits only open between its runs
+var runsAsDaemon bool
+
+// the failed checks of the current run whose events were left out as their
+// circuit is open
+var unreportedCircuits []string
+
+// returns if the circuits are used in the current run, a run of targeted
+// checks probes them regardless
+func breakerEnabled() bool {
+	return runsAsDaemon && viper.GetBool("breaker.enabled") && len(targetedChecks) == 0
+}
+
+// returns the circuit of the check if it is open
+func openCircuit(name string, category string) *circuit {
+	if !breakerEnabled() {
+		return nil
+	}
+	if c, ok := circuits[category+"/"+name]; ok && !c.OpenSince.IsZero() {
+		return c
+	}
+	return nil
+}
+
+// returns the last failure of a check whose circuit is open and which isn't
+// probed in this run, so it is reported again instead of running the check
+func circuitResult(name string, category string) (error, bool) {
+	c := openCircuit(name, category)
+	if c == nil || !time.Now().Before(c.NextProbe) {
+		return nil, false
+	}
+
+	log.Debug("Not probing", name, "until", c.NextProbe.Format("15:04:05"), "as its circuit is open")
+	if len(c.MessageID) > 0 {
+		return &probes.Message{ID: c.MessageID, Text: c.Summary}, true
+	}
+	return errors.New(c.Summary), true
+}
+
+// records the result of a check in its circuit and returns if its failure
+// is reported with an event. probed is false for a result which was only
+// reported again.
+func recordCircuit(name string, category string, err error, probed bool) bool {
+	if !breakerEnabled() {
+		return true
+	}
+
+	key := category + "/" + name
+	if err == nil {
+		if c, ok := circuits[key]; ok && !c.OpenSince.IsZero() {
+			log.Info("Closing the circuit of", name, "open since", c.OpenSince.Format(time.RFC3339))
+		}
+		delete(circuits, key)
+		return true
+	}
+
+	now := time.Now()
+	c, ok := circuits[key]
+	if !ok {
+		c = &circuit{FailingSince: now}
+		circuits[key] = c
+	}
+	if probed {
+		c.MessageID = messageID(name, err)
+		c.Summary = summarize(err.Error())
+		switch {
+		case !c.OpenSince.IsZero():
+			c.Probes++
+			c.NextProbe = now.Add(circuitBackoff(c.Probes))
+		case now.Sub(c.FailingSince) >= configMinutes("breaker.openAfter"):
+			log.Warning("Opening the circuit of", name, "failing since", c.FailingSince.Format(time.RFC3339))
+			c.OpenSince = now
+			c.NextProbe = now.Add(circuitBackoff(0))
+		}
+	}
+
+	if c.OpenSince.IsZero() {
+		return true
+	}
+	if now.Sub(c.LastReported) < configMinutes("breaker.reportWindow") {
+		log.Info("Not reporting", name, "again, its circuit is open since", c.OpenSince.Format(time.RFC3339))
+		return false
+	}
+	c.LastReported = now
+	return true
+}
+
+// returns breaker.backoff doubled for every failed probe, at most
+// breaker.maxBackoff
+func circuitBackoff(failedProbes int) time.Duration {
+	backoff := configMinutes("breaker.backoff")
+	for i := 0; i < failedProbes && backoff < configMinutes("breaker.maxBackoff"); i++ {
+		backoff *= 2
+	}
+	if max := configMinutes("breaker.maxBackoff"); backoff > max {
+		return max
+	}
+	return backoff
+}
+
+// returns the setting in minutes as duration
+func configMinutes(key string) time.Duration {
+	return time.Duration(viper.GetInt(key)) * time.Minute
+}
+
+func circuitFile() string {
+	if dir := viper.GetString("store.path"); len(dir) > 0 {
+		return filepath.Join(dir, "circuits.json")
+	}
+	return ""
+}
+
+// loads the circuits of the previous runs once
+func planCircuits() {
+	if circuits != nil || !breakerEnabled() {
+		return
+	}
+	circuits = map[string]*circuit{}
+
+	file := circuitFile()
+	if len(file) == 0 {
+		return
+	}
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Warning("Not able to read circuits", err)
+		}
+		return
+	}
+	if err := json.Unmarshal(content, &circuits); err != nil {
+		log.Warning("Not able to parse circuits", err)
+	}
+}
+
+// persists the circuits, so a restart of the daemon doesn't close them
+func saveCircuits() {
+	file := circuitFile()
+	if circuits == nil || len(file) == 0 {
+		return
+	}
+	content, err := json.Marshal(circuits)
+	if err != nil {
+		log.Error("Not able to serialize circuits", err)
+		return
+	}
+	if err := ioutil.WriteFile(file, content, 0640); err != nil {
+		log.Error("Not able to save circuits", err)
+	}
+}
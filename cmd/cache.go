@@ -0,0 +1,135 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/oscp/openshift-monitoring-cli/probes"
+	"github.com/spf13/viper"
+)
+
+// the cached outcome of a check, an empty summary means it was healthy
+type cachedResult struct {
+	Time    time.Time `json:"time"`
+	Summary string    `json:"summary,omitempty"`
+	// keeps the message id of the summary for the events
+	MessageID string `json:"message_id,omitempty"`
+}
+
+// cached results by check name and category, persisted in the result store
+var resultCache map[string]cachedResult
+
+// returns how long results of a check are cached, configured in seconds as
+// cache.ttl.<check>
+func cacheTTL(name string) time.Duration {
+	return time.Duration(viper.GetInt("cache.ttl."+strings.SplitN(name, ":", 2)[0])) * time.Second
+}
+
+// runs fn unless a result younger than the ttl of the check is cached.
+// Reports if the returned error comes from the cache.
+func runCached(category string, name string, fn func() error) (bool, error) {
+	ttl := cacheTTL(name)
+	// targeted runs are used to confirm a fix and always run the check
+	if ttl <= 0 || len(targetedChecks) > 0 {
+		return false, fn()
+	}
+
+	if resultCache == nil {
+		loadCache()
+	}
+
+	key := category + "/" + name
+	if cached, ok := resultCache[key]; ok && time.Since(cached.Time) < ttl {
+		log.Debug("Using cached result of", name, "from", cached.Time)
+		if len(cached.Summary) > 0 && len(cached.MessageID) > 0 {
+			return true, &probes.Message{ID: cached.MessageID, Text: cached.Summary}
+		}
+		if len(cached.Summary) > 0 {
+			return true, errors.New(cached.Summary)
+		}
+		return true, nil
+	}
+
+	err := fn()
+	if err == errAborted {
+		return false, err
+	}
+
+	var cached = cachedResult{Time: time.Now()}
+	if err != nil {
+		cached.Summary = err.Error()
+		cached.MessageID = probes.MessageID(err)
+	}
+	resultCache[key] = cached
+
+	return false, err
+}
+
+func cacheFile() string {
+	if dir := viper.GetString("store.path"); len(dir) > 0 {
+		return filepath.Join(dir, "cache.json")
+	}
+	return ""
+}
+
+func loadCache() {
+	resultCache = map[string]cachedResult{}
+
+	file := cacheFile()
+	if len(file) == 0 {
+		return
+	}
+
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Warning("Not able to read result cache", err)
+		}
+		return
+	}
+	if err := json.Unmarshal(content, &resultCache); err != nil {
+		log.Warning("Not able to parse result cache", err)
+		resultCache = map[string]cachedResult{}
+	}
+}
+
+// persists the cache if it was used in this run
+func saveCache() {
+	file := cacheFile()
+	if resultCache == nil || len(file) == 0 {
+		return
+	}
+
+	content, err := json.Marshal(resultCache)
+	if err != nil {
+		log.Error("Not able to serialize result cache", err)
+		return
+	}
+
+	if err := os.MkdirAll(filepath.Dir(file), 0750); err != nil {
+		log.Error("Not able to create result store", err)
+		return
+	}
+	if err := ioutil.WriteFile(file, content, 0640); err != nil {
+		log.Error("Not able to save result cache", err)
+	}
+}
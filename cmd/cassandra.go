@@ -0,0 +1,54 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+
+	"github.com/oscp/openshift-monitoring-cli/probes"
+)
+
+// runs the compaction and heap checks of the metrics cassandra, nodetool is
+// run once per pod for both. A degraded cassandra loses metrics while the
+// hawkular health check still passes.
+func evalCassandraHealth(ctx context.Context, c probes.Component) {
+	measure := func(ctx context.Context) (interface{}, error) {
+		return probes.ReadCassandraStats(ctx, c)
+	}
+
+	evalLevels(ctx, levelCheck{
+		name:      "cassandra-compactions",
+		threshold: thresholdDisplay("cassandra-compactions", ""),
+		measure:   measure,
+		evaluate: func(ctx context.Context, stats interface{}, category string) error {
+			s, ok := stats.([]probes.CassandraStats)
+			if !ok {
+				return unexpectedMeasurement("cassandra-compactions", stats)
+			}
+			return probes.CheckCassandraCompactions(s, threshold("cassandra-compactions", category))
+		},
+	}, levelCheck{
+		name:      "cassandra-heap",
+		threshold: thresholdDisplay("cassandra-heap", "%"),
+		measure:   measure,
+		evaluate: func(ctx context.Context, stats interface{}, category string) error {
+			s, ok := stats.([]probes.CassandraStats)
+			if !ok {
+				return unexpectedMeasurement("cassandra-heap", stats)
+			}
+			return probes.CheckCassandraHeap(s, threshold("cassandra-heap", category))
+		},
+	})
+}
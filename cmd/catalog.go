@@ -0,0 +1,305 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"strings"
+
+	"github.com/oscp/openshift-monitoring-cli/probes"
+	"github.com/spf13/viper"
+)
+
+// describes a check and how it is run, the name matches the name the
+// results and events of the check are reported with
+type checkInfo struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	NodeTypes   []string       `json:"node_types"`
+	Tags        []string       `json:"tags"`
+	Severities  []severityInfo `json:"severities"`
+	Params      []string       `json:"params,omitempty"`
+	// checks which must not have failed in the same run, otherwise the
+	// check is skipped as its failure would only be a symptom
+	DependsOn []string `json:"depends_on,omitempty"`
+	// what the check does besides reading, see capabilityCreate and
+	// capabilityExec. Such checks are skipped in the read-only mode.
+	Capabilities []string `json:"capabilities,omitempty"`
+	// the api access of the check, see the rbac command
+	Rules []policyRule `json:"rules,omitempty"`
+	// external commands the check runs, it is skipped if one is missing
+	Commands []string `json:"commands,omitempty"`
+	// the failure modes of the check, see checkErrorCodes
+	Errors []errorCode `json:"errors,omitempty"`
+}
+
+const (
+	// creates resources in the cluster, like pods or tokens
+	capabilityCreate = "create"
+	// executes commands in pods
+	capabilityExec = "exec"
+)
+
+type severityInfo struct {
+	Category  string `json:"category"`
+	Threshold string `json:"threshold,omitempty"`
+}
+
+var (
+	onAll            = []string{"master", "node", "infra", "storage", "lb", "etcd"}
+	onMaster         = []string{"master"}
+	onNode           = []string{"node", "infra"}
+	onStorage        = []string{"storage"}
+	onLb             = []string{"lb"}
+	onEtcd           = []string{"etcd"}
+	onNodeAndStorage = []string{"node", "infra", "storage"}
+	onMasterAndEtcd  = []string{"master", "etcd"}
+	onMasterAndNode  = []string{"master", "node", "infra"}
+	onMasterAndInfra = []string{"master", "infra"}
+	majorOnly        = []severityInfo{{Category: "MAJOR"}}
+	minorOnly        = []severityInfo{{Category: "MINOR"}}
+	dependsOnApi     = []string{"master-apis"}
+)
+
+// all checks of the cli, keep in sync with runChecks. The thresholds are
+// the defaults, see defaultThresholds.
+var checkCatalog = []checkInfo{
+	{Name: "glusterd", Description: "glusterd is running", NodeTypes: onStorage, Tags: []string{"storage"}, Severities: majorOnly, Commands: []string{"gluster"}},
+	{Name: "mount-point-sizes", Description: "Usage of the mount points", NodeTypes: onStorage, Tags: []string{"storage"},
+		Severities: []severityInfo{{"MAJOR", "90%"}, {"MINOR", "85%"}}, Params: thresholdParams("mount-point-sizes")},
+	{Name: "lv-pool-sizes", Description: "Data and metadata usage of the LVM thin pools", NodeTypes: onStorage, Tags: []string{"storage"},
+		Severities: []severityInfo{{"MAJOR", "90% data, 80% metadata"}, {"MINOR", "80% data, 70% metadata"}}, Params: append(thresholdParams("lv-pool-sizes"), thresholdParams("lv-pool-metadata")...), Commands: []string{"lvs"}},
+	{Name: "vg-sizes", Description: "Free space of the volume groups", NodeTypes: onStorage, Tags: []string{"storage"},
+		Severities: []severityInfo{{"MAJOR", "5% free"}, {"MINOR", "10% free"}}, Params: thresholdParams("vg-sizes"), Commands: []string{"vgs"}},
+	{Name: "open-file-count", Description: "Number of open files", NodeTypes: onStorage, Tags: []string{"os"}, Severities: minorOnly},
+	{Name: "docker-pool", Description: "Usage of the docker storage pool", NodeTypes: onNode, Tags: []string{"storage"},
+		Severities: []severityInfo{{"MAJOR", "90%"}, {"MINOR", "80%"}}, Params: thresholdParams("docker-pool"), Commands: []string{"lvs"}},
+	{Name: "geo-replication", Description: "Geo-replication sessions of the volumes are not faulty and synced recently, reported as geo-replication:<volume>", NodeTypes: onStorage, Tags: []string{"storage"},
+		Severities: []severityInfo{{"MAJOR", "6h0m0s lag"}, {"MINOR", "1h0m0s lag"}}, Params: append(thresholdParams("geo-replication"), "geoReplication.enabled", "geoReplication.volumes", "geoReplication.maxLag"), Commands: []string{"gluster"}},
+	{Name: "haproxy", Description: "haproxy is running and answers on its stats socket", NodeTypes: onLb, Tags: []string{"network"}, Severities: majorOnly, Params: []string{"haproxy.socket"}},
+	{Name: "haproxy-sessions", Description: "Current connections of haproxy in percent of maxconn", NodeTypes: onLb, Tags: []string{"network"},
+		Severities: []severityInfo{{"MAJOR", "90%"}, {"MINOR", "75%"}}, Params: append(thresholdParams("haproxy-sessions"), "haproxy.socket"), DependsOn: []string{"haproxy"}},
+	{Name: "haproxy-backends", Description: "Servers of the haproxy backends are up, major if a backend has none up", NodeTypes: onLb, Tags: []string{"network"},
+		Severities: []severityInfo{{Category: "MAJOR"}, {Category: "MINOR"}}, Params: []string{"haproxy.socket"}, DependsOn: []string{"haproxy"}},
+	{Name: "dns-nslookup-kubernetes", Description: "kubernetes.default.svc can be resolved", NodeTypes: onMasterAndNode, Tags: []string{"network"}, Severities: majorOnly, Commands: []string{"nslookup"}},
+	{Name: "dns-service-node", Description: "The node local dns service answers", NodeTypes: onMasterAndNode, Tags: []string{"network"}, Severities: majorOnly, Commands: []string{"nslookup"}},
+	{Name: "http-service", Description: "A service can be called over http", NodeTypes: onMasterAndNode, Tags: []string{"network"}, Severities: minorOnly, DependsOn: []string{"dns-service-node"}},
+	{Name: "kubeconfig-expiry", Description: "Expiry of certificates and tokens embedded in kubeconfigs", NodeTypes: onMasterAndNode, Tags: []string{"certs"},
+		Severities: []severityInfo{{"MAJOR", "7 days"}, {"MINOR", "30 days"}}, Params: append(thresholdParams("kubeconfig-expiry"), "kubeconfigs.paths")},
+	{Name: "etcd-health", Description: "Health of the etcd cluster", NodeTypes: onMaster, Tags: []string{"control-plane"}, Severities: majorOnly, Params: []string{"etcd.ips"}, Commands: []string{"etcdctl"}},
+	{Name: "master-apis", Description: "The master api answers", NodeTypes: onMaster, Tags: []string{"control-plane"}, Severities: majorOnly, Params: []string{"master.url"},
+		DependsOn: []string{"etcd-health"}},
+	{Name: "oc-get-nodes", Description: "All nodes are ready", NodeTypes: onMaster, Tags: []string{"control-plane"}, Severities: majorOnly, DependsOn: dependsOnApi,
+		Rules: []policyRule{readNodes}, Commands: []string{"oc"}},
+	{Name: "registry-health", Description: "Health of the integrated registry", NodeTypes: onMasterAndInfra, Tags: []string{"infra"}, Severities: majorOnly, Params: []string{"registry.ip"},
+		DependsOn: dependsOnApi},
+	{Name: "router-health", Description: "Health of every router, reported as router-health:<ip>", NodeTypes: onMasterAndInfra, Tags: []string{"network", "infra"}, Severities: majorOnly,
+		Params: []string{"router.ips"}, DependsOn: dependsOnApi},
+	{Name: "anonymous-access", Description: "Anonymous access to sensitive api endpoints is denied", NodeTypes: onMaster, Tags: []string{"security", "control-plane"}, Severities: majorOnly,
+		Params: []string{"master.url", "anonymousAccess.paths"}, DependsOn: dependsOnApi},
+	{Name: "oauth-login", Description: "The oauth server is healthy and the test user can log in", NodeTypes: onMaster, Tags: []string{"deep", "auth", "control-plane"}, Severities: majorOnly,
+		Params: []string{"oauthLogin.enabled", "oauthLogin.url", "master.url", "oauthLogin.username", "oauthLogin.password"}, DependsOn: dependsOnApi,
+		Capabilities: []string{capabilityCreate}},
+	{Name: "cassandra-storage", Description: "Usage of the persistent volumes of the metrics cassandra", NodeTypes: onMasterAndInfra, Tags: []string{"metrics", "storage"},
+		Severities: []severityInfo{{"MAJOR", "90%"}, {"MINOR", "80%"}}, Params: append(thresholdParams("cassandra-storage"), "storageComponents.enabled", "storageComponents.cassandra.namespace", "storageComponents.cassandra.selector", "storageComponents.cassandra.path"), DependsOn: dependsOnApi,
+		Capabilities: []string{capabilityExec},
+		Rules:        []policyRule{readPods, execPods}, Commands: []string{"oc"}},
+	{Name: "cassandra-restarts", Description: "The metrics cassandra pods are not in a restart loop", NodeTypes: onMasterAndInfra, Tags: []string{"metrics"},
+		Severities: []severityInfo{{"MAJOR", "10"}, {"MINOR", "3"}}, Params: append(thresholdParams("cassandra-restarts"), "storageComponents.enabled", "storageComponents.cassandra.namespace", "storageComponents.cassandra.selector"), DependsOn: dependsOnApi,
+		Rules: []policyRule{readPods}},
+	{Name: "cassandra-compactions", Description: "The metrics cassandra is not behind on compactions", NodeTypes: onMasterAndInfra, Tags: []string{"deep", "metrics"},
+		Severities: []severityInfo{{"MAJOR", "100"}, {"MINOR", "30"}}, Params: append(thresholdParams("cassandra-compactions"), "storageComponents.enabled", "storageComponents.cassandra.namespace", "storageComponents.cassandra.selector"), DependsOn: dependsOnApi,
+		Capabilities: []string{capabilityExec},
+		Rules:        []policyRule{readPods, execPods}, Commands: []string{"oc"}},
+	{Name: "cassandra-heap", Description: "The heap of the metrics cassandra is not exhausted", NodeTypes: onMasterAndInfra, Tags: []string{"deep", "metrics"},
+		Severities: []severityInfo{{"MAJOR", "95%"}, {"MINOR", "85%"}}, Params: append(thresholdParams("cassandra-heap"), "storageComponents.enabled", "storageComponents.cassandra.namespace", "storageComponents.cassandra.selector"), DependsOn: dependsOnApi,
+		Capabilities: []string{capabilityExec},
+		Rules:        []policyRule{readPods, execPods}, Commands: []string{"oc"}},
+	{Name: "elasticsearch-storage", Description: "Usage of the persistent volumes of the logging elasticsearch", NodeTypes: onMasterAndInfra, Tags: []string{"logging", "storage"},
+		Severities: []severityInfo{{"MAJOR", "85%"}, {"MINOR", "75%"}}, Params: append(thresholdParams("elasticsearch-storage"), "storageComponents.enabled", "storageComponents.elasticsearch.namespace", "storageComponents.elasticsearch.selector", "storageComponents.elasticsearch.path"), DependsOn: dependsOnApi,
+		Capabilities: []string{capabilityExec},
+		Rules:        []policyRule{readPods, execPods}, Commands: []string{"oc"}},
+	{Name: "elasticsearch-restarts", Description: "The logging elasticsearch pods are not in a restart loop", NodeTypes: onMasterAndInfra, Tags: []string{"logging"},
+		Severities: []severityInfo{{"MAJOR", "10"}, {"MINOR", "3"}}, Params: append(thresholdParams("elasticsearch-restarts"), "storageComponents.enabled", "storageComponents.elasticsearch.namespace", "storageComponents.elasticsearch.selector"), DependsOn: dependsOnApi,
+		Rules: []policyRule{readPods}},
+	{Name: "api-load-balancer", Description: "The load balancer of the master api serves all healthy masters", NodeTypes: onMaster, Tags: []string{"control-plane", "network"},
+		Severities: majorOnly, Params: []string{"apiLoadBalancer.url", "apiLoadBalancer.masters", "apiLoadBalancer.requests"}, DependsOn: dependsOnApi},
+	{Name: "webconsole", Description: "The web console and its assets are served through the routers within the latency", NodeTypes: onMaster, Tags: []string{"infra"},
+		Severities: []severityInfo{{"MAJOR", "10000ms"}, {"MINOR", "3000ms"}}, Params: append(thresholdParams("webconsole"), "webconsole.enabled", "webconsole.url", "webconsole.assets", "router.ips"), DependsOn: []string{"router-health"}},
+	{Name: "canary-app", Description: "A canary pod, service and route can be deployed and called", NodeTypes: onMaster, Tags: []string{"deep", "network", "infra"}, Severities: majorOnly,
+		Params: []string{"features.canary", "canary.namespace", "canary.image", "canary.timeout"}, DependsOn: []string{"master-apis", "router-health"},
+		Capabilities: []string{capabilityCreate},
+		Rules:        []policyRule{readProjects, manageCanary, manageRoutes}, Commands: []string{"oc"}},
+	{Name: "diagnostics", Description: "Errors and warnings of oc adm diagnostics", NodeTypes: onMaster, Tags: []string{"deep", "control-plane"}, Severities: []severityInfo{{Category: "MAJOR"}, {Category: "MINOR"}},
+		Params: []string{"features.diagnostics", "diagnostics.modules", "diagnostics.timeout"}, DependsOn: dependsOnApi,
+		Capabilities: []string{capabilityCreate},
+		Rules:        []policyRule{allResources}, Commands: []string{"oc"}},
+	{Name: "external-system", Description: "An external system can be reached", NodeTypes: onMaster, Tags: []string{"network"}, Severities: minorOnly, Params: []string{"externalSystem.url"}},
+	{Name: "hawcular-health", Description: "Health of hawkular metrics", NodeTypes: onMasterAndInfra, Tags: []string{"metrics"}, Severities: minorOnly, Params: []string{"hawkular.ip"}},
+	{Name: "router-restart-count", Description: "Restarts of the routers", NodeTypes: onMasterAndInfra, Tags: []string{"infra"}, Severities: minorOnly, DependsOn: dependsOnApi,
+		Rules: []policyRule{readPods}, Commands: []string{"oc"}},
+	{Name: "limits-and-quotas", Description: "Projects without limits and quotas", NodeTypes: onMaster, Tags: []string{"control-plane"}, Severities: minorOnly,
+		Params: []string{"limitsAndQuotas.projectsWithoutLimits"}, DependsOn: dependsOnApi,
+		Rules: []policyRule{readQuotas}, Commands: []string{"oc"}},
+	{Name: "logging-restart-count", Description: "Restarts of the logging pods", NodeTypes: onMasterAndInfra, Tags: []string{"logging"}, Severities: minorOnly, DependsOn: dependsOnApi,
+		Rules: []policyRule{readPods}, Commands: []string{"oc"}},
+	{Name: "privileged-sccs", Description: "Privileged sccs are only granted to allowed namespaces", NodeTypes: onMaster, Tags: []string{"security"}, Severities: minorOnly,
+		Params: []string{"security.sccAllowedNamespaces"}, DependsOn: dependsOnApi,
+		Rules: []policyRule{readSccs}},
+	{Name: "cluster-admin-service-accounts", Description: "Only allowed service accounts and groups have cluster-admin", NodeTypes: onMaster, Tags: []string{"security"}, Severities: minorOnly,
+		Params: []string{"security.clusterAdminAllowed"}, DependsOn: dependsOnApi,
+		Rules: []policyRule{readBindings}},
+	{Name: "audit-log", Description: "The api audit log is written", NodeTypes: onMaster, Tags: []string{"security"}, Severities: minorOnly,
+		Params: []string{"audit.logPath", "audit.maxAge"}},
+	{Name: "ldap-sync", Description: "Groups were synced from ldap recently and the ldap server accepts the bind credentials", NodeTypes: onMaster, Tags: []string{"auth"},
+		Severities: []severityInfo{{"MINOR", "24h"}}, Params: []string{"ldapSync.enabled", "ldapSync.url", "ldapSync.bindDN", "ldapSync.bindPassword", "ldapSync.insecure", "ldapSync.maxAge"}, DependsOn: dependsOnApi,
+		Rules: []policyRule{readGroups}},
+	{Name: "router-restarts-rate", Description: "Growth of the router restarts within the window", NodeTypes: onMasterAndInfra, Tags: []string{"infra", "rate"},
+		Severities: []severityInfo{{"MAJOR", "+10/60m"}, {"MINOR", "+3/60m"}}, Params: append(thresholdParams("router-restarts-rate"), "rates.enabled", "rates.router-restarts-rate.window"), DependsOn: dependsOnApi,
+		Rules: []policyRule{readPods}},
+	{Name: "etcd-db-size-rate", Description: "Growth of the etcd database within the window", NodeTypes: onMaster, Tags: []string{"control-plane", "rate"},
+		Severities: []severityInfo{{"MAJOR", "+500MB/60m"}, {"MINOR", "+200MB/60m"}}, Params: append(thresholdParams("etcd-db-size-rate"), "rates.enabled", "rates.etcd-db-size-rate.window")},
+	{Name: "docker-pool-rate", Description: "Growth of the docker storage pool usage within the window", NodeTypes: onNode, Tags: []string{"storage", "rate"},
+		Severities: []severityInfo{{"MAJOR", "+20%/60m"}, {"MINOR", "+10%/60m"}}, Params: append(thresholdParams("docker-pool-rate"), "rates.enabled", "rates.docker-pool-rate.window"), Commands: []string{"lvs"}},
+	{Name: "kernel-slab-rate", Description: "Growth of the unreclaimable slab memory of the kernel within the window", NodeTypes: onMasterAndNode, Tags: []string{"os", "rate"},
+		Severities: []severityInfo{{"MAJOR", "+2048MB/1440m"}, {"MINOR", "+512MB/1440m"}}, Params: append(thresholdParams("kernel-slab-rate"), "rates.enabled", "rates.kernel-slab-rate.window")},
+	{Name: "clock-skew", Description: "The clocks of the masters and etcd members are within the threshold of each other", NodeTypes: onMaster, Tags: []string{"control-plane", "os"},
+		Severities: []severityInfo{{"MAJOR", "1s"}, {"MINOR", "200ms"}}, Params: append(thresholdParams("clock-skew"), "clockSkew.enabled", "clockSkew.hosts", "etcd.ips")},
+	{Name: "etcd-member-health", Description: "The etcd members report themselves healthy", NodeTypes: onEtcd, Tags: []string{"control-plane"}, Severities: majorOnly,
+		Params: []string{"etcd.ips", "etcd.clientCert", "etcd.clientKey", "etcd.ca"}},
+	{Name: "etcd-member-list", Description: "The etcd members know the same members and all of them were started", NodeTypes: onEtcd, Tags: []string{"control-plane"}, Severities: majorOnly,
+		Params: []string{"etcd.ips", "etcd.clientCert", "etcd.clientKey", "etcd.ca"}},
+	{Name: "etcd-cert-expiry", Description: "Expiry of the etcd ca, server and peer certificates", NodeTypes: onEtcd, Tags: []string{"certs", "control-plane"},
+		Severities: []severityInfo{{"MAJOR", "7 days"}, {"MINOR", "30 days"}}, Params: append(thresholdParams("etcd-cert-expiry"), "etcd.certificates")},
+	{Name: "etcd-wal-fsync", Description: "Quantile of the wal fsync duration of the etcd members", NodeTypes: onMasterAndEtcd, Tags: []string{"control-plane", "storage"},
+		Severities: []severityInfo{{"MAJOR", "100ms p99"}, {"MINOR", "10ms p99"}}, Params: append(thresholdParams("etcd-wal-fsync"), "etcdLatency.enabled", "etcdLatency.quantile", "etcd.ips")},
+	{Name: "etcd-backend-commit", Description: "Quantile of the backend commit duration of the etcd members", NodeTypes: onMasterAndEtcd, Tags: []string{"control-plane", "storage"},
+		Severities: []severityInfo{{"MAJOR", "250ms p99"}, {"MINOR", "25ms p99"}}, Params: append(thresholdParams("etcd-backend-commit"), "etcdLatency.enabled", "etcdLatency.quantile", "etcd.ips")},
+	{Name: "etcd-backup", Description: "The latest etcd backup is recent and of a plausible size", NodeTypes: onMaster, Tags: []string{"control-plane", "backup"},
+		Severities: []severityInfo{{"MAJOR", "48h"}, {"MINOR", "26h"}},
+		Params:     append(thresholdParams("etcd-backup"), "etcdBackup.enabled", "etcdBackup.path", "etcdBackup.url", "etcdBackup.token", "etcdBackup.minSize")},
+	{Name: "object-backup", Description: "The latest export of the cluster objects is recent and contains the expected kinds", NodeTypes: onMaster, Tags: []string{"control-plane", "backup"},
+		Severities: []severityInfo{{"MAJOR", "48h"}, {"MINOR", "26h"}},
+		Params:     append(thresholdParams("object-backup"), "objectBackup.enabled", "objectBackup.path", "objectBackup.url", "objectBackup.token", "objectBackup.minObjects", "objectBackup.kinds")},
+	{Name: "ntpd", Description: "ntpd is running, synchronized to a reachable peer and its stratum is below the threshold", NodeTypes: onAll, Tags: []string{"os"},
+		Severities: []severityInfo{{"MAJOR", "stratum < 10"}, {"MINOR", "stratum < 5"}}, Params: thresholdParams("ntpd"), Commands: []string{"ntpq"}},
+	{Name: "nfs-mounts", Description: "The nfs mounts have no stale file handles and answer a stat within the timeout", NodeTypes: onMasterAndNode, Tags: []string{"storage"}, Severities: majorOnly,
+		Params: []string{"nfsMounts.enabled", "nfsMounts.timeout"}},
+	{Name: "fluentd-buffer-size", Description: "The file buffers of the local fluentd are below the size", NodeTypes: onMasterAndNode, Tags: []string{"logging"},
+		Severities: []severityInfo{{"MAJOR", "200MB"}, {"MINOR", "100MB"}}, Params: append(thresholdParams("fluentd-buffer-size"), "fluentdBuffer.enabled", "fluentdBuffer.path")},
+	{Name: "fluentd-buffer-age", Description: "The local fluentd sends its queued chunks within the age", NodeTypes: onMasterAndNode, Tags: []string{"logging"},
+		Severities: []severityInfo{{"MAJOR", "60m"}, {"MINOR", "15m"}}, Params: append(thresholdParams("fluentd-buffer-age"), "fluentdBuffer.enabled", "fluentdBuffer.path")},
+	{Name: "ingestion-lag", Description: "Elasticsearch has indexed the journal of the node within the lag", NodeTypes: onMasterAndNode, Tags: []string{"logging"},
+		Severities: []severityInfo{{"MAJOR", "60m"}, {"MINOR", "15m"}}, Params: append(thresholdParams("ingestion-lag"), "ingestionLag.url", "ingestionLag.token", "ingestionLag.index", "ingestionLag.hostname", "ingestionLag.insecure"),
+		Commands: []string{"journalctl"}},
+	{Name: "smart-health", Description: "smartctl reports the disks as healthy", NodeTypes: onNodeAndStorage, Tags: []string{"storage", "os"}, Severities: majorOnly,
+		Params: []string{"diskHealth.enabled", "diskHealth.devices"}, Commands: []string{"smartctl"}},
+	{Name: "disk-io-errors", Description: "The kernel log contains no disk i/o errors since the previous run", NodeTypes: onNodeAndStorage, Tags: []string{"storage", "os"}, Severities: minorOnly,
+		Params: []string{"diskHealth.enabled", "diskHealth.window"}, Commands: []string{"journalctl"}},
+	{Name: "cloud-provider", Description: "The api of the cloud provider answers and accepts the credentials of the cloud config", NodeTypes: onMaster, Tags: []string{"control-plane", "network"},
+		Severities: minorOnly, Params: []string{"cloudProvider.enabled", "cloudProvider.type", "cloudProvider.config"}},
+	{Name: "bootstrap-token-expiry", Description: "Expiry of the bootstrap token secrets in kube-system", NodeTypes: onMaster, Tags: []string{"certs"},
+		Severities: []severityInfo{{"MAJOR", "7 days"}, {"MINOR", "30 days"}}, Params: thresholdParams("bootstrap-token-expiry"), DependsOn: dependsOnApi,
+		Rules: []policyRule{listSecrets}},
+	{Name: "datastore-capacity", Description: "The vSphere datastores used for dynamic provisioning have free space", NodeTypes: onMaster, Tags: []string{"storage"},
+		Severities: []severityInfo{{"MAJOR", "90%"}, {"MINOR", "80%"}},
+		Params:     append(thresholdParams("datastore-capacity"), "vsphereDatastores.url", "vsphereDatastores.username", "vsphereDatastores.password", "vsphereDatastores.datastores", "vsphereDatastores.insecure")},
+	{Name: "prometheus-targets", Description: "Prometheus scrapes all its targets and the expected jobs", NodeTypes: onMaster, Tags: []string{"metrics"},
+		Severities: minorOnly, Params: []string{"prometheus.url", "prometheus.token", "prometheus.insecure", "prometheus.jobs"}},
+	{Name: "prometheus-rules", Description: "Prometheus evaluates its recording and alerting rules without errors", NodeTypes: onMaster, Tags: []string{"metrics"},
+		Severities: minorOnly, Params: []string{"prometheus.url", "prometheus.token", "prometheus.insecure"}},
+	{Name: "prometheus-alertmanagers", Description: "Prometheus has an alertmanager and sends alerts to it", NodeTypes: onMaster, Tags: []string{"metrics"},
+		Severities: minorOnly, Params: []string{"prometheus.url", "prometheus.token", "prometheus.insecure"}},
+	{Name: "dns-consistency", Description: "The inventory hosts have matching forward and reverse dns and the hostname resolves to the node ip", NodeTypes: onAll, Tags: []string{"network"},
+		Severities: minorOnly, Params: []string{"dnsConsistency.enabled", "dnsConsistency.hosts", "dnsConsistency.nodeIp"}},
+	{Name: "dns-srv", Description: "The srv records of a headless service resolve and match its ready endpoints", NodeTypes: onMaster, Tags: []string{"network"},
+		Severities: minorOnly, Params: []string{"dnsSrv.namespace", "dnsSrv.service", "dnsSrv.port", "dnsSrv.domain"}, DependsOn: []string{"master-apis", "dns-service-node"},
+		Rules: []policyRule{readEndpoints}},
+	{Name: "registry-trust", Description: "Docker and the system trust store trust the certificate of the integrated registry", NodeTypes: onNode, Tags: []string{"infra"},
+		Severities: minorOnly, Params: []string{"registryTrust.enabled", "registryTrust.host", "registry.ip"}},
+	{Name: "kubelet-gc-config", Description: "The image gc and disk eviction thresholds of the kubelet fit each other and the disks", NodeTypes: onNode, Tags: []string{"os"},
+		Severities: minorOnly, Params: []string{"kubeletDisks.enabled", "kubeletDisks.nodefs", "kubeletDisks.imagefs"}, Commands: []string{"df"}},
+	{Name: "eviction-headroom", Description: "Free space of the node and image filesystems above the eviction thresholds of the kubelet", NodeTypes: onNode, Tags: []string{"os"},
+		Severities: []severityInfo{{"MAJOR", "2% free"}, {"MINOR", "5% free"}}, Params: append(thresholdParams("eviction-headroom"), "kubeletDisks.enabled", "kubeletDisks.nodefs", "kubeletDisks.imagefs"), Commands: []string{"df"}},
+	{Name: "pod-density", Description: "The pods of the node are below max-pods and pods-per-core of the kubelet", NodeTypes: onNode, Tags: []string{"os"},
+		Severities: []severityInfo{{"MAJOR", "95%"}, {"MINOR", "85%"}}, Params: append(thresholdParams("pod-density"), "podDensity.enabled"), Commands: []string{"docker"}},
+	{Name: "zombie-processes", Description: "Defunct processes of the host", NodeTypes: onMasterAndNode, Tags: []string{"os"},
+		Severities: []severityInfo{{"MAJOR", "100"}, {"MINOR", "20"}}, Params: append(thresholdParams("zombie-processes"), "processes.enabled"), Commands: []string{"ps"}},
+	{Name: "runtime-processes", Description: "No runc hangs and no shim of the container runtime stays defunct", NodeTypes: onMasterAndNode, Tags: []string{"os"},
+		Severities: []severityInfo{{"MAJOR", "60m"}, {"MINOR", "10m"}}, Params: append(thresholdParams("runtime-processes"), "processes.enabled"), Commands: []string{"ps"}},
+	{Name: "dying-cgroups", Description: "Memory cgroups removed but not yet freed by the kernel", NodeTypes: onMasterAndNode, Tags: []string{"os"},
+		Severities: []severityInfo{{"MAJOR", "100000"}, {"MINOR", "20000"}}, Params: append(thresholdParams("dying-cgroups"), "memoryCgroups.enabled")},
+	{Name: "baseline-drift", Description: "Packages, sysctls, mounts and certificates match the saved baseline", NodeTypes: onAll, Tags: []string{"os"}, Severities: minorOnly,
+		Params: []string{"baseline.enabled", "baseline.path", "baseline.sysctls", "baseline.certificates"}, Commands: []string{"rpm"}},
+}
+
+func thresholdParams(check string) []string {
+	return []string{"thresholds." + check + ".major", "thresholds." + check + ".minor"}
+}
+
+// returns the catalog entry of a check, checks run per target (like
+// router-health:<ip>) are found by their name
+func catalogEntry(name string) (checkInfo, bool) {
+	name = strings.SplitN(name, ":", 2)[0]
+	for _, c := range checkCatalog {
+		if c.Name == name {
+			return c, true
+		}
+	}
+	return checkInfo{}, false
+}
+
+// returns why a check is skipped if it needs more than reading in the
+// read-only mode or if one of the checks it depends on failed or was
+// skipped itself earlier in the run, an empty string otherwise
+func skipReason(name string) string {
+	info, ok := catalogEntry(name)
+	if !ok {
+		return ""
+	}
+
+	if viper.GetBool("readOnly") && len(info.Capabilities) > 0 {
+		return "Skipped in read-only mode, the check needs " + strings.Join(info.Capabilities, ", ")
+	}
+
+	for _, dependency := range info.DependsOn {
+		for _, r := range results {
+			if strings.SplitN(r.Name, ":", 2)[0] != dependency {
+				continue
+			}
+			switch r.Status {
+			case "FAILED":
+				return "Skipped because " + r.Name + " failed"
+			case "SKIPPED":
+				// keeps the root cause of the skipped dependency
+				return r.Summary
+			}
+		}
+	}
+	return ""
+}
+
+// returns an error if one of the external commands of a check is missing
+func missingCommand(name string) error {
+	info, ok := catalogEntry(name)
+	if !ok {
+		return nil
+	}
+	for _, command := range info.Commands {
+		if err := probes.MissingCommand(command); err != nil {
+			return err
+		}
+	}
+	return nil
+}
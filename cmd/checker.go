@@ -0,0 +1,130 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"errors"
+
+	"github.com/oscp/openshift-monitoring-checks/checks"
+	"github.com/spf13/viper"
+)
+
+// the checks of the openshift-monitoring-checks library used by evalChecks.
+// Replace checker to run the cli without a cluster.
+type Checker interface {
+	CheckIfGlusterdIsRunning() error
+	CheckMountPointSizes(okSize int) error
+	CheckLVPoolSizes(okSize int) error
+	CheckVGSizes(okSize int) error
+	CheckDockerPool(okSize int) error
+	CheckDnsNslookupOnKubernetes() error
+	CheckDnsServiceNode() error
+	CheckOcGetNodes() error
+	CheckEtcdHealth(etcdIps string, etcdCertPath string) error
+	CheckRegistryHealth(ip string) error
+	CheckRouterHealth(ip string) error
+	CheckMasterApis(urlToCheck string) error
+	CheckOpenFileCount() error
+	CheckHttpService(slow bool) error
+	CheckExternalSystem(url string) error
+	CheckHawcularHealth(ip string) error
+	CheckRouterRestartCount() error
+	CheckLimitsAndQuotas(allowedWithout int) error
+	CheckLoggingRestartsCount() error
+	CheckNtpd() error
+}
+
+var checker Checker = libraryChecker{}
+
+// calls the openshift-monitoring-checks library
+type libraryChecker struct{}
+
+func (libraryChecker) CheckIfGlusterdIsRunning() error { return checks.CheckIfGlusterdIsRunning() }
+func (libraryChecker) CheckMountPointSizes(okSize int) error {
+	return checks.CheckMountPointSizes(okSize)
+}
+func (libraryChecker) CheckLVPoolSizes(okSize int) error { return checks.CheckLVPoolSizes(okSize) }
+func (libraryChecker) CheckVGSizes(okSize int) error     { return checks.CheckVGSizes(okSize) }
+func (libraryChecker) CheckDockerPool(okSize int) error  { return checks.CheckDockerPool(okSize) }
+func (libraryChecker) CheckDnsNslookupOnKubernetes() error {
+	return checks.CheckDnsNslookupOnKubernetes()
+}
+func (libraryChecker) CheckDnsServiceNode() error { return checks.CheckDnsServiceNode() }
+func (libraryChecker) CheckOcGetNodes() error     { return checks.CheckOcGetNodes() }
+func (libraryChecker) CheckEtcdHealth(etcdIps string, etcdCertPath string) error {
+	return checks.CheckEtcdHealth(etcdIps, etcdCertPath)
+}
+func (libraryChecker) CheckRegistryHealth(ip string) error { return checks.CheckRegistryHealth(ip) }
+func (libraryChecker) CheckRouterHealth(ip string) error   { return checks.CheckRouterHealth(ip) }
+func (libraryChecker) CheckMasterApis(urlToCheck string) error {
+	return checks.CheckMasterApis(urlToCheck)
+}
+func (libraryChecker) CheckOpenFileCount() error            { return checks.CheckOpenFileCount() }
+func (libraryChecker) CheckHttpService(slow bool) error     { return checks.CheckHttpService(slow) }
+func (libraryChecker) CheckExternalSystem(url string) error { return checks.CheckExternalSystem(url) }
+func (libraryChecker) CheckHawcularHealth(ip string) error  { return checks.CheckHawcularHealth(ip) }
+func (libraryChecker) CheckRouterRestartCount() error       { return checks.CheckRouterRestartCount() }
+func (libraryChecker) CheckLimitsAndQuotas(allowedWithout int) error {
+	return checks.CheckLimitsAndQuotas(allowedWithout)
+}
+func (libraryChecker) CheckLoggingRestartsCount() error { return checks.CheckLoggingRestartsCount() }
+func (libraryChecker) CheckNtpd() error                 { return checks.CheckNtpd() }
+
+// a checker which fails the checks configured in Failures with their
+// message and passes all others, used with --fake-checks to try outputs
+// and notifications without a cluster
+type FakeChecker struct {
+	Failures map[string]string
+	// names of the checks called, in order
+	Calls []string
+}
+
+// returns a fake checker failing the checks in fakeChecks.failures
+func NewFakeChecker() *FakeChecker {
+	return &FakeChecker{Failures: viper.GetStringMapString("fakeChecks.failures")}
+}
+
+func (f *FakeChecker) check(name string) error {
+	f.Calls = append(f.Calls, name)
+	if msg, ok := f.Failures[name]; ok {
+		return errors.New(msg)
+	}
+	return nil
+}
+
+func (f *FakeChecker) CheckIfGlusterdIsRunning() error       { return f.check("glusterd") }
+func (f *FakeChecker) CheckMountPointSizes(okSize int) error { return f.check("mount-point-sizes") }
+func (f *FakeChecker) CheckLVPoolSizes(okSize int) error     { return f.check("lv-pool-sizes") }
+func (f *FakeChecker) CheckVGSizes(okSize int) error         { return f.check("vg-sizes") }
+func (f *FakeChecker) CheckDockerPool(okSize int) error      { return f.check("docker-pool") }
+func (f *FakeChecker) CheckDnsNslookupOnKubernetes() error   { return f.check("dns-nslookup-kubernetes") }
+func (f *FakeChecker) CheckDnsServiceNode() error            { return f.check("dns-service-node") }
+func (f *FakeChecker) CheckOcGetNodes() error                { return f.check("oc-get-nodes") }
+func (f *FakeChecker) CheckEtcdHealth(etcdIps string, etcdCertPath string) error {
+	return f.check("etcd-health")
+}
+func (f *FakeChecker) CheckRegistryHealth(ip string) error     { return f.check("registry-health") }
+func (f *FakeChecker) CheckRouterHealth(ip string) error       { return f.check("router-health") }
+func (f *FakeChecker) CheckMasterApis(urlToCheck string) error { return f.check("master-apis") }
+func (f *FakeChecker) CheckOpenFileCount() error               { return f.check("open-file-count") }
+func (f *FakeChecker) CheckHttpService(slow bool) error        { return f.check("http-service") }
+func (f *FakeChecker) CheckExternalSystem(url string) error    { return f.check("external-system") }
+func (f *FakeChecker) CheckHawcularHealth(ip string) error     { return f.check("hawcular-health") }
+func (f *FakeChecker) CheckRouterRestartCount() error          { return f.check("router-restart-count") }
+func (f *FakeChecker) CheckLimitsAndQuotas(allowedWithout int) error {
+	return f.check("limits-and-quotas")
+}
+func (f *FakeChecker) CheckLoggingRestartsCount() error { return f.check("logging-restart-count") }
+func (f *FakeChecker) CheckNtpd() error                 { return f.check("ntpd") }
@@ -0,0 +1,83 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/oscp/openshift-monitoring-cli/probes"
+	"github.com/spf13/viper"
+)
+
+// returns the hosts whose clocks are compared, clockSkew.hosts or the etcd
+// members
+func clockSkewHosts() []string {
+	if len(viper.GetString("clockSkew.hosts")) > 0 {
+		return strings.Split(viper.GetString("clockSkew.hosts"), ",")
+	}
+	return etcdHosts()
+}
+
+// returns the hosts of the etcd members, which are the masters on most
+// clusters
+func etcdHosts() []string {
+	var hosts []string
+	for _, member := range strings.Split(viper.GetString("etcd.ips"), ",") {
+		if u, err := url.Parse(member); err == nil && len(u.Hostname()) > 0 {
+			hosts = append(hosts, u.Hostname())
+		} else if len(member) > 0 {
+			hosts = append(hosts, member)
+		}
+	}
+	return hosts
+}
+
+// runs the clock-skew check, the clocks of the hosts are queried once and
+// their spread is compared with the major and minor thresholds
+// (milliseconds).
+func evalClockSkew(ctx context.Context) {
+	evalLevels(ctx, levelCheck{
+		name: "clock-skew",
+		threshold: func(category string) string {
+			return clockSkewLimit(category).String()
+		},
+		measure: func(ctx context.Context) (interface{}, error) {
+			offsets := map[string]time.Duration{}
+			for _, host := range clockSkewHosts() {
+				offset, err := probes.ClockOffset(ctx, host)
+				if err != nil {
+					return offsets, err
+				}
+				offsets[host] = offset
+			}
+			return offsets, nil
+		},
+		evaluate: func(ctx context.Context, offsets interface{}, category string) error {
+			o, ok := offsets.(map[string]time.Duration)
+			if !ok {
+				return unexpectedMeasurement("clock-skew", offsets)
+			}
+			return probes.CheckClockSkew(o, clockSkewLimit(category))
+		},
+	})
+}
+
+// returns the spread of the clocks allowed for the level
+func clockSkewLimit(category string) time.Duration {
+	return time.Duration(threshold("clock-skew", category)) * time.Millisecond
+}
@@ -0,0 +1,58 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/viper"
+)
+
+// the keys of a cluster which are not settings
+var clusterKeys = []string{"kubeconfig", "apiurl"}
+
+// applies clusters.<name> on top of the configuration, so the master checks
+// run against that cluster. oc and the api client use the kubeconfig of the
+// cluster, the api url replaces master.url and every other key of the
+// cluster overrides the setting, e.g. etcd.ips. The result store gets a
+// directory per cluster.
+func applyCluster(name string) error {
+	if !viper.IsSet("clusters." + name) {
+		return fmt.Errorf("Cluster %s is not defined.", name)
+	}
+	settings := viper.GetStringMap("clusters." + name)
+
+	if kubeconfig := viper.GetString("clusters." + name + ".kubeconfig"); len(kubeconfig) > 0 {
+		os.Setenv("KUBECONFIG", kubeconfig)
+		viper.Set("api.kubeconfig", kubeconfig)
+	}
+	if apiUrl := viper.GetString("clusters." + name + ".apiUrl"); len(apiUrl) > 0 {
+		viper.Set("master.url", apiUrl)
+	}
+
+	for key, value := range flattenSettings("", settings) {
+		if containsString(clusterKeys, key) {
+			continue
+		}
+		viper.Set(key, value)
+	}
+
+	if dir := viper.GetString("store.path"); len(dir) > 0 {
+		viper.Set("store.path", filepath.Join(dir, name))
+	}
+	return nil
+}
@@ -0,0 +1,191 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var bundlePath string
+var bundleRuns int
+
+var collectBundleCmd = &cobra.Command{
+	Use:   "collect-bundle",
+	Short: "Collects results, logs, config and host facts into a tar.gz.",
+	Long: `Collects the last stored runs, the log files, the effective config with redacted
+secrets and facts about the host into a tar.gz which can be attached to incident tickets.`,
+	RunE: runCollectBundle,
+}
+
+func init() {
+	rootCmd.AddCommand(collectBundleCmd)
+
+	collectBundleCmd.Flags().StringVar(&bundlePath, "path", "", "file to write (default openshift-monitoring-cli-<host>-<time>.tar.gz)")
+	collectBundleCmd.Flags().IntVar(&bundleRuns, "runs", 10, "number of stored runs to include")
+}
+
+// the last part of config keys whose values are replaced in the bundle
+var secretKeys = []string{"password", "passphrase", "secret", "token", "apikey", "routingkey", "community"}
+
+// webhook urls contain their credentials
+var secretUrlPrefixes = []string{"notifications.slack.", "notifications.mattermost.", "notifications.teams."}
+
+func runCollectBundle(cmd *cobra.Command, args []string) error {
+	hostname := nodeName()
+	now := time.Now()
+
+	path := bundlePath
+	if len(path) == 0 {
+		path = fmt.Sprintf("openshift-monitoring-cli-%s-%s.tar.gz", hostname, now.Format("20060102T150405"))
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	gz := gzip.NewWriter(file)
+	tw := tar.NewWriter(gz)
+	dir := strings.TrimSuffix(filepath.Base(path), ".tar.gz") + "/"
+
+	add := func(name string, content []byte) error {
+		header := &tar.Header{Name: dir + name, Mode: 0600, Size: int64(len(content)), ModTime: now}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		_, err := tw.Write(content)
+		return err
+	}
+
+	facts, err := json.MarshalIndent(bundleFacts(hostname), "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := add("facts.json", facts); err != nil {
+		return err
+	}
+
+	if err := add("config.yml", redactedConfig()); err != nil {
+		return err
+	}
+
+	if storePath := viper.GetString("store.path"); len(storePath) > 0 {
+		files, err := runFiles(storePath)
+		if err != nil {
+			log.Warning("Not able to read result store", err)
+		}
+		if len(files) > bundleRuns {
+			files = files[len(files)-bundleRuns:]
+		}
+		for _, f := range files {
+			if err := addFile(add, "runs/"+filepath.Base(f), f); err != nil {
+				return err
+			}
+		}
+	}
+
+	if logFile := viper.GetString("logging.file"); len(logFile) > 0 {
+		logs, _ := filepath.Glob(logFile + ".*")
+		for _, f := range append([]string{logFile}, logs...) {
+			if err := addFile(add, "logs/"+filepath.Base(f), f); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+
+	fmt.Println("Wrote", path)
+	return nil
+}
+
+// adds a file to the bundle, files which can't be read are skipped
+func addFile(add func(name string, content []byte) error, name string, path string) error {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		log.Warning("Skipping unreadable file", path, err)
+		return nil
+	}
+	return add(name, content)
+}
+
+func bundleFacts(hostname string) map[string]interface{} {
+	return map[string]interface{}{
+		"time":               time.Now(),
+		"hostname":           hostname,
+		"os":                 runtime.GOOS,
+		"arch":               runtime.GOARCH,
+		"goVersion":          runtime.Version(),
+		"integrationVersion": data.IntegrationVersion,
+		"configFile":         viper.ConfigFileUsed(),
+		"nodeType":           viper.GetString("node.type"),
+		"detected":           detectHostFacts(),
+		"ocVersion":          detectOc("version"),
+	}
+}
+
+// returns the effective config as sorted key: value lines with secrets replaced
+func redactedConfig() []byte {
+	settings := flattenSettings("", viper.AllSettings())
+
+	var keys []string
+	for key := range settings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var out bytes.Buffer
+	for _, key := range keys {
+		fmt.Fprintf(&out, "%s: %s\n", key, maskedValue(key, settings[key]))
+	}
+	return out.Bytes()
+}
+
+func isSecretKey(key string) bool {
+	key = strings.ToLower(key)
+	last := key[strings.LastIndex(key, ".")+1:]
+
+	for _, secret := range secretKeys {
+		if strings.Contains(last, secret) {
+			return true
+		}
+	}
+	for _, prefix := range secretUrlPrefixes {
+		if strings.HasPrefix(key, prefix) && (last == "url" || strings.HasPrefix(key, prefix+"urls.")) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,144 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// separates the documents of a yaml file
+var yamlDocumentSeparator = regexp.MustCompile(`(?m)^---[ \t]*$`)
+
+// reads the config file again with all its documents, each document
+// migrated to the current layout, see configVersion. viper only reads the
+// first document of the config file and doesn't migrate it.
+func mergeConfigFile() error {
+	// a missing config file was reported already
+	used := viper.ConfigFileUsed()
+	if _, err := os.Stat(used); len(used) == 0 || err != nil {
+		return nil
+	}
+	return mergeConfigDocuments(used, true)
+}
+
+// merges the files of configDir into the config, migrated like the config
+// file. They are merged in the order of their names and after the remote
+// config, so base settings distributed by config management can be
+// overridden by site or node specific files like 90-site.yml. Maps are
+// merged key by key, other values are replaced.
+func mergeConfigDir() error {
+	used := viper.ConfigFileUsed()
+	if _, err := os.Stat(used); len(used) == 0 || err != nil {
+		return nil
+	}
+
+	for _, file := range configDirFiles(used) {
+		log.Debug("Merging config file", file)
+		if err := mergeConfigDocuments(file, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// returns the yaml files of configDir in the order of their names, a
+// relative configDir is relative to the config file
+func configDirFiles(configFile string) []string {
+	dir := viper.GetString("configDir")
+	if len(dir) == 0 {
+		dir = filepath.Join(filepath.Dir(configFile), "config.d")
+	} else if !filepath.IsAbs(dir) {
+		dir = filepath.Join(filepath.Dir(configFile), dir)
+	}
+
+	var files []string
+	for _, pattern := range []string{"*.yml", "*.yaml"} {
+		matches, _ := filepath.Glob(filepath.Join(dir, pattern))
+		files = append(files, matches...)
+	}
+	sort.Strings(files)
+	return files
+}
+
+// merges the migrated documents of the yaml file into the config, with
+// replace the first document replaces the config
+func mergeConfigDocuments(path string, replace bool) error {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("Not able to read config file %s: %v", path, err)
+	}
+	return mergeConfigContent(path, string(content), replace)
+}
+
+// merges the migrated documents of the yaml content read from source into
+// the config, with replace the first document replaces the config. All
+// documents are migrated first, so content which can't be parsed doesn't
+// change the config.
+func mergeConfigContent(source string, content string, replace bool) error {
+	documents := splitConfigDocuments(content)
+
+	var migrated []string
+	for i, document := range documents {
+		m, version, err := migrateConfigDocument(document)
+		if err != nil {
+			return fmt.Errorf("Not able to migrate document %d of %s: %v", i+1, source, err)
+		}
+		if version < currentConfigVersion {
+			log.Warning("Document", i+1, "of", source, "has the config layout of version", version, "- run config migrate to upgrade it")
+		}
+		migrated = append(migrated, m)
+	}
+
+	viper.SetConfigType("yaml")
+	for i, document := range migrated {
+		var err error
+		if i == 0 && replace {
+			err = viper.ReadConfig(strings.NewReader(document))
+		} else {
+			err = viper.MergeConfig(strings.NewReader(document))
+		}
+		if err != nil {
+			return fmt.Errorf("Not able to merge document %d of %s: %v", i+1, source, err)
+		}
+	}
+	return nil
+}
+
+// returns the documents of the yaml file
+func readConfigDocuments(path string) ([]string, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Not able to read config file %s: %v", path, err)
+	}
+	return splitConfigDocuments(string(content)), nil
+}
+
+// splits yaml content into its documents
+func splitConfigDocuments(content string) []string {
+	documents := yamlDocumentSeparator.Split(content, -1)
+	// a separator at the start doesn't end a document
+	if len(documents) > 1 && len(strings.TrimSpace(documents[0])) == 0 {
+		documents = documents[1:]
+	}
+	return documents
+}
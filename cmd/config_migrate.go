@@ -0,0 +1,99 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var configMigrateInPlace bool
+
+var configMigrateCmd = &cobra.Command{
+	Use:   "migrate [file...]",
+	Short: "Upgrades config files to the current layout.",
+	Long: `Migrates the documents of the config files, by default the config file and the files of
+configDir, to the layout of configVersion ` + fmt.Sprint(currentConfigVersion) + ` and prints them. With --in-place the
+files are replaced and the originals kept as <file>.bak. Runs read older layouts as well,
+but warn about them. Comments of migrated documents are not kept.`,
+	RunE: runConfigMigrate,
+}
+
+func init() {
+	configCmd.AddCommand(configMigrateCmd)
+
+	configMigrateCmd.Flags().BoolVar(&configMigrateInPlace, "in-place", false, "replace the files instead of printing them")
+}
+
+func runConfigMigrate(cmd *cobra.Command, args []string) error {
+	files := args
+	if len(files) == 0 {
+		used := viper.ConfigFileUsed()
+		if len(used) == 0 {
+			return fmt.Errorf("No config file found, pass the files to migrate")
+		}
+		files = append([]string{used}, configDirFiles(used)...)
+	}
+
+	for _, file := range files {
+		documents, err := readConfigDocuments(file)
+		if err != nil {
+			return err
+		}
+
+		changed := false
+		for i, document := range documents {
+			migrated, version, err := migrateConfigDocument(document)
+			if err != nil {
+				return fmt.Errorf("Not able to migrate document %d of %s: %v", i+1, file, err)
+			}
+			if version < currentConfigVersion {
+				changed = true
+				// the separator is followed by the newline it was split at
+				if i > 0 {
+					migrated = "\n" + migrated
+				}
+				documents[i] = migrated
+			}
+		}
+		out := strings.Join(documents, "---")
+
+		if !configMigrateInPlace {
+			fmt.Printf("# %s\n%s", file, out)
+			continue
+		}
+		if !changed {
+			log.Info(file, "has the current config layout")
+			continue
+		}
+		info, err := os.Stat(file)
+		if err != nil {
+			return err
+		}
+		if err := os.Rename(file, file+".bak"); err != nil {
+			return fmt.Errorf("Not able to keep %s: %v", file, err)
+		}
+		if err := ioutil.WriteFile(file, []byte(out), info.Mode()); err != nil {
+			return fmt.Errorf("Not able to write %s: %v", file, err)
+		}
+		log.Info("Migrated", file, "to config version", currentConfigVersion)
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v2"
+)
+
+var configShowEffective bool
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Shows the configuration.",
+}
+
+var configShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Prints the configuration with secret values masked.",
+	Long: `Prints the settings of the config file or, with --effective, the merged configuration of
+the config file, the files of configDir, the remote config, the sops file, the profile, the
+flags and all defaults as used by a run.
+References like ${env:NAME} are shown resolved. Secret values are masked.`,
+	RunE: runConfigShow,
+}
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configShowCmd)
+
+	configShowCmd.Flags().BoolVar(&configShowEffective, "effective", false, "print the merged configuration including all defaults")
+}
+
+func runConfigShow(cmd *cobra.Command, args []string) error {
+	if configShowEffective {
+		detectMasterDefaults()
+		if len(logLevel) > 0 {
+			viper.Set("logging.level", logLevel)
+		}
+	}
+
+	settings := map[string]interface{}{}
+	for key, value := range flattenSettings("", viper.AllSettings()) {
+		if !configShowEffective && !viper.InConfig(key) {
+			continue
+		}
+		// values are kept as they are, so numbers and lists stay yaml numbers and lists
+		if isSecretKey(key) && len(fmt.Sprint(value)) > 0 {
+			value = "<redacted>"
+		}
+		setNested(settings, strings.Split(key, "."), value)
+	}
+
+	out, err := yaml.Marshal(settings)
+	if err != nil {
+		return err
+	}
+	fmt.Print(string(out))
+	return nil
+}
+
+// sets the value at the path of keys, creating the maps in between
+func setNested(settings map[string]interface{}, keys []string, value interface{}) {
+	for _, key := range keys[:len(keys)-1] {
+		nested, ok := settings[key].(map[string]interface{})
+		if !ok {
+			nested = map[string]interface{}{}
+			settings[key] = nested
+		}
+		settings = nested
+	}
+	settings[keys[len(keys)-1]] = value
+}
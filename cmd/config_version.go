@@ -0,0 +1,167 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// the layout version of the config written by this release, a config
+// without configVersion has version 1
+const currentConfigVersion = 3
+
+// a migration upgrades a config document from the version before to its
+// version. Settings are moved as they are, so their values keep working.
+type configMigration struct {
+	version     int
+	description string
+	migrate     func(doc yaml.MapSlice) yaml.MapSlice
+}
+
+var configMigrations = []configMigration{
+	{2, "moves hawcularIP, externalSystemUrl and projectsWithoutLimits into the sections of their checks", func(doc yaml.MapSlice) yaml.MapSlice {
+		doc = moveSetting(doc, "hawcularIP", "hawkular.ip")
+		doc = moveSetting(doc, "externalSystemUrl", "externalSystem.url")
+		return moveSetting(doc, "projectsWithoutLimits", "limitsAndQuotas.projectsWithoutLimits")
+	}},
+	{3, "moves canary.enabled and diagnostics.enabled into features", func(doc yaml.MapSlice) yaml.MapSlice {
+		doc = moveSectionSetting(doc, "canary.enabled", "features.canary")
+		return moveSectionSetting(doc, "diagnostics.enabled", "features.diagnostics")
+	}},
+}
+
+// migrates a yaml config document to the current version. The settings of
+// the profiles and clusters are migrated like those at the top. Returns
+// the version the document had.
+func migrateConfigDocument(content string) (string, int, error) {
+	var doc yaml.MapSlice
+	if err := yaml.Unmarshal([]byte(content), &doc); err != nil {
+		return "", 0, err
+	}
+
+	version := 1
+	if i := itemIndex(doc, "configVersion"); i >= 0 {
+		v, ok := doc[i].Value.(int)
+		if !ok {
+			return "", 0, fmt.Errorf("configVersion %v is not a number", doc[i].Value)
+		}
+		version = v
+	}
+	if version > currentConfigVersion {
+		return "", version, fmt.Errorf("configVersion %d is newer than %d of this release", version, currentConfigVersion)
+	}
+	if version == currentConfigVersion || len(doc) == 0 {
+		return content, version, nil
+	}
+
+	for _, m := range configMigrations {
+		if m.version <= version {
+			continue
+		}
+		log.Debug("Migrating config to version", m.version, "which", m.description)
+		doc = m.migrate(doc)
+		for _, section := range []string{"profiles", "clusters"} {
+			if i := itemIndex(doc, section); i >= 0 {
+				if scopes, ok := doc[i].Value.(yaml.MapSlice); ok {
+					for j := range scopes {
+						if scope, ok := scopes[j].Value.(yaml.MapSlice); ok {
+							scopes[j].Value = m.migrate(scope)
+						}
+					}
+				}
+			}
+		}
+	}
+
+	if i := itemIndex(doc, "configVersion"); i >= 0 {
+		doc[i].Value = currentConfigVersion
+	} else {
+		doc = append(yaml.MapSlice{{Key: "configVersion", Value: currentConfigVersion}}, doc...)
+	}
+	out, err := yaml.Marshal(doc)
+	return string(out), version, err
+}
+
+// returns the index of the key in the map, -1 if it is missing
+func itemIndex(m yaml.MapSlice, key string) int {
+	for i, item := range m {
+		if fmt.Sprint(item.Key) == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// moves the top level setting from to the dotted path to. The section of
+// to takes the place of from if it doesn't exist yet, an existing setting
+// at to is kept.
+func moveSetting(doc yaml.MapSlice, from string, to string) yaml.MapSlice {
+	i := itemIndex(doc, from)
+	if i < 0 {
+		return doc
+	}
+	value := doc[i].Value
+	keys := strings.Split(to, ".")
+
+	if j := itemIndex(doc, keys[0]); j >= 0 {
+		section, ok := doc[j].Value.(yaml.MapSlice)
+		if !ok && doc[j].Value != nil {
+			log.Warning("Not able to migrate", from, "to", to, "as", keys[0], "is no section")
+			return doc
+		}
+		doc[j].Value = setNestedItem(section, keys[1:], value)
+		return append(doc[:i], doc[i+1:]...)
+	}
+
+	doc[i] = yaml.MapItem{Key: keys[0], Value: setNestedItem(nil, keys[1:], value)}
+	return doc
+}
+
+// moves the setting from of a top level section to the dotted path to. The
+// section of to is added at the end if it doesn't exist yet, an existing
+// setting at to is kept.
+func moveSectionSetting(doc yaml.MapSlice, from string, to string) yaml.MapSlice {
+	keys := strings.SplitN(from, ".", 2)
+	i := itemIndex(doc, keys[0])
+	if i < 0 {
+		return doc
+	}
+	section, ok := doc[i].Value.(yaml.MapSlice)
+	j := itemIndex(section, keys[1])
+	if !ok || j < 0 {
+		return doc
+	}
+	value := section[j].Value
+	doc[i].Value = append(section[:j], section[j+1:]...)
+	return setNestedItem(doc, strings.Split(to, "."), value).(yaml.MapSlice)
+}
+
+// sets the value at the path of keys in m unless it is set already
+func setNestedItem(m yaml.MapSlice, keys []string, value interface{}) interface{} {
+	if len(keys) == 0 {
+		return value
+	}
+	if i := itemIndex(m, keys[0]); i >= 0 {
+		if len(keys) > 1 {
+			nested, _ := m[i].Value.(yaml.MapSlice)
+			m[i].Value = setNestedItem(nested, keys[1:], value)
+		}
+		return m
+	}
+	return append(m, yaml.MapItem{Key: keys[0], Value: setNestedItem(nil, keys[1:], value)})
+}
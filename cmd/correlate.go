@@ -0,0 +1,123 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// the subsystems whose failures can cause failures in a subsystem, e.g. a
+// broken network makes the control plane checks fail as well. Subsystems
+// which are not listed (like security) are never a symptom.
+var subsystemCauses = map[string][]string{
+	"network":       {"os"},
+	"storage":       {"os"},
+	"control-plane": {"os", "storage", "network"},
+	"auth":          {"network", "control-plane"},
+	"infra":         {"os", "storage", "network", "control-plane"},
+	"metrics":       {"storage", "network", "control-plane", "infra"},
+	"logging":       {"storage", "network", "control-plane", "infra"},
+}
+
+// tags which don't name a subsystem
+var nonSubsystemTags = []string{"deep", "rate"}
+
+// returns the subsystem of a check, the first subsystem tag of its catalog entry
+func subsystemOf(name string) string {
+	info, ok := catalogEntry(name)
+	if !ok {
+		return "other"
+	}
+	for _, tag := range info.Tags {
+		if !containsString(nonSubsystemTags, tag) {
+			return tag
+		}
+	}
+	return "other"
+}
+
+// groups the failed checks by subsystem and marks them as likely root-cause
+// or as symptom of a failure in a subsystem they depend on. The marks are
+// added to the results and events.
+func correlateResults() {
+	failedSubsystems := map[string]bool{}
+	for _, r := range results {
+		if r.Status == "FAILED" {
+			failedSubsystems[subsystemOf(r.Name)] = true
+		}
+	}
+
+	for i, r := range results {
+		if r.Status != "FAILED" {
+			continue
+		}
+
+		subsystem := subsystemOf(r.Name)
+		cause := "root-cause"
+		for _, s := range subsystemCauses[subsystem] {
+			if failedSubsystems[s] {
+				cause = "symptom"
+			}
+		}
+
+		results[i].Subsystem = subsystem
+		results[i].Cause = cause
+		if event, ok := failedEvents[r.Category+"/"+r.Name]; ok {
+			event["subsystem"] = subsystem
+			event["cause"] = cause
+		}
+	}
+}
+
+// prints the failed checks grouped by subsystem, the likely root causes first
+func outputCorrelation(w io.Writer, results []checkResult) {
+	groups := map[string][]string{}
+	rootCauses := map[string]bool{}
+	for _, r := range results {
+		if r.Status != "FAILED" || len(r.Subsystem) == 0 {
+			continue
+		}
+		groups[r.Subsystem] = append(groups[r.Subsystem], fmt.Sprintf("%s (%s)", r.Name, r.Category))
+		if r.Cause == "root-cause" {
+			rootCauses[r.Subsystem] = true
+		}
+	}
+	if len(groups) == 0 {
+		return
+	}
+
+	var subsystems []string
+	for s := range groups {
+		subsystems = append(subsystems, s)
+	}
+	sort.Slice(subsystems, func(i, j int) bool {
+		if rootCauses[subsystems[i]] != rootCauses[subsystems[j]] {
+			return rootCauses[subsystems[i]]
+		}
+		return subsystems[i] < subsystems[j]
+	})
+
+	fmt.Fprintln(w, "\nFailed checks by subsystem:")
+	for _, s := range subsystems {
+		cause := "symptom"
+		if rootCauses[s] {
+			cause = "likely root cause"
+		}
+		fmt.Fprintf(w, "  %s (%s): %s\n", s, cause, strings.Join(groups[s], ", "))
+	}
+}
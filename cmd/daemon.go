@@ -0,0 +1,253 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var daemonInterval time.Duration
+var daemonListen string
+
+var daemonCmd = &cobra.Command{
+	Use:   "daemon",
+	Short: "Runs the checks periodically and serves health endpoints.",
+	Long: `Runs the checks every daemon.interval seconds and serves /healthz and /readyz on
+daemon.listen. /healthz fails if the scheduler is stuck, /readyz until a run finished.
+POST /run?checks=a,b runs the given checks immediately, see daemon.runToken.
+/metrics serves the health score of the latest run as prometheus gauge, with
+accounting.metrics also the wall time, cpu time and disk blocks of each check.
+Under systemd the watchdog is notified as long as the scheduler is healthy.
+With breaker.enabled a check which keeps failing is probed less often and its
+event is added once per breaker.reportWindow.`,
+	Run: runDaemon,
+}
+
+func init() {
+	rootCmd.AddCommand(daemonCmd)
+
+	daemonCmd.Flags().DurationVar(&daemonInterval, "interval", 0, "time between two runs, e.g. 5m (default daemon.interval)")
+	daemonCmd.Flags().StringVar(&daemonListen, "listen", "", "address of the health endpoints (default daemon.listen)")
+}
+
+// the state of the scheduler reported by the health endpoints
+type daemonState struct {
+	lock sync.Mutex
+
+	interval time.Duration
+	// how long a run may take before the scheduler counts as stuck
+	stallTimeout time.Duration
+
+	State             string    `json:"state"`
+	Started           time.Time `json:"started"`
+	RunStarted        time.Time `json:"runStarted"`
+	LastRun           time.Time `json:"lastRun"`
+	LastSuccessfulRun time.Time `json:"lastSuccessfulRun"`
+	NextRun           time.Time `json:"nextRun"`
+	FailedChecks      int       `json:"failedChecks"`
+}
+
+func (s *daemonState) runStarted() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.State = "running"
+	s.RunStarted = time.Now()
+}
+
+func (s *daemonState) runFinished(successful bool, failedChecks int) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.State = "idle"
+	s.LastRun = time.Now()
+	s.NextRun = s.LastRun.Add(s.interval)
+	if successful {
+		s.LastSuccessfulRun = s.LastRun
+		s.FailedChecks = failedChecks
+	}
+}
+
+func (s *daemonState) stopped() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.State = "stopped"
+}
+
+// reports if the scheduler is alive, i.e. not stopped and no run is stuck
+func (s *daemonState) healthy() bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	switch s.State {
+	case "idle":
+		return true
+	case "running":
+		return time.Since(s.RunStarted) < s.stallTimeout
+	}
+	return false
+}
+
+// reports if a run finished successfully and recently enough
+func (s *daemonState) ready() bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return !s.LastSuccessfulRun.IsZero() && time.Since(s.LastSuccessfulRun) < 2*s.interval+s.stallTimeout
+}
+
+func (s *daemonState) handler(check func() bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ok := check()
+
+		s.lock.Lock()
+		content, err := json.Marshal(s)
+		s.lock.Unlock()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if !ok {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		w.Write(content)
+	}
+}
+
+func runDaemon(cmd *cobra.Command, args []string) {
+	interval := daemonInterval
+	if interval == 0 {
+		interval = time.Duration(viper.GetInt("daemon.interval")) * time.Second
+	}
+	listen := daemonListen
+	if len(listen) == 0 {
+		listen = viper.GetString("daemon.listen")
+	}
+	if interval <= 0 {
+		log.Fatal("Invalid daemon interval", interval)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	defer cancelOnSignal(cancel)()
+
+	initInCluster()
+	validateConfig()
+	initAPIClient()
+
+	runsAsDaemon = true
+	var state = &daemonState{interval: interval, stallTimeout: 2 * interval, State: "starting", Started: time.Now()}
+	if timeout := viper.GetInt("run.timeout"); timeout > 0 {
+		state.stallTimeout = time.Duration(timeout)*time.Second + time.Minute
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", state.handler(state.healthy))
+	mux.HandleFunc("/readyz", state.handler(state.ready))
+	mux.HandleFunc("/run", handleRun(ctx))
+	mux.HandleFunc("/metrics", handleMetrics)
+	server := &http.Server{Addr: listen, Handler: mux}
+
+	go func() {
+		log.Info("Serving health endpoints on", listen)
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Error("Health endpoints failed:", err)
+			cancel()
+		}
+	}()
+	defer server.Close()
+
+	if len(viper.GetString("server.listen")) > 0 {
+		go serveResults(ctx, cancel)
+	}
+
+	go notifyWatchdog(ctx, state)
+	sdNotify("READY=1")
+
+	for {
+		reconcileCheckConfigs()
+		state.runStarted()
+		runLock.Lock()
+		runCtx, runCancel := runContext(ctx)
+		executeRun(runCtx)
+		successful := runCtx.Err() == nil
+		runCancel()
+		rememberRun()
+		runLock.Unlock()
+
+		failed := countStatus(lastRun.Results, "FAILED")
+		state.runFinished(successful, failed)
+		log.Info("Run finished,", failed, "of", len(lastRun.Results), "checks failed, next run at", time.Now().Add(interval).Format("15:04:05"))
+
+		select {
+		case <-ctx.Done():
+			state.stopped()
+			sdNotify("STOPPING=1")
+			log.Info("Stopping daemon.")
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+// sends WATCHDOG=1 to systemd as long as the scheduler is healthy, if the
+// unit has a WatchdogSec
+func notifyWatchdog(ctx context.Context, state *daemonState) {
+	usec, err := strconv.Atoi(os.Getenv("WATCHDOG_USEC"))
+	if err != nil || usec <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(time.Duration(usec) * time.Microsecond / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if state.healthy() {
+				sdNotify("WATCHDOG=1")
+			}
+		}
+	}
+}
+
+// sends a state to the systemd notify socket, if there is one
+func sdNotify(message string) {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if len(socket) == 0 {
+		return
+	}
+
+	conn, err := net.Dial("unixgram", socket)
+	if err != nil {
+		log.Warning("Not able to notify systemd", err)
+		return
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte(message)); err != nil {
+		log.Warning("Not able to notify systemd", err)
+	}
+}
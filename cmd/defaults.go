@@ -0,0 +1,156 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// the defaults of each node type on top of the global defaults, so a
+// config.yml only needs to contain what differs on a cluster
+var nodeTypeDefaults = map[string]map[string]interface{}{
+	"master": {
+		"kubeconfigs.paths":     "/etc/origin/master/admin.kubeconfig,/etc/origin/master/openshift-master.kubeconfig",
+		"baseline.certificates": "/etc/origin/master/*.crt,/etc/origin/node/*.crt",
+		// without a test user only the oauth endpoints are checked
+		"oauthLogin.enabled":  true,
+		"etcdLatency.enabled": true,
+		"clockSkew.enabled":   true,
+	},
+	"node": {
+		"kubeconfigs.paths":     "/etc/origin/node/node.kubeconfig,/etc/origin/node/bootstrap.kubeconfig",
+		"baseline.certificates": "/etc/origin/node/*.crt",
+	},
+	"infra": {
+		"kubeconfigs.paths":     "/etc/origin/node/node.kubeconfig,/etc/origin/node/bootstrap.kubeconfig",
+		"baseline.certificates": "/etc/origin/node/*.crt",
+	},
+	"etcd": {
+		"etcd.clientCert":       "/etc/etcd/peer.crt",
+		"etcd.clientKey":        "/etc/etcd/peer.key",
+		"etcd.ca":               "/etc/etcd/ca.crt",
+		"etcd.certificates":     "/etc/etcd/ca.crt,/etc/etcd/server.crt,/etc/etcd/peer.crt",
+		"baseline.certificates": "/etc/etcd/*.crt",
+		"etcdLatency.enabled":   true,
+	},
+	"storage": {
+		"kubeconfigs.paths":     "/etc/origin/node/node.kubeconfig",
+		"baseline.certificates": "/etc/origin/node/*.crt",
+	},
+}
+
+// sets the defaults of the configured node type
+func applyNodeTypeDefaults() {
+	for _, nodeType := range nodeTypeBases(viper.GetString("node.type")) {
+		for key, value := range nodeTypeDefaults[nodeType] {
+			viper.SetDefault(key, value)
+		}
+	}
+	applyCustomNodeType()
+}
+
+// detects the etcd and router ips of a master, the router ips of an infra
+// node and the local member of an etcd host if they are not configured,
+// like init-config does
+func detectMasterDefaults() {
+	if isNodeType("etcd") && len(viper.GetString("etcd.ips")) == 0 {
+		viper.SetDefault("etcd.ips", detectLocalEtcdUrls())
+	}
+	if !isNodeType("master") && !isNodeType("infra") {
+		return
+	}
+	if isNodeType("master") && len(viper.GetString("etcd.ips")) == 0 {
+		viper.SetDefault("etcd.ips", detectEtcdUrls())
+	}
+	if isNodeType("master") && viper.GetBool("cloudProvider.enabled") && len(viper.GetString("cloudProvider.type")) == 0 {
+		provider, config := detectCloudProvider()
+		viper.SetDefault("cloudProvider.type", provider)
+		viper.SetDefault("cloudProvider.config", config)
+	}
+	if len(viper.GetString("router.ips")) == 0 {
+		viper.SetDefault("router.ips", joinFields(detectOc("get", "pods", "-n", "default", "-l", "router", "-o", "jsonpath={.items[*].status.hostIP}")))
+	}
+}
+
+var printDefaultsNodeType string
+
+var printDefaultsCmd = &cobra.Command{
+	Use:   "print-defaults",
+	Short: "Prints the effective settings of the node type and where they come from.",
+	Long: `Prints every setting with its effective value and whether it is a built-in default,
+a default of the node type or set in the config. Secret values are masked.`,
+	Run: runPrintDefaults,
+}
+
+func init() {
+	rootCmd.AddCommand(printDefaultsCmd)
+
+	printDefaultsCmd.Flags().StringVar(&printDefaultsNodeType, "node-type", "", "node type to show the defaults of (default node.type)")
+}
+
+func runPrintDefaults(cmd *cobra.Command, args []string) {
+	if len(printDefaultsNodeType) > 0 {
+		viper.Set("node.type", printDefaultsNodeType)
+		applyNodeTypeDefaults()
+	}
+	detectMasterDefaults()
+
+	nodeType := viper.GetString("node.type")
+	settings := flattenSettings("", viper.AllSettings())
+
+	var keys []string
+	for key := range settings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SETTING\tVALUE\tSOURCE")
+	for _, key := range keys {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", key, maskedValue(key, settings[key]), settingSource(key, nodeType))
+	}
+	tw.Flush()
+}
+
+// returns the value of a setting with secrets replaced
+func maskedValue(key string, value interface{}) string {
+	s := fmt.Sprint(value)
+	if isSecretKey(key) && len(s) > 0 {
+		return "<redacted>"
+	}
+	return s
+}
+
+func settingSource(key string, nodeType string) string {
+	if strings.HasPrefix(key, "thresholds.") && viper.IsSet("nodeTypes."+nodeType+"."+key) {
+		return "node type " + nodeType
+	}
+	if viper.InConfig(key) {
+		return "config"
+	}
+	for _, base := range nodeTypeBases(nodeType) {
+		if nodeTypeDefaults[base][key] != nil {
+			return "default " + base
+		}
+	}
+	return "default"
+}
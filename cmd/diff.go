@@ -0,0 +1,130 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var diffOutput string
+
+var diffCmd = &cobra.Command{
+	Use:   "diff",
+	Short: "Compares the latest stored run with the run before.",
+	Long: `Lists the checks which failed for the first time, which are still failing and which
+were resolved in the latest run of the result store (store.path) compared to the run before.`,
+	RunE: runDiff,
+}
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+
+	diffCmd.Flags().StringVarP(&diffOutput, "output", "o", "text", "output format (text|json)")
+}
+
+// the events of the failed checks of the current run by category/name
+var failedEvents map[string]EventData
+
+// sets the state of the failed checks and their events to new or
+// still-failing compared to the previous run
+func annotateStates(previous *storedRun) {
+	changes := compareRuns(previous, results)
+
+	isNew := map[string]bool{}
+	for _, r := range changes.New {
+		isNew[r.Category+"/"+r.Name] = true
+	}
+
+	for i, r := range results {
+		if r.Status != "FAILED" {
+			continue
+		}
+		key := r.Category + "/" + r.Name
+		results[i].State = "still-failing"
+		if isNew[key] {
+			results[i].State = "new"
+		}
+		if event, ok := failedEvents[key]; ok {
+			event["state"] = results[i].State
+		}
+	}
+}
+
+type runDiffData struct {
+	From         time.Time     `json:"from"`
+	To           time.Time     `json:"to"`
+	New          []checkResult `json:"new"`
+	StillFailing []checkResult `json:"still_failing"`
+	Resolved     []checkResult `json:"resolved"`
+}
+
+func runDiff(cmd *cobra.Command, args []string) error {
+	runs, err := loadRuns(time.Time{}, time.Time{})
+	if err != nil {
+		return fmt.Errorf("Not able to read result store: %v", err)
+	}
+	if len(runs) < 2 {
+		return errors.New("The result store needs at least two runs to compare.")
+	}
+
+	previous, current := runs[len(runs)-2], runs[len(runs)-1]
+	changes := compareRuns(&previous, current.Results)
+
+	var diff = runDiffData{From: previous.Time, To: current.Time, New: changes.New, Resolved: changes.Resolved}
+	for _, r := range changes.Failed {
+		if !containsResult(changes.New, r) {
+			diff.StillFailing = append(diff.StillFailing, r)
+		}
+	}
+
+	switch diffOutput {
+	case "json":
+		return json.NewEncoder(os.Stdout).Encode(diff)
+	case "text":
+		printDiff(os.Stdout, diff)
+		return nil
+	}
+	return fmt.Errorf("Unknown output format %s.", diffOutput)
+}
+
+func printDiff(w io.Writer, diff runDiffData) {
+	fmt.Fprintf(w, "Changes from %s to %s\n", diff.From.Local().Format("2006-01-02 15:04:05"), diff.To.Local().Format("2006-01-02 15:04:05"))
+
+	for _, section := range []struct {
+		title   string
+		results []checkResult
+	}{{"New", diff.New}, {"Still failing", diff.StillFailing}, {"Resolved", diff.Resolved}} {
+		fmt.Fprintf(w, "\n%s (%d)\n", section.title, len(section.results))
+		for _, r := range section.results {
+			fmt.Fprintf(w, "  %-8s %s: %s\n", r.Category, r.Name, r.Summary)
+		}
+	}
+}
+
+func containsResult(list []checkResult, r checkResult) bool {
+	for _, l := range list {
+		if l.Category == r.Category && l.Name == r.Name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,50 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/oscp/openshift-monitoring-cli/probes"
+	"github.com/spf13/viper"
+)
+
+// runs the smart health check of diskHealth.devices and the check for i/o
+// errors in the kernel log since the previous run
+func evalDiskHealth(ctx context.Context) {
+	if len(viper.GetString("diskHealth.devices")) > 0 {
+		evalMajor(ctx, "smart-health", "", func(ctx context.Context) error {
+			return probes.CheckSmartHealth(ctx, strings.Split(viper.GetString("diskHealth.devices"), ","))
+		})
+	}
+	evalMinor(ctx, "disk-io-errors", "", func(ctx context.Context) error {
+		return probes.CheckKernelIoErrors(ctx, ioErrorsSince())
+	})
+}
+
+// returns the time of the previous run or, without one, diskHealth.window
+// minutes ago
+func ioErrorsSince() time.Time {
+	previous := previousRun()
+	if previous == nil {
+		previous = lastRun
+	}
+	if previous != nil {
+		return previous.Time.Local()
+	}
+	return time.Now().Add(-time.Duration(viper.GetInt("diskHealth.window")) * time.Minute)
+}
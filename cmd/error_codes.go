@@ -0,0 +1,137 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import "strings"
+
+// errorCode is a failure mode of a check with a stable code, so alert
+// rules and suppression policies can match a failure precisely. A code is
+// never renumbered or reused, new failure modes get the next free number
+// of their area.
+type errorCode struct {
+	Code string `json:"code"`
+	// the message id of the failure mode, empty for any other failure of
+	// the check, like those of the library checks
+	MessageID string `json:"message_id,omitempty"`
+}
+
+// the code of a check skipped because a command it needs is missing
+const missingCommandCode = "OSM-RUN-001"
+
+// the error codes of the checks by area
+var checkErrorCodes = map[string][]errorCode{
+	// storage
+	"glusterd":           {{"OSM-STORAGE-001", ""}},
+	"mount-point-sizes":  {{"OSM-STORAGE-002", ""}},
+	"lv-pool-sizes":      {{"OSM-STORAGE-003", ""}, {"OSM-STORAGE-004", "lv-pool-metadata-full"}},
+	"vg-sizes":           {{"OSM-STORAGE-005", ""}},
+	"open-file-count":    {{"OSM-STORAGE-006", ""}},
+	"geo-replication":    {{"OSM-STORAGE-007", ""}, {"OSM-STORAGE-008", "geo-replication-degraded"}},
+	"nfs-mounts":         {{"OSM-STORAGE-009", ""}, {"OSM-STORAGE-010", "nfs-mounts-unusable"}},
+	"smart-health":       {{"OSM-STORAGE-011", ""}, {"OSM-STORAGE-012", "disks-failing"}},
+	"disk-io-errors":     {{"OSM-STORAGE-013", ""}, {"OSM-STORAGE-014", "disk-io-errors"}},
+	"datastore-capacity": {{"OSM-STORAGE-015", ""}, {"OSM-STORAGE-016", "datastores-full"}},
+	// lb
+	"haproxy":           {{"OSM-LB-001", ""}, {"OSM-LB-002", "haproxy-not-running"}},
+	"haproxy-sessions":  {{"OSM-LB-003", ""}, {"OSM-LB-004", "haproxy-sessions-high"}},
+	"haproxy-backends":  {{"OSM-LB-005", ""}, {"OSM-LB-006", "haproxy-backends-degraded"}},
+	"api-load-balancer": {{"OSM-LB-007", ""}, {"OSM-LB-008", "load-balancer-calls-failed"}, {"OSM-LB-009", "load-balancer-masters-missing"}},
+	// dns
+	"dns-nslookup-kubernetes": {{"OSM-DNS-001", ""}},
+	"dns-service-node":        {{"OSM-DNS-002", ""}},
+	"dns-consistency":         {{"OSM-DNS-003", ""}, {"OSM-DNS-004", "dns-inconsistent"}},
+	"dns-srv":                 {{"OSM-DNS-005", ""}, {"OSM-DNS-006", "dns-srv-missing"}, {"OSM-DNS-007", "dns-srv-inconsistent"}},
+	// node
+	"docker-pool":       {{"OSM-NODE-001", ""}},
+	"docker-pool-rate":  {{"OSM-NODE-002", ""}},
+	"http-service":      {{"OSM-NODE-003", ""}},
+	"kubeconfig-expiry": {{"OSM-NODE-004", ""}, {"OSM-NODE-005", "kubeconfig-expiring"}},
+	"baseline-drift":    {{"OSM-NODE-006", ""}, {"OSM-NODE-007", "baseline-drifted"}},
+	"registry-trust":    {{"OSM-NODE-008", ""}, {"OSM-NODE-009", "registry-handshake-failed"}, {"OSM-NODE-010", "registry-ca-untrusted"}},
+	"kubelet-gc-config": {{"OSM-NODE-011", ""}, {"OSM-NODE-012", "kubelet-gc-inconsistent"}},
+	"eviction-headroom": {{"OSM-NODE-013", ""}, {"OSM-NODE-014", "eviction-imminent"}},
+	"pod-density":       {{"OSM-NODE-015", ""}, {"OSM-NODE-016", "pod-density-high"}},
+	"zombie-processes":  {{"OSM-NODE-017", ""}, {"OSM-NODE-018", "zombie-processes"}},
+	"runtime-processes": {{"OSM-NODE-019", ""}, {"OSM-NODE-020", "runtime-processes-stuck"}},
+	"dying-cgroups":     {{"OSM-NODE-021", ""}, {"OSM-NODE-022", "memory-cgroups-dying"}},
+	"kernel-slab-rate":  {{"OSM-NODE-023", ""}},
+	// etcd
+	"etcd-health":         {{"OSM-ETCD-001", ""}},
+	"etcd-member-health":  {{"OSM-ETCD-002", ""}, {"OSM-ETCD-003", "etcd-members-unhealthy"}},
+	"etcd-member-list":    {{"OSM-ETCD-004", ""}, {"OSM-ETCD-005", "etcd-member-lists-inconsistent"}},
+	"etcd-cert-expiry":    {{"OSM-ETCD-006", ""}, {"OSM-ETCD-007", "certificates-expiring"}},
+	"etcd-wal-fsync":      {{"OSM-ETCD-008", ""}, {"OSM-ETCD-009", "etcd-latency-high"}},
+	"etcd-backend-commit": {{"OSM-ETCD-010", ""}, {"OSM-ETCD-011", "etcd-latency-high"}},
+	"etcd-db-size-rate":   {{"OSM-ETCD-012", ""}},
+	"etcd-backup":         {{"OSM-ETCD-013", ""}, {"OSM-ETCD-014", "backup-not-found"}, {"OSM-ETCD-015", "backup-too-old"}, {"OSM-ETCD-016", "backup-too-small"}},
+	// master
+	"master-apis":            {{"OSM-MASTER-001", ""}},
+	"oc-get-nodes":           {{"OSM-MASTER-002", ""}},
+	"webconsole":             {{"OSM-MASTER-003", ""}, {"OSM-MASTER-004", "webconsole-unavailable"}},
+	"canary-app":             {{"OSM-MASTER-005", ""}, {"OSM-MASTER-006", "canary-pod-not-ready"}, {"OSM-MASTER-007", "canary-route-unreachable"}},
+	"diagnostics":            {{"OSM-MASTER-008", ""}, {"OSM-MASTER-009", "diagnostics-finding"}},
+	"external-system":        {{"OSM-MASTER-010", ""}},
+	"limits-and-quotas":      {{"OSM-MASTER-011", ""}},
+	"object-backup":          {{"OSM-MASTER-012", ""}, {"OSM-MASTER-013", "backup-not-found"}, {"OSM-MASTER-014", "backup-too-old"}, {"OSM-MASTER-015", "backup-too-small"}, {"OSM-MASTER-016", "backup-too-few-objects"}, {"OSM-MASTER-017", "backup-kinds-missing"}},
+	"cloud-provider":         {{"OSM-MASTER-018", ""}, {"OSM-MASTER-019", "cloud-provider-degraded"}},
+	"bootstrap-token-expiry": {{"OSM-MASTER-020", ""}, {"OSM-MASTER-021", "bootstrap-tokens-expiring"}},
+	// infra
+	"registry-health":      {{"OSM-INFRA-001", ""}},
+	"router-health":        {{"OSM-INFRA-002", ""}},
+	"router-restart-count": {{"OSM-INFRA-003", ""}},
+	"router-restarts-rate": {{"OSM-INFRA-004", ""}},
+	// sec
+	"anonymous-access":               {{"OSM-SEC-001", ""}, {"OSM-SEC-002", "anonymous-access-allowed"}},
+	"oauth-login":                    {{"OSM-SEC-003", ""}, {"OSM-SEC-004", "oauth-unhealthy"}},
+	"privileged-sccs":                {{"OSM-SEC-005", ""}, {"OSM-SEC-006", "privileged-sccs-granted"}},
+	"cluster-admin-service-accounts": {{"OSM-SEC-007", ""}, {"OSM-SEC-008", "cluster-admin-service-accounts"}},
+	"audit-log":                      {{"OSM-SEC-009", ""}, {"OSM-SEC-010", "audit-log-missing"}, {"OSM-SEC-011", "audit-log-stale"}},
+	"ldap-sync":                      {{"OSM-SEC-012", ""}, {"OSM-SEC-013", "ldap-never-synced"}, {"OSM-SEC-014", "ldap-sync-too-old"}},
+	// metrics
+	"hawcular-health":          {{"OSM-METRICS-001", ""}},
+	"cassandra-storage":        {{"OSM-METRICS-002", ""}, {"OSM-METRICS-003", "component-pods-missing"}, {"OSM-METRICS-004", "component-storage-full"}},
+	"cassandra-restarts":       {{"OSM-METRICS-005", ""}, {"OSM-METRICS-006", "component-pods-missing"}, {"OSM-METRICS-007", "component-pods-restarting"}},
+	"cassandra-compactions":    {{"OSM-METRICS-008", ""}, {"OSM-METRICS-009", "component-pods-missing"}, {"OSM-METRICS-010", "cassandra-compactions-pending"}},
+	"cassandra-heap":           {{"OSM-METRICS-011", ""}, {"OSM-METRICS-012", "component-pods-missing"}, {"OSM-METRICS-013", "cassandra-heap-exhausted"}},
+	"prometheus-targets":       {{"OSM-METRICS-014", ""}, {"OSM-METRICS-015", "prometheus-targets-down"}},
+	"prometheus-rules":         {{"OSM-METRICS-016", ""}, {"OSM-METRICS-017", "prometheus-rules-failing"}},
+	"prometheus-alertmanagers": {{"OSM-METRICS-018", ""}, {"OSM-METRICS-019", "prometheus-no-alertmanager"}, {"OSM-METRICS-020", "prometheus-alerts-not-sent"}},
+	// logging
+	"logging-restart-count":  {{"OSM-LOGGING-001", ""}},
+	"elasticsearch-storage":  {{"OSM-LOGGING-002", ""}, {"OSM-LOGGING-003", "component-pods-missing"}, {"OSM-LOGGING-004", "component-storage-full"}},
+	"elasticsearch-restarts": {{"OSM-LOGGING-005", ""}, {"OSM-LOGGING-006", "component-pods-missing"}, {"OSM-LOGGING-007", "component-pods-restarting"}},
+	"fluentd-buffer-size":    {{"OSM-LOGGING-008", ""}, {"OSM-LOGGING-009", "fluentd-buffer-full"}},
+	"fluentd-buffer-age":     {{"OSM-LOGGING-010", ""}, {"OSM-LOGGING-011", "fluentd-buffer-stuck"}},
+	"ingestion-lag":          {{"OSM-LOGGING-012", ""}, {"OSM-LOGGING-013", "ingestion-no-logs"}, {"OSM-LOGGING-014", "ingestion-lagging"}},
+	// time
+	"ntpd":       {{"OSM-TIME-001", ""}, {"OSM-TIME-002", "ntpd-no-peers"}, {"OSM-TIME-003", "ntpd-not-synchronized"}, {"OSM-TIME-004", "ntpd-stratum-high"}},
+	"clock-skew": {{"OSM-TIME-005", ""}, {"OSM-TIME-006", "clocks-skewed"}},
+}
+
+// returns the error code of a failure of the check with the message id,
+// the code of the other failures of the check if the message id isn't one
+// of its failure modes
+func errorCodeOf(name string, messageID string) string {
+	var code string
+	for _, c := range checkErrorCodes[strings.SplitN(name, ":", 2)[0]] {
+		if c.MessageID == messageID {
+			return c.Code
+		}
+		if len(c.MessageID) == 0 {
+			code = c.Code
+		}
+	}
+	return code
+}
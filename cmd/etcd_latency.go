@@ -0,0 +1,66 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/oscp/openshift-monitoring-cli/probes"
+	"github.com/spf13/viper"
+)
+
+// the etcd disk latency checks and their histograms
+var etcdLatencyChecks = []struct {
+	name   string
+	metric string
+}{
+	{"etcd-wal-fsync", probes.WalFsyncMetric},
+	{"etcd-backend-commit", probes.BackendCommitMetric},
+}
+
+// runs the etcd disk latency checks, the metrics of the members are read
+// once and compared with the major and minor thresholds (milliseconds) of
+// each check.
+func evalEtcdLatency(ctx context.Context) {
+	measure := func(ctx context.Context) (interface{}, error) {
+		return probes.EtcdDiskLatencies(ctx, strings.Split(viper.GetString("etcd.ips"), ","), viper.GetFloat64("etcdLatency.quantile"))
+	}
+
+	var checks []levelCheck
+	for _, check := range etcdLatencyChecks {
+		check := check
+		limit := func(category string) time.Duration {
+			return time.Duration(threshold(check.name, category)) * time.Millisecond
+		}
+		checks = append(checks, levelCheck{
+			name: check.name,
+			threshold: func(category string) string {
+				return fmt.Sprintf("%s p%.4g", limit(category), viper.GetFloat64("etcdLatency.quantile")*100)
+			},
+			measure: measure,
+			evaluate: func(ctx context.Context, members interface{}, category string) error {
+				m, ok := members.([]probes.EtcdDiskLatency)
+				if !ok {
+					return unexpectedMeasurement(check.name, members)
+				}
+				return probes.CheckEtcdDiskLatency(m, check.metric, limit(category))
+			},
+		})
+	}
+	evalLevels(ctx, checks...)
+}
@@ -0,0 +1,71 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+
+	"github.com/oscp/openshift-monitoring-cli/probes"
+	"github.com/spf13/viper"
+)
+
+// the thresholds of the kubelet and the filesystems they apply to
+type kubeletDisks struct {
+	thresholds probes.KubeletThresholds
+	nodefs     probes.Filesystem
+	imagefs    probes.Filesystem
+}
+
+// runs the gc and eviction checks of the kubelet, the node config and the
+// filesystems are read once. A node which evicts for disk pressure also
+// stops to admit pods.
+func evalKubeletDisks(ctx context.Context) {
+	measure := func(ctx context.Context) (interface{}, error) {
+		var disks kubeletDisks
+		var err error
+		if disks.thresholds, err = probes.ReadKubeletThresholds(nodeConfigPath); err != nil {
+			return disks, err
+		}
+		if disks.nodefs, err = probes.ReadFilesystem(ctx, viper.GetString("kubeletDisks.nodefs")); err != nil {
+			return disks, err
+		}
+		disks.imagefs, err = probes.ReadFilesystem(ctx, viper.GetString("kubeletDisks.imagefs"))
+		return disks, err
+	}
+
+	evalLevels(ctx, levelCheck{
+		name:       "kubelet-gc-config",
+		categories: []string{"MINOR"},
+		measure:    measure,
+		evaluate: func(ctx context.Context, disks interface{}, category string) error {
+			d, ok := disks.(kubeletDisks)
+			if !ok {
+				return unexpectedMeasurement("kubelet-gc-config", disks)
+			}
+			return probes.CheckKubeletThresholds(d.thresholds, d.nodefs, d.imagefs)
+		},
+	}, levelCheck{
+		name:      "eviction-headroom",
+		threshold: thresholdDisplay("eviction-headroom", "% free"),
+		measure:   measure,
+		evaluate: func(ctx context.Context, disks interface{}, category string) error {
+			d, ok := disks.(kubeletDisks)
+			if !ok {
+				return unexpectedMeasurement("eviction-headroom", disks)
+			}
+			return probes.CheckEvictionHeadroom(d.thresholds, d.nodefs, d.imagefs, threshold("eviction-headroom", category))
+		},
+	})
+}
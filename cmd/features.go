@@ -0,0 +1,62 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// an experimental or intrusive part of the checks which only runs when it
+// is enabled with features.<name>
+type feature struct {
+	Name        string
+	Description string
+}
+
+var features = []feature{
+	{"canary", "deploys a canary pod, service and route and calls them through the routers"},
+	{"diagnostics", "runs oc adm diagnostics as cluster admin, which creates pods on the nodes"},
+}
+
+// returns whether the feature is enabled, unknown features never are
+func featureEnabled(name string) bool {
+	return viper.GetBool("features." + name)
+}
+
+// returns the state of all known features, it is reported with the results
+// of each run
+func featureStates() map[string]bool {
+	states := map[string]bool{}
+	for _, f := range features {
+		states[f.Name] = featureEnabled(f.Name)
+	}
+	return states
+}
+
+// warns about settings in features which don't name a known feature, e.g.
+// a typo which would leave the feature disabled
+func warnUnknownFeatures() {
+	for name := range viper.GetStringMap("features") {
+		known := false
+		for _, f := range features {
+			known = known || strings.EqualFold(f.Name, name)
+		}
+		if !known {
+			log.Warning("Unknown feature", name, "in features")
+		}
+	}
+}
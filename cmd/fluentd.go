@@ -0,0 +1,55 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"time"
+
+	"github.com/oscp/openshift-monitoring-cli/probes"
+	"github.com/spf13/viper"
+)
+
+// runs the backlog checks of the local fluentd, the buffer is read once.
+// fluentd drops logs once its buffer queue is full.
+func evalFluentdBuffer(ctx context.Context) {
+	measure := func(ctx context.Context) (interface{}, error) {
+		return probes.ReadFluentdBuffer(viper.GetString("fluentdBuffer.path"))
+	}
+
+	evalLevels(ctx, levelCheck{
+		name:      "fluentd-buffer-size",
+		threshold: thresholdDisplay("fluentd-buffer-size", "MB"),
+		measure:   measure,
+		evaluate: func(ctx context.Context, buffer interface{}, category string) error {
+			b, ok := buffer.(probes.FluentdBuffer)
+			if !ok {
+				return unexpectedMeasurement("fluentd-buffer-size", buffer)
+			}
+			return probes.CheckFluentdBufferSize(b, threshold("fluentd-buffer-size", category))
+		},
+	}, levelCheck{
+		name:      "fluentd-buffer-age",
+		threshold: thresholdDisplay("fluentd-buffer-age", "m"),
+		measure:   measure,
+		evaluate: func(ctx context.Context, buffer interface{}, category string) error {
+			b, ok := buffer.(probes.FluentdBuffer)
+			if !ok {
+				return unexpectedMeasurement("fluentd-buffer-age", buffer)
+			}
+			return probes.CheckFluentdBufferAge(b, time.Duration(threshold("fluentd-buffer-age", category))*time.Minute)
+		},
+	})
+}
@@ -0,0 +1,85 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/oscp/openshift-monitoring-cli/probes"
+	"github.com/spf13/viper"
+)
+
+// returns the lag in minutes allowed for the volume, geoReplication.maxLag.<volume>
+// or thresholds.geo-replication
+func geoReplicationMaxLag(volume string, category string) int {
+	if key := "geoReplication.maxLag." + volume + "." + strings.ToLower(category); viper.IsSet(key) {
+		return viper.GetInt(key)
+	}
+	return threshold("geo-replication", category)
+}
+
+// runs the geo-replication check per volume, reported as
+// geo-replication:<volume>. The status is read once, faulty bricks are
+// majors and the sync lag is compared with the thresholds (minutes).
+func evalGeoReplication(ctx context.Context) {
+	if !checkSelected("geo-replication") {
+		return
+	}
+
+	// the sessions may be read before the checks to find the volumes
+	var m measurement
+	measure := func(ctx context.Context) (interface{}, error) {
+		return m.take(ctx, func(ctx context.Context) (interface{}, error) {
+			return probes.GeoReplicationSessions(ctx)
+		})
+	}
+
+	var volumes []string
+	if len(viper.GetString("geoReplication.volumes")) > 0 {
+		volumes = strings.Split(viper.GetString("geoReplication.volumes"), ",")
+	} else if sessions, err := measure(ctx); err != nil {
+		evalMajor(ctx, "geo-replication", "", func(ctx context.Context) error { return err })
+		return
+	} else {
+		s, _ := sessions.([]probes.GeoReplicationSession)
+		volumes = probes.GeoReplicationVolumes(s)
+	}
+
+	var checks []levelCheck
+	for _, volume := range volumes {
+		volume := volume
+		maxLag := func(category string) time.Duration {
+			return time.Duration(geoReplicationMaxLag(volume, category)) * time.Minute
+		}
+		checks = append(checks, levelCheck{
+			name: "geo-replication:" + volume,
+			threshold: func(category string) string {
+				return fmt.Sprintf("%s lag", maxLag(category))
+			},
+			measure: measure,
+			evaluate: func(ctx context.Context, sessions interface{}, category string) error {
+				s, ok := sessions.([]probes.GeoReplicationSession)
+				if !ok {
+					return unexpectedMeasurement("geo-replication", sessions)
+				}
+				return probes.CheckGeoReplication(s, volume, maxLag(category), category == "MAJOR")
+			},
+		})
+	}
+	evalLevels(ctx, checks...)
+}
@@ -0,0 +1,84 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
+// the json output of a run with the fake checker. The durations and the
+// resource usage of the checks are left out as they vary between runs.
+type goldenRun struct {
+	Output  IntegrationData `json:"output"`
+	Results []checkResult   `json:"results"`
+	// the library checks called, in order
+	Calls []string `json:"calls"`
+}
+
+// runs the checks with testdata/<scenario>.yml and compares the json output
+// with testdata/<scenario>.golden.json, go test -update rewrites them
+func TestJSONOutput(t *testing.T) {
+	defer func() { checker = libraryChecker{} }()
+
+	for _, scenario := range []string{"storage-healthy", "storage-failures"} {
+		t.Run(scenario, func(t *testing.T) {
+			viper.Reset()
+			configFile = filepath.Join("testdata", scenario+".yml")
+			initConfig()
+			fake, ok := checker.(*FakeChecker)
+			if !ok {
+				t.Fatal("fakeChecks.enabled is not set in", configFile)
+			}
+
+			executeRun(context.Background())
+
+			run := goldenRun{Output: data, Results: results, Calls: fake.Calls}
+			for i := range run.Results {
+				run.Results[i].Duration = 0
+				run.Results[i].CpuTime = 0
+				run.Results[i].DiskBlocks = 0
+			}
+			actual, err := json.MarshalIndent(run, "", "\t")
+			if err != nil {
+				t.Fatal(err)
+			}
+			actual = append(actual, '\n')
+
+			golden := filepath.Join("testdata", scenario+".golden.json")
+			if *update {
+				if err := ioutil.WriteFile(golden, actual, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			expected, err := ioutil.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(actual, expected) {
+				t.Errorf("The output differs from %s, run go test -update if the change is intended:\n%s", golden, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+
+	"github.com/oscp/openshift-monitoring-cli/probes"
+	"github.com/spf13/viper"
+)
+
+// runs the haproxy checks of the lb node type, the stats socket is read
+// once. A backend without any server up is a major, a single server down a
+// minor.
+func evalHaproxy(ctx context.Context) {
+	measure := func(ctx context.Context) (interface{}, error) {
+		return probes.ReadHaproxyStats(ctx, viper.GetString("haproxy.socket"))
+	}
+
+	evalLevels(ctx, levelCheck{
+		name:       "haproxy",
+		categories: []string{"MAJOR"},
+		measure:    measure,
+		evaluate: func(ctx context.Context, stats interface{}, category string) error {
+			return nil
+		},
+	}, levelCheck{
+		name:      "haproxy-sessions",
+		threshold: thresholdDisplay("haproxy-sessions", "%"),
+		measure:   measure,
+		evaluate: func(ctx context.Context, stats interface{}, category string) error {
+			s, ok := stats.(probes.HaproxyStats)
+			if !ok {
+				return unexpectedMeasurement("haproxy-sessions", stats)
+			}
+			return probes.CheckHaproxySessions(s, threshold("haproxy-sessions", category))
+		},
+	}, levelCheck{
+		name:    "haproxy-backends",
+		measure: measure,
+		evaluate: func(ctx context.Context, stats interface{}, category string) error {
+			s, ok := stats.(probes.HaproxyStats)
+			if !ok {
+				return unexpectedMeasurement("haproxy-backends", stats)
+			}
+			return probes.CheckHaproxyBackends(s, category == "MAJOR")
+		},
+	})
+}
@@ -0,0 +1,112 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// the HealthReport resource of deploy/healthreport-crd.yml
+const healthReportPath = "/apis/monitoring.sbb.ch/v1alpha1/healthreports"
+
+type healthReport struct {
+	ApiVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Metadata   struct {
+		Name string `json:"name"`
+	} `json:"metadata"`
+	Spec   healthReportSpec   `json:"spec"`
+	Status healthReportStatus `json:"status"`
+}
+
+type healthReportSpec struct {
+	NodeName string `json:"nodeName"`
+	NodeType string `json:"nodeType"`
+}
+
+type healthReportStatus struct {
+	Time     time.Time             `json:"time"`
+	Severity string                `json:"severity"`
+	Checks   int                   `json:"checks"`
+	Failed   int                   `json:"failed"`
+	Findings []healthReportFinding `json:"findings"`
+}
+
+type healthReportFinding struct {
+	Name       string `json:"name"`
+	Category   string `json:"category"`
+	Status     string `json:"status"`
+	Threshold  string `json:"threshold,omitempty"`
+	Summary    string `json:"summary"`
+	RunbookUrl string `json:"runbookUrl,omitempty"`
+	Hint       string `json:"hint,omitempty"`
+}
+
+// creates or updates the HealthReport of the node if healthReport.enabled is
+// set. The findings are the checks which did not pass.
+func publishHealthReport() {
+	if !viper.GetBool("healthReport.enabled") {
+		return
+	}
+	if apiClient == nil {
+		log.Error("Health reports need api.enabled.")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var report = healthReport{ApiVersion: "monitoring.sbb.ch/v1alpha1", Kind: "HealthReport"}
+	report.Metadata.Name = nodeName()
+	report.Spec = healthReportSpec{NodeName: nodeName(), NodeType: viper.GetString("node.type")}
+	report.Status = healthReportStatus{Time: time.Now().UTC(), Checks: len(results), Findings: []healthReportFinding{}}
+
+	var failed []checkResult
+	for _, r := range results {
+		if r.Status == "OK" {
+			continue
+		}
+		if r.Status == "FAILED" {
+			failed = append(failed, r)
+		}
+		report.Status.Findings = append(report.Status.Findings, healthReportFinding{
+			Name:       r.Name,
+			Category:   r.Category,
+			Status:     r.Status,
+			Threshold:  r.Threshold,
+			Summary:    r.Summary,
+			RunbookUrl: r.RunbookUrl,
+			Hint:       r.Hint,
+		})
+	}
+	report.Status.Failed = len(failed)
+	report.Status.Severity = worstSeverity(failed)
+
+	var existing healthReport
+	var err error
+	if apiClient.Get(ctx, healthReportPath+"/"+report.Metadata.Name, &existing) == nil {
+		// a merge patch replaces the findings as a whole
+		var patch = map[string]interface{}{"spec": report.Spec, "status": report.Status}
+		err = apiClient.Do(ctx, "PATCH", healthReportPath+"/"+report.Metadata.Name, "application/merge-patch+json", patch, nil)
+	} else {
+		err = apiClient.Do(ctx, "POST", healthReportPath, "application/json", report, nil)
+	}
+	if err != nil {
+		log.Error("Not able to publish the health report", err)
+	}
+}
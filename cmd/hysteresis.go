@@ -0,0 +1,93 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// the percentage thresholds with hysteresis by the check they belong to. A
+// check which failed in the previous run only clears once its value is
+// hysteresis.margin percentage points better than the threshold, so a
+// value hovering around the threshold doesn't flap every run.
+var hysteresisChecks = map[string]string{
+	"mount-point-sizes":     "mount-point-sizes",
+	"lv-pool-sizes":         "lv-pool-sizes",
+	"lv-pool-metadata":      "lv-pool-sizes",
+	"vg-sizes":              "vg-sizes",
+	"docker-pool":           "docker-pool",
+	"cassandra-storage":     "cassandra-storage",
+	"cassandra-heap":        "cassandra-heap",
+	"elasticsearch-storage": "elasticsearch-storage",
+	"haproxy-sessions":      "haproxy-sessions",
+	"datastore-capacity":    "datastore-capacity",
+	"fluentd-buffer-size":   "fluentd-buffer-size",
+	"eviction-headroom":     "eviction-headroom",
+	"pod-density":           "pod-density",
+}
+
+// the thresholds of free space, they fail below the threshold and clear
+// above it
+var freeSpaceThresholds = map[string]bool{
+	"vg-sizes":          true,
+	"eviction-headroom": true,
+}
+
+// the checks which failed in the previous run by category and name. The
+// category is the one before the quiet hours, so the state is kept while a
+// check is downgraded to INFO.
+var failedBefore map[string]bool
+
+// remembers the failed checks of the previous run for the hysteresis
+func planHysteresis(previous *storedRun) {
+	if previous == nil {
+		previous = lastRun
+	}
+	failedBefore = map[string]bool{}
+	if previous == nil {
+		return
+	}
+	for _, r := range previous.Results {
+		if r.Status != "FAILED" {
+			continue
+		}
+		category := r.Category
+		if len(r.QuietCategory) > 0 {
+			category = r.QuietCategory
+		}
+		failedBefore[category+"/"+r.Name] = true
+	}
+}
+
+// returns the threshold of the category at which a check failing in the
+// previous run clears, thresholds.<check>.<level>Clear or the threshold
+// moved by hysteresis.margin
+func clearThreshold(check string, category string, value int) int {
+	name, ok := hysteresisChecks[check]
+	if !ok || !viper.GetBool("hysteresis.enabled") || !failedBefore[mapSeverity(name, category)+"/"+name] {
+		return value
+	}
+
+	key := "thresholds." + check + "." + strings.ToLower(category) + "Clear"
+	if viper.IsSet(key) {
+		return viper.GetInt(key)
+	}
+	if freeSpaceThresholds[check] {
+		return value + viper.GetInt("hysteresis.margin")
+	}
+	return value - viper.GetInt("hysteresis.margin")
+}
@@ -0,0 +1,115 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/oscp/openshift-monitoring-cli/probes"
+	"github.com/spf13/viper"
+)
+
+// applies the in-cluster mode if inCluster.enabled is set. The api is called
+// with the service account of the pod and the node type is derived from the
+// labels of the node the pod runs on, see deploy/daemonset.yml.
+func initInCluster() {
+	if !viper.GetBool("inCluster.enabled") {
+		return
+	}
+
+	nodeName := os.Getenv(viper.GetString("inCluster.nodeNameEnv"))
+	if len(nodeName) == 0 {
+		log.Fatal("In-cluster mode needs the node name in", viper.GetString("inCluster.nodeNameEnv"))
+	}
+	viper.Set("node.name", nodeName)
+	viper.Set("api.enabled", true)
+	viper.Set("api.kubeconfig", "")
+
+	client, err := probes.NewAPIClient("")
+	if err != nil {
+		log.Fatal("Not able to use the service account:", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var node struct {
+		Metadata struct {
+			Labels map[string]string `json:"labels"`
+		} `json:"metadata"`
+	}
+	if err := client.Get(ctx, "/api/v1/nodes/"+nodeName, &node); err != nil {
+		log.Fatal("Not able to get node", nodeName, err)
+	}
+
+	nodeLabels = node.Metadata.Labels
+	nodeType := nodeTypeOf(node.Metadata.Labels)
+	log.Info("Running in-cluster on node", nodeName, "as node type", nodeType)
+	viper.Set("node.type", nodeType)
+	applyNodeTypeDefaults()
+}
+
+// the labels of the node in the in-cluster mode
+var nodeLabels map[string]string
+
+// returns the first node type whose label selector of
+// inCluster.nodeTypeLabels matches, node otherwise
+func nodeTypeOf(labels map[string]string) string {
+	// the node types of the config are more specific than the built-in ones
+	for _, nodeType := range append(customNodeTypes(), "master", "storage", "infra") {
+		for _, selector := range strings.Split(viper.GetString("inCluster.nodeTypeLabels."+nodeType), ",") {
+			parts := strings.SplitN(selector, "=", 2)
+			value, ok := labels[parts[0]]
+			if len(parts[0]) > 0 && ok && (len(parts) == 1 || parts[1] == value) {
+				return nodeType
+			}
+		}
+	}
+	return "node"
+}
+
+// returns the name of the node the checks run for, which is not the hostname
+// of the pod in the in-cluster mode
+func nodeName() string {
+	if name := viper.GetString("node.name"); len(name) > 0 {
+		return name
+	}
+	hostname, _ := os.Hostname()
+	return hostname
+}
+
+// posts the run to inCluster.pushUrl, if configured, so the results of all
+// nodes can be aggregated
+func pushRun() {
+	url := viper.GetString("inCluster.pushUrl")
+	if len(url) == 0 {
+		return
+	}
+
+	var run = storedRun{
+		Time:     time.Now().UTC(),
+		Hostname: nodeName(),
+		NodeType: viper.GetString("node.type"),
+		Events:   data.Events,
+		Results:  results,
+		Features: data.Features,
+	}
+	if err := postJSON(url, nil, run); err != nil {
+		log.Error("Not able to push results to", url, err)
+	}
+}
@@ -0,0 +1,48 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/oscp/openshift-monitoring-cli/probes"
+	"github.com/spf13/viper"
+)
+
+// runs the ingestion lag check of the logging, the lag is measured once
+// for both levels
+func evalIngestionLag(ctx context.Context) {
+	evalLevels(ctx, levelCheck{
+		name:      "ingestion-lag",
+		threshold: thresholdDisplay("ingestion-lag", "m"),
+		measure: func(ctx context.Context) (interface{}, error) {
+			hostname := viper.GetString("ingestionLag.hostname")
+			if len(hostname) == 0 {
+				hostname, _ = os.Hostname()
+			}
+			return probes.IngestionLag(ctx, viper.GetString("ingestionLag.url"), viper.GetString("ingestionLag.token"),
+				viper.GetString("ingestionLag.index"), hostname, viper.GetBool("ingestionLag.insecure"))
+		},
+		evaluate: func(ctx context.Context, lag interface{}, category string) error {
+			l, ok := lag.(time.Duration)
+			if !ok {
+				return unexpectedMeasurement("ingestion-lag", lag)
+			}
+			return probes.CheckIngestionLag(l, time.Duration(threshold("ingestion-lag", category))*time.Minute)
+		},
+	})
+}
@@ -0,0 +1,258 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/oscp/openshift-monitoring-cli/probes"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+)
+
+var initConfigPath string
+var initConfigForce bool
+
+var initConfigCmd = &cobra.Command{
+	Use:   "init-config",
+	Short: "Writes a starter config.yml based on the local host.",
+	Long: `Probes the local host for its node type, kubeconfigs and, on masters, the etcd, router
+and registry addresses and writes a config.yml with commented defaults.`,
+	RunE: runInitConfig,
+}
+
+func init() {
+	rootCmd.AddCommand(initConfigCmd)
+
+	initConfigCmd.Flags().StringVar(&initConfigPath, "path", "", "file to write (default config.yml next to the executable)")
+	initConfigCmd.Flags().BoolVar(&initConfigForce, "force", false, "overwrite an existing file")
+}
+
+const (
+	masterConfigPath = "/etc/origin/master/master-config.yaml"
+	etcdConfigPath   = "/etc/etcd/etcd.conf"
+	nodeConfigPath   = "/etc/origin/node/node-config.yaml"
+)
+
+// the facts detected on the local host
+type hostFacts struct {
+	NodeType    string
+	Kubeconfigs []string
+	EtcdIps     string
+	RouterIps   string
+	RegistryIp  string
+}
+
+const starterConfig = `# generated by openshift-monitoring-cli init-config, see config.template.yml for all settings
+configVersion: {{ configVersion }}
+node:
+  # detected: {{ if .NodeType }}{{ .NodeType }}{{ else }}unknown, set to node, master or storage{{ end }}
+  type: {{ or .NodeType "node" }}
+logging:
+  level: info
+{{- if eq .NodeType "master" }}
+etcd:
+  ips: {{ or .EtcdIps "<https://ip:port>,<https://ip:port>" }}
+router:
+  ips: {{ or .RouterIps "<ip>,<ip>" }}
+{{- if .RegistryIp }}
+registry:
+  ip: {{ .RegistryIp }}
+{{- else }}
+# registry:
+#   ip: <ip>
+{{- end }}
+# externalSystem:
+#   url: <https://url>
+# hawkular:
+#   ip: <ip>
+limitsAndQuotas:
+  projectsWithoutLimits: 0
+{{- end }}
+{{- if .Kubeconfigs }}
+kubeconfigs:
+  # detected kubeconfigs which are checked for expiring credentials
+  paths: {{ join .Kubeconfigs "," }}
+{{- end }}
+# store:
+#   path: /var/lib/openshift-monitoring-cli
+#   keep: 100
+`
+
+func runInitConfig(cmd *cobra.Command, args []string) error {
+	path := initConfigPath
+	if len(path) == 0 {
+		ex, err := os.Executable()
+		if err != nil {
+			return err
+		}
+		path = filepath.Join(filepath.Dir(ex), "config.yml")
+	}
+
+	if _, err := os.Stat(path); err == nil && !initConfigForce {
+		return fmt.Errorf("%s already exists, use --force to overwrite it.", path)
+	}
+
+	facts := detectHostFacts()
+
+	var out bytes.Buffer
+	tmpl := template.Must(template.New("config").Funcs(template.FuncMap{"join": strings.Join, "configVersion": func() int { return currentConfigVersion }}).Parse(starterConfig))
+	if err := tmpl.Execute(&out, facts); err != nil {
+		return err
+	}
+
+	if err := ioutil.WriteFile(path, out.Bytes(), 0640); err != nil {
+		return err
+	}
+
+	fmt.Println("Wrote", path)
+	return nil
+}
+
+func detectHostFacts() hostFacts {
+	var facts hostFacts
+
+	switch {
+	case fileExists(masterConfigPath):
+		facts.NodeType = "master"
+	case fileExists(nodeConfigPath):
+		facts.NodeType = "node"
+	case fileExists(etcdConfigPath):
+		facts.NodeType = "etcd"
+	case fileExists("/var/lib/glusterd") || fileExists("/usr/sbin/glusterd"):
+		facts.NodeType = "storage"
+	case fileExists("/etc/haproxy/haproxy.cfg"):
+		facts.NodeType = "lb"
+	}
+
+	for _, path := range []string{
+		"/etc/origin/master/admin.kubeconfig",
+		"/etc/origin/master/openshift-master.kubeconfig",
+		"/etc/origin/node/node.kubeconfig",
+		"/etc/origin/node/bootstrap.kubeconfig",
+	} {
+		if fileExists(path) {
+			facts.Kubeconfigs = append(facts.Kubeconfigs, path)
+		}
+	}
+
+	if facts.NodeType == "master" {
+		facts.EtcdIps = detectEtcdUrls()
+		facts.RouterIps = joinFields(detectOc("get", "pods", "-n", "default", "-l", "router", "-o", "jsonpath={.items[*].status.hostIP}"))
+		facts.RegistryIp = detectOc("get", "service", "docker-registry", "-n", "default", "-o", "jsonpath={.spec.clusterIP}")
+	}
+	if facts.NodeType == "etcd" {
+		facts.EtcdIps = detectLocalEtcdUrls()
+	}
+
+	return facts
+}
+
+// reads the etcd client urls from the master config
+func detectEtcdUrls() string {
+	content, err := ioutil.ReadFile(probes.HostPath(masterConfigPath))
+	if err != nil {
+		log.Warning("Not able to read", masterConfigPath, err)
+		return ""
+	}
+
+	var config struct {
+		EtcdClientInfo struct {
+			Urls []string `yaml:"urls"`
+		} `yaml:"etcdClientInfo"`
+	}
+	if err := yaml.Unmarshal(content, &config); err != nil {
+		log.Warning("Not able to parse", masterConfigPath, err)
+		return ""
+	}
+
+	return strings.Join(config.EtcdClientInfo.Urls, ",")
+}
+
+// reads the cloud provider and its config from the api server arguments of
+// the master config
+func detectCloudProvider() (string, string) {
+	content, err := ioutil.ReadFile(probes.HostPath(masterConfigPath))
+	if err != nil {
+		log.Warning("Not able to read", masterConfigPath, err)
+		return "", ""
+	}
+
+	var config struct {
+		KubernetesMasterConfig struct {
+			ApiServerArguments map[string][]string `yaml:"apiServerArguments"`
+		} `yaml:"kubernetesMasterConfig"`
+	}
+	if err := yaml.Unmarshal(content, &config); err != nil {
+		log.Warning("Not able to parse", masterConfigPath, err)
+		return "", ""
+	}
+
+	args := config.KubernetesMasterConfig.ApiServerArguments
+	if len(args["cloud-provider"]) == 0 || len(args["cloud-config"]) == 0 {
+		return "", ""
+	}
+	return args["cloud-provider"][0], args["cloud-config"][0]
+}
+
+// reads the advertised client urls of the local etcd member
+func detectLocalEtcdUrls() string {
+	content, err := ioutil.ReadFile(probes.HostPath(etcdConfigPath))
+	if err != nil {
+		log.Warning("Not able to read", etcdConfigPath, err)
+		return ""
+	}
+	for _, line := range strings.Split(string(content), "\n") {
+		if strings.HasPrefix(line, "ETCD_ADVERTISE_CLIENT_URLS=") {
+			return strings.Trim(strings.TrimPrefix(line, "ETCD_ADVERTISE_CLIENT_URLS="), "\"' ")
+		}
+	}
+	return ""
+}
+
+// runs oc and returns its output or an empty string if it failed
+func detectOc(args ...string) string {
+	cmd := exec.Command(probes.CommandPath("oc"), args...)
+	start := time.Now()
+	out, err := cmd.Output()
+	var stderr []byte
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		stderr = exitErr.Stderr
+	}
+	probes.TraceCommand(cmd, out, stderr, err, time.Since(start))
+	if err != nil {
+		log.Warning("Not able to run oc", strings.Join(args, " "), err)
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// joins the whitespace separated fields of the output of a command with commas
+func joinFields(out string) string {
+	return strings.Join(strings.Fields(out), ",")
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(probes.HostPath(path))
+	return err == nil
+}
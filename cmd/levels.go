@@ -0,0 +1,162 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// levelCheck is a check with a threshold per level. measure runs once per
+// run and evaluate decides each level on the measurement, so an expensive
+// check isn't run for the major and again for the minor.
+type levelCheck struct {
+	name string
+	// the levels of the check, the major and the minor if empty
+	categories []string
+	// the threshold shown in the results, e.g. "90%", optional
+	threshold func(category string) string
+	measure   func(ctx context.Context) (interface{}, error)
+	evaluate  func(ctx context.Context, measurement interface{}, category string) error
+	// the measured value shown in the results, e.g. "93%", optional
+	value func(measurement interface{}) string
+}
+
+// evaluates the levels of the checks one check after the other. The checks
+// share one measurement, taken with the measure of the first check, so
+// checks reading the same command output or api run it once. The
+// measurement is taken lazily so a replayed or cached level doesn't cause it.
+func evalLevels(ctx context.Context, checks ...levelCheck) {
+	if len(checks) == 0 {
+		return
+	}
+	var m measurement
+	measure := checks[0].measure
+
+	for _, check := range checks {
+		check := check
+		categories := check.categories
+		if len(categories) == 0 {
+			categories = []string{"MAJOR", "MINOR"}
+		}
+
+		for _, category := range categories {
+			category := category
+			var threshold string
+			if check.threshold != nil {
+				threshold = check.threshold(category)
+			}
+			eval(ctx, category, check.name, threshold, func(ctx context.Context) error {
+				value, err := m.take(ctx, measure)
+				if err != nil {
+					return err
+				}
+				if check.value != nil {
+					recordValue(ctx, check.value(value))
+				}
+				return check.evaluate(ctx, value, category)
+			})
+		}
+	}
+}
+
+// measurement keeps the measurement shared by the levels of a check. It is
+// only kept once measure returned in time, as the timeout of a level
+// abandons measure in its goroutine. The next level then measures again.
+type measurement struct {
+	mu    sync.Mutex
+	taken bool
+	value interface{}
+	err   error
+}
+
+// returns the kept measurement or takes it with measure
+func (m *measurement) take(ctx context.Context, measure func(ctx context.Context) (interface{}, error)) (interface{}, error) {
+	m.mu.Lock()
+	if m.taken {
+		defer m.mu.Unlock()
+		return m.value, m.err
+	}
+	m.mu.Unlock()
+
+	value, err := measure(ctx)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.taken && ctx.Err() == nil {
+		m.taken, m.value, m.err = true, value, err
+	}
+	return value, err
+}
+
+type measuredValueKey struct{}
+
+// the value measured by a check, eval passes it in the context of the check
+type measuredValue struct {
+	mu    sync.Mutex
+	value string
+}
+
+// records the value measured by the check running with ctx, it is shown in
+// its result
+func recordValue(ctx context.Context, value string) {
+	if v, ok := ctx.Value(measuredValueKey{}).(*measuredValue); ok {
+		v.mu.Lock()
+		defer v.mu.Unlock()
+		v.value = value
+	}
+}
+
+func (v *measuredValue) get() string {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.value
+}
+
+// returns the error of an evaluate func for a measurement of the wrong type
+func unexpectedMeasurement(name string, value interface{}) error {
+	return fmt.Errorf("Unexpected measurement %T of %s", value, name)
+}
+
+// returns a level check for a check which takes its threshold and can't
+// return a measurement, like those of the library. It is run with the minor
+// threshold and only if that fails again with the major threshold, as the
+// minor threshold is the stricter one.
+func thresholdCheck(name string, unit string, check func(ctx context.Context, category string) error) levelCheck {
+	return levelCheck{
+		name:      name,
+		threshold: thresholdDisplay(name, unit),
+		measure: func(ctx context.Context) (interface{}, error) {
+			// the failure of the minor is the measurement, it isn't a
+			// failure of the major yet
+			return check(ctx, "MINOR"), nil
+		},
+		evaluate: func(ctx context.Context, measurement interface{}, category string) error {
+			minorErr, _ := measurement.(error)
+			if category == "MINOR" || minorErr == nil {
+				return minorErr
+			}
+			return check(ctx, category)
+		},
+	}
+}
+
+// returns the threshold of the level followed by unit
+func thresholdDisplay(name string, unit string) func(category string) string {
+	return func(category string) string {
+		return fmt.Sprint(threshold(name, category)) + unit
+	}
+}
@@ -0,0 +1,71 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+var listChecksOutput string
+
+var listChecksCmd = &cobra.Command{
+	Use:   "list-checks",
+	Short: "Lists all checks with their node types, severities and parameters.",
+	RunE:  runListChecks,
+}
+
+func init() {
+	rootCmd.AddCommand(listChecksCmd)
+
+	listChecksCmd.Flags().StringVarP(&listChecksOutput, "output", "o", "text", "output format (text|json)")
+}
+
+func runListChecks(cmd *cobra.Command, args []string) error {
+	for i := range checkCatalog {
+		checkCatalog[i].Errors = checkErrorCodes[checkCatalog[i].Name]
+	}
+
+	switch listChecksOutput {
+	case "json":
+		OutputJSON(checkCatalog)
+		return nil
+	case "text":
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "CHECK\tNODE TYPES\tTAGS\tSEVERITIES\tERROR CODES\tDESCRIPTION")
+		for _, c := range checkCatalog {
+			var severities []string
+			for _, s := range c.Severities {
+				if len(s.Threshold) > 0 {
+					severities = append(severities, s.Category+" "+s.Threshold)
+				} else {
+					severities = append(severities, s.Category)
+				}
+			}
+			var codes []string
+			for _, e := range c.Errors {
+				codes = append(codes, e.Code)
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", c.Name, strings.Join(c.NodeTypes, ","), strings.Join(c.Tags, ","),
+				strings.Join(severities, ", "), strings.Join(codes, ","), c.Description)
+		}
+		return tw.Flush()
+	}
+	return fmt.Errorf("Unknown output format %s.", listChecksOutput)
+}
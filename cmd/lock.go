@@ -0,0 +1,130 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/oscp/openshift-monitoring-cli/probes"
+	"github.com/spf13/viper"
+)
+
+// a run which holds the lock file
+type runLockHolder struct {
+	Pid   int
+	Start time.Time
+}
+
+var errLocked = errors.New("Another run is active")
+
+// returns the lock file from lock.path or run.lock in store.path
+func lockFile() string {
+	if path := viper.GetString("lock.path"); len(path) > 0 {
+		return path
+	}
+	if dir := viper.GetString("store.path"); len(dir) > 0 {
+		return filepath.Join(dir, "run.lock")
+	}
+	return filepath.Join(os.TempDir(), "openshift-monitoring-cli.lock")
+}
+
+// acquireRunLock takes an exclusive lock on the lock file, so overlapping
+// cron invocations don't run concurrently, see openRunLock. If another run
+// holds the lock, errLocked is returned together with that run. The lock is
+// released by the returned function or when the process exits, so a run
+// which died doesn't leave a stale lock behind.
+func acquireRunLock() (func(), *runLockHolder, error) {
+	path := lockFile()
+
+	f, locked, err := openRunLock(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Could not open lock file %s: %v", path, err)
+	}
+	if !locked {
+		holder := readLockHolder(f)
+		f.Close()
+		return nil, holder, errLocked
+	}
+
+	f.Truncate(0)
+	fmt.Fprintf(f, "%d %d\n", os.Getpid(), time.Now().Unix())
+	f.Sync()
+
+	// the file is kept, a run which opened it in the meantime would
+	// otherwise lock a removed file
+	return func() {
+		f.Truncate(0)
+		f.Close()
+	}, nil, nil
+}
+
+func readLockHolder(f *os.File) *runLockHolder {
+	content, err := ioutil.ReadAll(f)
+	if err != nil {
+		return nil
+	}
+	fields := strings.Fields(string(content))
+	if len(fields) != 2 {
+		return nil
+	}
+	pid, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return nil
+	}
+	start, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil {
+		return nil
+	}
+	return &runLockHolder{Pid: pid, Start: time.Unix(start, 0)}
+}
+
+// handles a run which is skipped because of an active run. The skipped run
+// is reported with an info event, only if the active run is active longer
+// than lock.maxAge a major event is reported.
+func reportLockedRun(holder *runLockHolder) {
+	maxAge := time.Duration(viper.GetInt("lock.maxAge")) * time.Minute
+	if holder == nil || time.Since(holder.Start) < maxAge {
+		skipped := probes.NewMessage("run-locked")
+		if holder != nil {
+			skipped = probes.NewMessage("run-locked-by", holder.Pid, holder.Start.Format(time.RFC3339))
+		}
+		log.Info(skipped.Error())
+
+		event := createEvent(skipped)
+		event["category"] = "INFO"
+		data.Events = []EventData{event}
+		results = []checkResult{{Name: "run-lock", Category: "INFO", Status: "SKIPPED", Summary: skipped.Error(), MessageID: probes.MessageID(skipped)}}
+		data.HealthScore = healthScore(results)
+		outputResults()
+		return
+	}
+
+	err := fmt.Errorf("Previous run %d is still active since %s, longer than %s.", holder.Pid, holder.Start.Format(time.RFC3339), maxAge)
+	log.Error("MAJOR:", err.Error())
+
+	event := createEvent(err)
+	event["category"] = "MAJOR"
+	data.Events = []EventData{event}
+	results = []checkResult{{Name: "run-lock", Category: "MAJOR", Status: "FAILED", Threshold: viper.GetString("lock.maxAge") + "m", Summary: err.Error()}}
+	data.HealthScore = healthScore(results)
+	outputResults()
+}
@@ -0,0 +1,43 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"os"
+	"syscall"
+)
+
+// ERROR_SHARING_VIOLATION, which the syscall package doesn't define
+const errorSharingViolation syscall.Errno = 32
+
+// opens the lock file without sharing write access, so a second run can't
+// open it for writing while the handle is open. If another run holds the
+// lock, the file is returned only for reading its holder.
+func openRunLock(path string) (*os.File, bool, error) {
+	name, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return nil, false, err
+	}
+	handle, err := syscall.CreateFile(name, syscall.GENERIC_READ|syscall.GENERIC_WRITE, syscall.FILE_SHARE_READ, nil,
+		syscall.OPEN_ALWAYS, syscall.FILE_ATTRIBUTE_NORMAL, 0)
+	if err == errorSharingViolation {
+		f, err := os.Open(path)
+		return f, false, err
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return os.NewFile(uintptr(handle), path), true, nil
+}
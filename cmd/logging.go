@@ -0,0 +1,248 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/op/go-logging"
+	"github.com/spf13/viper"
+)
+
+func initLogging() {
+	var format = logging.MustStringFormatter(
+		`%{color}%{time:15:04:05.000} %{shortfunc} - %{level:.4s} %{id:03x}%{color:reset} %{message}`,
+	)
+	var fileFormat = logging.MustStringFormatter(
+		`%{time:2006-01-02T15:04:05.000Z07:00} %{shortfunc} - %{level:.4s} %{id:03x} %{message}`,
+	)
+	jsonFormat := viper.GetString("logging.format") == "json"
+
+	stdOutBackend := logging.NewLogBackend(os.Stdout, "", 0)
+	var stdOut = logging.NewBackendFormatter(stdOutBackend, format)
+	if jsonFormat {
+		stdOut = newJSONBackend(os.Stdout)
+	}
+	logging.SetBackend(stdOut)
+
+	var backends []logging.Backend
+
+	if runtime.GOOS != "windows" {
+		sysLogBackend, err := logging.NewSyslogBackend("openshift-monitoring-cli")
+
+		if err != nil {
+			log.Warning("Wasn't able to initialize syslog.", err)
+		} else {
+			backends = append(backends, logging.NewBackendFormatter(sysLogBackend, format))
+		}
+	}
+
+	if path := viper.GetString("logging.file"); len(path) > 0 {
+		file := &rotatingFile{
+			path:       path,
+			maxSize:    int64(viper.GetInt("logging.maxSize")) * 1024 * 1024,
+			maxBackups: viper.GetInt("logging.maxBackups"),
+		}
+		if jsonFormat {
+			backends = append(backends, newJSONBackend(file))
+		} else {
+			backends = append(backends, logging.NewBackendFormatter(logging.NewLogBackend(file, "", 0), fileFormat))
+		}
+	}
+
+	// without debug the messages only go to syslog and the log file
+	if debug || len(backends) == 0 {
+		backends = append(backends, stdOut)
+	}
+	logging.SetBackend(logging.MultiLogger(backends...))
+
+	setLogLevels()
+}
+
+// the logging modules, their level can be set with logging.levels.<module>
+var logModules = []string{"openshift-monitoring-cli", "probes", "notify"}
+
+// sets the level of all modules from --log-level or logging.level and
+// overrides it with logging.levels. The level trace is debug plus a log of
+// every command and http request the checks execute.
+func setLogLevels() {
+	name := logLevel
+	if len(name) == 0 {
+		name = viper.GetString("logging.level")
+	}
+	level, trace := parseLogLevel(name)
+	for _, module := range logModules {
+		logging.SetLevel(level, module)
+	}
+
+	for module, name := range viper.GetStringMapString("logging.levels") {
+		moduleLevel, moduleTrace := parseLogLevel(name)
+		logging.SetLevel(moduleLevel, module)
+		trace = trace || moduleTrace
+	}
+
+	// trace messages are logged on debug level
+	if trace {
+		logging.SetLevel(logging.DEBUG, "trace")
+	} else {
+		logging.SetLevel(logging.INFO, "trace")
+	}
+}
+
+// parses a level name, unknown names fall back to info
+func parseLogLevel(name string) (level logging.Level, trace bool) {
+	if len(name) == 0 {
+		return logging.INFO, false
+	}
+	if strings.ToLower(name) == "trace" {
+		return logging.DEBUG, true
+	}
+
+	level, err := logging.LogLevel(name)
+	if err != nil {
+		log.Warning("Unknown log level", name, "using info.")
+		return logging.INFO, false
+	}
+	return level, false
+}
+
+// the fields added to every json log message, e.g. the check which is running
+var logFields = map[string]string{}
+var logFieldsLock sync.Mutex
+
+// sets a field of the json log messages, an empty value removes it
+func setLogField(key string, value string) {
+	logFieldsLock.Lock()
+	defer logFieldsLock.Unlock()
+
+	if len(value) == 0 {
+		delete(logFields, key)
+	} else {
+		logFields[key] = value
+	}
+}
+
+// writes every message as a json object on its own line, so the logs can be
+// shipped to elasticsearch or splunk without parsing
+type jsonBackend struct {
+	lock     sync.Mutex
+	w        io.Writer
+	hostname string
+}
+
+func newJSONBackend(w io.Writer) *jsonBackend {
+	hostname, _ := os.Hostname()
+	return &jsonBackend{w: w, hostname: hostname}
+}
+
+func (b *jsonBackend) Log(level logging.Level, calldepth int, rec *logging.Record) error {
+	var entry = map[string]interface{}{
+		"time":      rec.Time.Format(time.RFC3339Nano),
+		"level":     level.String(),
+		"module":    rec.Module,
+		"message":   rec.Message(),
+		"hostname":  b.hostname,
+		"node_type": viper.GetString("node.type"),
+	}
+
+	logFieldsLock.Lock()
+	for k, v := range logFields {
+		entry[k] = v
+	}
+	logFieldsLock.Unlock()
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	_, err = b.w.Write(append(line, '\n'))
+	return err
+}
+
+// appends to a log file and rotates it to path.1 ... path.<maxBackups> as
+// soon as it grows over maxSize bytes. A maxSize of zero disables rotation.
+type rotatingFile struct {
+	lock       sync.Mutex
+	path       string
+	maxSize    int64
+	maxBackups int
+	file       *os.File
+	size       int64
+}
+
+func (f *rotatingFile) Write(p []byte) (int, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if f.file == nil {
+		if err := f.open(); err != nil {
+			return 0, err
+		}
+	}
+
+	if f.maxSize > 0 && f.size+int64(len(p)) > f.maxSize && f.size > 0 {
+		if err := f.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+func (f *rotatingFile) open() error {
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0640)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	f.file = file
+	f.size = info.Size()
+	return nil
+}
+
+func (f *rotatingFile) rotate() error {
+	f.file.Close()
+	f.file = nil
+
+	if f.maxBackups > 0 {
+		os.Remove(fmt.Sprintf("%s.%d", f.path, f.maxBackups))
+		for i := f.maxBackups - 1; i > 0; i-- {
+			os.Rename(fmt.Sprintf("%s.%d", f.path, i), fmt.Sprintf("%s.%d", f.path, i+1))
+		}
+		if err := os.Rename(f.path, f.path+".1"); err != nil {
+			return err
+		}
+	} else if err := os.Remove(f.path); err != nil {
+		return err
+	}
+
+	return f.open()
+}
@@ -0,0 +1,126 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	annotationPrefix  = "monitoring.sbb.ch/"
+	nodeConditionType = "MonitoringHealthy"
+)
+
+// publishes the worst severity and the failed checks of the run on the node
+// object as annotations (nodeStatus.annotations) and as node condition
+// (nodeStatus.condition). Both need api.enabled or the in-cluster mode.
+func publishNodeStatus() {
+	annotations, condition := viper.GetBool("nodeStatus.annotations"), viper.GetBool("nodeStatus.condition")
+	if !annotations && !condition {
+		return
+	}
+	if apiClient == nil {
+		log.Error("Publishing the node status needs api.enabled.")
+		return
+	}
+
+	// the status is also published if the run was aborted
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var failed []checkResult
+	for _, r := range results {
+		if r.Status == "FAILED" {
+			failed = append(failed, r)
+		}
+	}
+	var names []string
+	for _, r := range failed {
+		names = append(names, r.Name)
+	}
+	worst := worstSeverity(failed)
+	now := time.Now().UTC().Format(time.RFC3339)
+	path := "/api/v1/nodes/" + nodeName()
+
+	if annotations {
+		var patch = map[string]interface{}{
+			"metadata": map[string]interface{}{
+				"annotations": map[string]string{
+					annotationPrefix + "severity":      worst,
+					annotationPrefix + "failed-checks": strings.Join(names, ","),
+					annotationPrefix + "last-run":      now,
+				},
+			},
+		}
+		if err := apiClient.Do(ctx, "PATCH", path, "application/merge-patch+json", patch, nil); err != nil {
+			log.Error("Not able to annotate node", nodeName(), err)
+		}
+	}
+
+	if condition {
+		if err := patchNodeCondition(ctx, path, worst, names, now); err != nil {
+			log.Error("Not able to set condition of node", nodeName(), err)
+		}
+	}
+}
+
+// sets the condition, its transition time only changes with its status
+func patchNodeCondition(ctx context.Context, path string, worst string, names []string, now string) error {
+	var node struct {
+		Status struct {
+			Conditions []struct {
+				Type               string `json:"type"`
+				Status             string `json:"status"`
+				LastTransitionTime string `json:"lastTransitionTime"`
+			} `json:"conditions"`
+		} `json:"status"`
+	}
+	if err := apiClient.Get(ctx, path, &node); err != nil {
+		return err
+	}
+
+	var status, reason, message = "True", "ChecksPassed", "All checks passed"
+	if len(names) > 0 {
+		status, reason = "False", "Checks"+strings.Title(strings.ToLower(worst))
+		message = fmt.Sprintf("%d check(s) failed: %s", len(names), strings.Join(names, ", "))
+	}
+
+	transition := now
+	for _, c := range node.Status.Conditions {
+		if c.Type == nodeConditionType && c.Status == status {
+			transition = c.LastTransitionTime
+		}
+	}
+
+	// conditions are merged by their type
+	var patch = map[string]interface{}{
+		"status": map[string]interface{}{
+			"conditions": []map[string]string{{
+				"type":               nodeConditionType,
+				"status":             status,
+				"reason":             reason,
+				"message":            message,
+				"lastHeartbeatTime":  now,
+				"lastTransitionTime": transition,
+			}},
+		},
+	}
+	return apiClient.Do(ctx, "PATCH", path+"/status", "application/strategic-merge-patch+json", patch, nil)
+}
@@ -0,0 +1,77 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// the node types with checks in the cli, nodeTypes.<name> defines further
+// node types on top of them
+var builtinNodeTypes = []string{"master", "node", "infra", "storage", "lb", "etcd"}
+
+// returns the built-in node types whose checks a node type runs, the
+// nodeTypes.<name>.base of a node type defined in the config (default node)
+func nodeTypeBases(nodeType string) []string {
+	if !viper.IsSet("nodeTypes." + nodeType) {
+		return []string{nodeType}
+	}
+	if base := viper.GetString("nodeTypes." + nodeType + ".base"); len(base) > 0 {
+		return strings.Split(base, ",")
+	}
+	return []string{"node"}
+}
+
+// reports if the configured node type is or is based on the built-in node type
+func isNodeType(nodeType string) bool {
+	return containsString(nodeTypeBases(viper.GetString("node.type")), nodeType)
+}
+
+// returns the node types defined in the config
+func customNodeTypes() []string {
+	var names []string
+	for name := range viper.GetStringMap("nodeTypes") {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// applies nodeTypes.<name>.thresholds of the configured node type on top of
+// the configured thresholds
+func applyCustomNodeType() {
+	nodeType := viper.GetString("node.type")
+	if !viper.IsSet("nodeTypes." + nodeType) {
+		return
+	}
+	for _, base := range nodeTypeBases(nodeType) {
+		if !containsString(builtinNodeTypes, base) {
+			log.Warning("Node type", nodeType, "is based on the unknown node type", base)
+		}
+	}
+	for key, value := range flattenSettings("thresholds.", viper.GetStringMap("nodeTypes."+nodeType+".thresholds")) {
+		viper.Set(key, value)
+	}
+}
+
+// reports if a check is in nodeTypes.<name>.checks of the configured node
+// type, all checks of its bases are if the list is empty
+func nodeTypeRunsCheck(name string) bool {
+	checks := viper.GetString("nodeTypes." + viper.GetString("node.type") + ".checks")
+	return len(checks) == 0 || containsString(strings.Split(checks, ","), name)
+}
@@ -0,0 +1,192 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/op/go-logging"
+	"github.com/oscp/openshift-monitoring-cli/probes"
+	"github.com/spf13/viper"
+)
+
+// the changes of a run compared to the previous stored run
+type runChanges struct {
+	Time     time.Time
+	Hostname string
+	NodeType string
+	// failed checks which did not fail in the previous run
+	New []checkResult
+	// checks which failed in the previous run and are healthy now
+	Resolved []checkResult
+	// all failed checks of this run
+	Failed []checkResult
+}
+
+// a notifier informs an external system about the changes of a run
+type notifier interface {
+	Name() string
+	Notify(changes runChanges) error
+}
+
+// returns all notifiers which are enabled in the configuration
+func configuredNotifiers() []notifier {
+	var notifiers []notifier
+
+	if viper.GetBool("notifications.email.enabled") {
+		notifiers = append(notifiers, newEmailNotifier())
+	}
+	if viper.GetBool("notifications.pagerduty.enabled") {
+		notifiers = append(notifiers, newPagerDutyNotifier())
+	}
+	if viper.GetBool("notifications.opsgenie.enabled") {
+		notifiers = append(notifiers, newOpsgenieNotifier())
+	}
+	if viper.GetBool("notifications.snmp.enabled") {
+		notifiers = append(notifiers, newSnmpNotifier())
+	}
+	if viper.GetBool("notifications.kubernetesEvents.enabled") {
+		notifiers = append(notifiers, newKubeEventNotifier())
+	}
+	for _, kind := range []string{"slack", "mattermost", "teams"} {
+		if viper.GetBool("notifications." + kind + ".enabled") {
+			notifiers = append(notifiers, newWebhookNotifier(kind))
+		}
+	}
+
+	return notifiers
+}
+
+// returns the latest stored run or nil if there is none
+func previousRun() *storedRun {
+	if len(viper.GetString("store.path")) == 0 {
+		return nil
+	}
+
+	runs, err := loadRuns(time.Time{}, time.Time{})
+	if err != nil || len(runs) == 0 {
+		return nil
+	}
+	return &runs[len(runs)-1]
+}
+
+// compares the current results with the previous run. Without a previous
+// run all failed checks are new. Aborted checks keep their previous state.
+func compareRuns(previous *storedRun, current []checkResult) runChanges {
+	var changes = runChanges{Time: time.Now(), NodeType: viper.GetString("node.type")}
+	changes.Hostname = nodeName()
+
+	failedBefore := map[string]bool{}
+	failedNow := map[string]bool{}
+	if previous != nil {
+		for _, r := range previous.Results {
+			if r.Status == "FAILED" {
+				failedBefore[r.Category+"/"+r.Name] = true
+			}
+		}
+	}
+
+	for _, r := range current {
+		// checks which didn't run keep their state
+		if (r.Status == "ABORTED" || r.Status == "SKIPPED") && failedBefore[r.Category+"/"+r.Name] {
+			failedNow[r.Category+"/"+r.Name] = true
+		}
+		if r.Status != "FAILED" {
+			continue
+		}
+		failedNow[r.Category+"/"+r.Name] = true
+		changes.Failed = append(changes.Failed, r)
+		if !failedBefore[r.Category+"/"+r.Name] {
+			changes.New = append(changes.New, r)
+		}
+	}
+
+	if previous != nil {
+		for _, r := range previous.Results {
+			if r.Status == "FAILED" && !failedNow[r.Category+"/"+r.Name] {
+				changes.Resolved = append(changes.Resolved, r)
+			}
+		}
+	}
+
+	return changes
+}
+
+// sends the changes of the current run to all configured notifiers
+func sendNotifications(previous *storedRun) {
+	notifiers := configuredNotifiers()
+	if len(notifiers) == 0 {
+		return
+	}
+
+	changes := compareRuns(previous, results)
+	for _, n := range notifiers {
+		if err := n.Notify(changes); err != nil {
+			notifyLog.Error("Sending notification over", n.Name(), "failed:", err)
+		}
+	}
+}
+
+// returns the results of the given category
+func filterCategory(results []checkResult, category string) []checkResult {
+	var filtered []checkResult
+	for _, r := range results {
+		if r.Category == category {
+			filtered = append(filtered, r)
+		}
+	}
+	return filtered
+}
+
+// the key which identifies the alerts of a check on a host
+func dedupKey(hostname string, r checkResult) string {
+	return "openshift-monitoring-cli/" + hostname + "/" + r.Name
+}
+
+var notifyLog = logging.MustGetLogger("notify")
+
+var notificationClient = &http.Client{Timeout: 30 * time.Second, Transport: probes.TraceTransport(nil)}
+
+// posts body as json and fails on any non 2xx response
+func postJSON(url string, headers map[string]string, body interface{}) error {
+	content, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", url, bytes.NewReader(content))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := notificationClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s returned status code %d", url, resp.StatusCode)
+	}
+	return nil
+}
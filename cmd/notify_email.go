@@ -0,0 +1,140 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// sends one email per run if new events of at least MAJOR appeared
+type emailNotifier struct {
+	host     string
+	port     int
+	from     string
+	to       []string
+	username string
+	password string
+	// none, starttls or tls
+	tlsMode            string
+	insecureSkipVerify bool
+}
+
+func newEmailNotifier() *emailNotifier {
+	return &emailNotifier{
+		host:               viper.GetString("notifications.email.host"),
+		port:               viper.GetInt("notifications.email.port"),
+		from:               viper.GetString("notifications.email.from"),
+		to:                 strings.Split(viper.GetString("notifications.email.to"), ","),
+		username:           viper.GetString("notifications.email.username"),
+		password:           viper.GetString("notifications.email.password"),
+		tlsMode:            viper.GetString("notifications.email.tls"),
+		insecureSkipVerify: viper.GetBool("notifications.email.insecureSkipVerify"),
+	}
+}
+
+func (n *emailNotifier) Name() string {
+	return "email"
+}
+
+func (n *emailNotifier) Notify(changes runChanges) error {
+	newMajors := filterAtLeast(changes.New, "MAJOR")
+	if len(newMajors) == 0 {
+		return nil
+	}
+
+	subject := fmt.Sprintf("[%s] %d new problem(s) on %s (%s)", worstSeverity(newMajors), len(newMajors), changes.Hostname, changes.NodeType)
+
+	var body bytes.Buffer
+	fmt.Fprintf(&body, "New problems on %s at %s:\r\n\r\n", changes.Hostname, changes.Time.Format("2006-01-02 15:04:05 MST"))
+	for _, r := range newMajors {
+		fmt.Fprintf(&body, "- [%s] %s: %s\r\n", r.Category, r.Name, r.Summary)
+		if len(r.Hint) > 0 {
+			fmt.Fprintf(&body, "  Hint: %s\r\n", r.Hint)
+		}
+		if len(r.RunbookUrl) > 0 {
+			fmt.Fprintf(&body, "  Runbook: %s\r\n", r.RunbookUrl)
+		}
+	}
+	fmt.Fprintf(&body, "\r\nAll failing checks:\r\n\r\n")
+	for _, r := range changes.Failed {
+		fmt.Fprintf(&body, "- [%s] %s: %s\r\n", r.Category, r.Name, r.Summary)
+	}
+
+	return n.send(subject, body.String())
+}
+
+func (n *emailNotifier) send(subject string, body string) error {
+	addr := net.JoinHostPort(n.host, strconv.Itoa(n.port))
+	tlsConfig := &tls.Config{ServerName: n.host, InsecureSkipVerify: n.insecureSkipVerify}
+
+	var client *smtp.Client
+	if n.tlsMode == "tls" {
+		conn, err := tls.Dial("tcp", addr, tlsConfig)
+		if err != nil {
+			return err
+		}
+		if client, err = smtp.NewClient(conn, n.host); err != nil {
+			return err
+		}
+	} else {
+		var err error
+		if client, err = smtp.Dial(addr); err != nil {
+			return err
+		}
+	}
+	defer client.Close()
+
+	if n.tlsMode == "starttls" {
+		if err := client.StartTLS(tlsConfig); err != nil {
+			return err
+		}
+	}
+
+	if len(n.username) > 0 {
+		if err := client.Auth(smtp.PlainAuth("", n.username, n.password, n.host)); err != nil {
+			return err
+		}
+	}
+
+	if err := client.Mail(n.from); err != nil {
+		return err
+	}
+	for _, to := range n.to {
+		if err := client.Rcpt(to); err != nil {
+			return err
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "From: %s\r\nTo: %s\r\nSubject: %s\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n%s",
+		n.from, strings.Join(n.to, ", "), subject, body)
+
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
@@ -0,0 +1,115 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// creates a kubernetes event on the node per failed major or critical check,
+// so it is shown by oc describe node. Like the kubelet, repeated failures
+// increase the count of the existing event instead of creating new ones.
+type kubeEventNotifier struct {
+	namespace string
+}
+
+type kubeEvent struct {
+	Metadata struct {
+		Name      string `json:"name"`
+		Namespace string `json:"namespace"`
+	} `json:"metadata"`
+	InvolvedObject struct {
+		Kind string `json:"kind"`
+		Name string `json:"name"`
+		UID  string `json:"uid"`
+	} `json:"involvedObject"`
+	Reason         string `json:"reason"`
+	Message        string `json:"message"`
+	Type           string `json:"type"`
+	FirstTimestamp string `json:"firstTimestamp"`
+	LastTimestamp  string `json:"lastTimestamp"`
+	Count          int    `json:"count"`
+	Source         struct {
+		Component string `json:"component"`
+		Host      string `json:"host"`
+	} `json:"source"`
+}
+
+// characters which are not allowed in event names
+var invalidEventName = regexp.MustCompile(`[^a-z0-9.-]+`)
+
+func newKubeEventNotifier() *kubeEventNotifier {
+	return &kubeEventNotifier{namespace: viper.GetString("notifications.kubernetesEvents.namespace")}
+}
+
+func (n *kubeEventNotifier) Name() string {
+	return "kubernetes-events"
+}
+
+func (n *kubeEventNotifier) Notify(changes runChanges) error {
+	if apiClient == nil {
+		return errors.New("Kubernetes events need api.enabled.")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	for _, r := range filterAtLeast(changes.Failed, "MAJOR") {
+		if err := n.record(ctx, changes, r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (n *kubeEventNotifier) record(ctx context.Context, changes runChanges, r checkResult) error {
+	name := invalidEventName.ReplaceAllString(strings.ToLower(changes.Hostname+"."+r.Name), "-")
+	path := "/api/v1/namespaces/" + n.namespace + "/events"
+	now := changes.Time.UTC().Format(time.RFC3339)
+
+	var existing kubeEvent
+	if err := apiClient.Get(ctx, path+"/"+name, &existing); err == nil {
+		var patch = map[string]interface{}{
+			"message":       r.Category + " " + r.Name + ": " + r.Summary,
+			"lastTimestamp": now,
+			"count":         existing.Count + 1,
+		}
+		return apiClient.Do(ctx, "PATCH", path+"/"+name, "application/merge-patch+json", patch, nil)
+	}
+
+	var event kubeEvent
+	event.Metadata.Name = name
+	event.Metadata.Namespace = n.namespace
+	event.InvolvedObject.Kind = "Node"
+	event.InvolvedObject.Name = changes.Hostname
+	// the kubelet uses the node name as uid of its node events
+	event.InvolvedObject.UID = changes.Hostname
+	event.Reason = "CheckFailed"
+	event.Message = r.Category + " " + r.Name + ": " + r.Summary
+	event.Type = "Warning"
+	event.FirstTimestamp = now
+	event.LastTimestamp = now
+	event.Count = 1
+	event.Source.Component = "openshift-monitoring-cli"
+	event.Source.Host = changes.Hostname
+
+	return apiClient.Do(ctx, "POST", path, "application/json", event, nil)
+}
@@ -0,0 +1,95 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// creates an opsgenie alert per failed major or critical check and closes
+// it again as soon as the check is healthy
+type opsgenieNotifier struct {
+	url    string
+	apiKey string
+}
+
+type opsgenieAlert struct {
+	Message     string   `json:"message"`
+	Alias       string   `json:"alias"`
+	Description string   `json:"description"`
+	Source      string   `json:"source"`
+	Priority    string   `json:"priority"`
+	Tags        []string `json:"tags"`
+	// remediation of the check
+	Details map[string]string `json:"details,omitempty"`
+}
+
+type opsgenieClose struct {
+	Source string `json:"source"`
+}
+
+func newOpsgenieNotifier() *opsgenieNotifier {
+	return &opsgenieNotifier{
+		url:    strings.TrimSuffix(viper.GetString("notifications.opsgenie.url"), "/"),
+		apiKey: viper.GetString("notifications.opsgenie.apiKey"),
+	}
+}
+
+func (n *opsgenieNotifier) Name() string {
+	return "opsgenie"
+}
+
+// all failed majors are created on every run, opsgenie deduplicates them by alias
+func (n *opsgenieNotifier) Notify(changes runChanges) error {
+	headers := map[string]string{"Authorization": "GenieKey " + n.apiKey}
+
+	for _, r := range filterAtLeast(changes.Failed, "MAJOR") {
+		priority := "P2"
+		if r.Category == "CRITICAL" {
+			priority = "P1"
+		}
+
+		alert := opsgenieAlert{
+			Message:     changes.Hostname + ": " + r.Summary,
+			Alias:       dedupKey(changes.Hostname, r),
+			Description: r.Summary,
+			Source:      changes.Hostname,
+			Priority:    priority,
+			Tags:        []string{"openshift", changes.NodeType, r.Name},
+			Details:     map[string]string{},
+		}
+		if len(r.Hint) > 0 {
+			alert.Details["hint"] = r.Hint
+		}
+		if len(r.RunbookUrl) > 0 {
+			alert.Details["runbook_url"] = r.RunbookUrl
+		}
+		if err := postJSON(n.url+"/v2/alerts", headers, alert); err != nil {
+			return err
+		}
+	}
+
+	for _, r := range filterAtLeast(changes.Resolved, "MAJOR") {
+		closeUrl := n.url + "/v2/alerts/" + url.PathEscape(dedupKey(changes.Hostname, r)) + "/close?identifierType=alias"
+		if err := postJSON(closeUrl, headers, opsgenieClose{Source: changes.Hostname}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,105 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"github.com/spf13/viper"
+)
+
+// triggers a pagerduty alert per failed major or critical check and
+// resolves it again as soon as the check is healthy
+type pagerDutyNotifier struct {
+	url        string
+	routingKey string
+}
+
+type pagerDutyEvent struct {
+	RoutingKey  string            `json:"routing_key"`
+	EventAction string            `json:"event_action"`
+	DedupKey    string            `json:"dedup_key"`
+	Payload     *pagerDutyPayload `json:"payload,omitempty"`
+	Links       []pagerDutyLink   `json:"links,omitempty"`
+}
+
+type pagerDutyLink struct {
+	Href string `json:"href"`
+	Text string `json:"text"`
+}
+
+type pagerDutyPayload struct {
+	Summary   string `json:"summary"`
+	Source    string `json:"source"`
+	Severity  string `json:"severity"`
+	Component string `json:"component"`
+	Group     string `json:"group"`
+	// remediation hint of the check
+	CustomDetails map[string]string `json:"custom_details,omitempty"`
+}
+
+func newPagerDutyNotifier() *pagerDutyNotifier {
+	return &pagerDutyNotifier{
+		url:        viper.GetString("notifications.pagerduty.url"),
+		routingKey: viper.GetString("notifications.pagerduty.routingKey"),
+	}
+}
+
+func (n *pagerDutyNotifier) Name() string {
+	return "pagerduty"
+}
+
+// all failed majors are triggered on every run, pagerduty deduplicates them by key
+func (n *pagerDutyNotifier) Notify(changes runChanges) error {
+	for _, r := range filterAtLeast(changes.Failed, "MAJOR") {
+		severity := "error"
+		if r.Category == "CRITICAL" {
+			severity = "critical"
+		}
+
+		event := pagerDutyEvent{
+			RoutingKey:  n.routingKey,
+			EventAction: "trigger",
+			DedupKey:    dedupKey(changes.Hostname, r),
+			Payload: &pagerDutyPayload{
+				Summary:   r.Summary,
+				Source:    changes.Hostname,
+				Severity:  severity,
+				Component: r.Name,
+				Group:     changes.NodeType,
+			},
+		}
+		if len(r.Hint) > 0 {
+			event.Payload.CustomDetails = map[string]string{"hint": r.Hint}
+		}
+		if len(r.RunbookUrl) > 0 {
+			event.Links = []pagerDutyLink{{Href: r.RunbookUrl, Text: "Runbook"}}
+		}
+		if err := postJSON(n.url, nil, event); err != nil {
+			return err
+		}
+	}
+
+	for _, r := range filterAtLeast(changes.Resolved, "MAJOR") {
+		event := pagerDutyEvent{
+			RoutingKey:  n.routingKey,
+			EventAction: "resolve",
+			DedupKey:    dedupKey(changes.Hostname, r),
+		}
+		if err := postJSON(n.url, nil, event); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
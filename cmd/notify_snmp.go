@@ -0,0 +1,133 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"strings"
+	"time"
+
+	"github.com/soniah/gosnmp"
+	"github.com/spf13/viper"
+)
+
+// sends a trap as defined in mib/OPENSHIFT-MONITORING-MIB.txt for every new
+// and every resolved major or critical event
+type snmpNotifier struct {
+	client        *gosnmp.GoSNMP
+	enterpriseOid string
+}
+
+// the oid of the trap, as defined by snmpTrapOID.0
+const snmpTrapOid = ".1.3.6.1.6.3.1.1.4.1.0"
+
+func newSnmpNotifier() *snmpNotifier {
+	client := &gosnmp.GoSNMP{
+		Target:    viper.GetString("notifications.snmp.target"),
+		Port:      uint16(viper.GetInt("notifications.snmp.port")),
+		Community: viper.GetString("notifications.snmp.community"),
+		Version:   gosnmp.Version2c,
+		Timeout:   10 * time.Second,
+		Retries:   2,
+	}
+
+	if viper.GetString("notifications.snmp.version") == "3" {
+		client.Version = gosnmp.Version3
+		client.SecurityModel = gosnmp.UserSecurityModel
+		params := &gosnmp.UsmSecurityParameters{
+			UserName:                 viper.GetString("notifications.snmp.username"),
+			AuthenticationProtocol:   gosnmp.NoAuth,
+			PrivacyProtocol:          gosnmp.NoPriv,
+			AuthenticationPassphrase: viper.GetString("notifications.snmp.authPassphrase"),
+			PrivacyPassphrase:        viper.GetString("notifications.snmp.privPassphrase"),
+		}
+		client.MsgFlags = gosnmp.NoAuthNoPriv
+
+		switch strings.ToUpper(viper.GetString("notifications.snmp.authProtocol")) {
+		case "MD5":
+			params.AuthenticationProtocol = gosnmp.MD5
+			client.MsgFlags = gosnmp.AuthNoPriv
+		case "SHA":
+			params.AuthenticationProtocol = gosnmp.SHA
+			client.MsgFlags = gosnmp.AuthNoPriv
+		}
+
+		if client.MsgFlags == gosnmp.AuthNoPriv {
+			switch strings.ToUpper(viper.GetString("notifications.snmp.privProtocol")) {
+			case "DES":
+				params.PrivacyProtocol = gosnmp.DES
+				client.MsgFlags = gosnmp.AuthPriv
+			case "AES":
+				params.PrivacyProtocol = gosnmp.AES
+				client.MsgFlags = gosnmp.AuthPriv
+			}
+		}
+
+		client.SecurityParameters = params
+	}
+
+	return &snmpNotifier{
+		client:        client,
+		enterpriseOid: strings.TrimSuffix(viper.GetString("notifications.snmp.enterpriseOid"), "."),
+	}
+}
+
+func (n *snmpNotifier) Name() string {
+	return "snmp"
+}
+
+func (n *snmpNotifier) Notify(changes runChanges) error {
+	raised := filterAtLeast(changes.New, "MAJOR")
+	resolved := filterAtLeast(changes.Resolved, "MAJOR")
+	if len(raised) == 0 && len(resolved) == 0 {
+		return nil
+	}
+
+	if err := n.client.Connect(); err != nil {
+		return err
+	}
+	defer n.client.Conn.Close()
+
+	for _, r := range raised {
+		if err := n.sendTrap(".1.1", changes, r); err != nil {
+			return err
+		}
+	}
+	for _, r := range resolved {
+		if err := n.sendTrap(".1.2", changes, r); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// sends the notification with the given oid below the enterprise oid
+func (n *snmpNotifier) sendTrap(notification string, changes runChanges, r checkResult) error {
+	objects := n.enterpriseOid + ".2"
+
+	trap := gosnmp.SnmpTrap{
+		Variables: []gosnmp.SnmpPDU{
+			{Name: snmpTrapOid, Type: gosnmp.ObjectIdentifier, Value: n.enterpriseOid + notification},
+			{Name: objects + ".1.0", Type: gosnmp.OctetString, Value: changes.Hostname},
+			{Name: objects + ".2.0", Type: gosnmp.OctetString, Value: changes.NodeType},
+			{Name: objects + ".3.0", Type: gosnmp.OctetString, Value: r.Name},
+			{Name: objects + ".4.0", Type: gosnmp.OctetString, Value: r.Category},
+			{Name: objects + ".5.0", Type: gosnmp.OctetString, Value: r.Summary},
+		},
+	}
+
+	_, err := n.client.SendTrap(trap)
+	return err
+}
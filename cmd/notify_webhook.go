@@ -0,0 +1,183 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+const defaultWebhookTemplate = `{{ .Category }} on {{ .Hostname }} ({{ .NodeType }})
+{{ range .New }}:red_circle: {{ .Name }}: {{ .Summary }}{{ if .RunbookUrl }} (runbook: {{ .RunbookUrl }}){{ end }}
+{{ end }}{{ range .Resolved }}:white_check_mark: {{ .Name }} resolved
+{{ end }}`
+
+// the data a webhook message template is rendered with
+type webhookMessage struct {
+	Hostname string
+	NodeType string
+	Category string
+	New      []checkResult
+	Resolved []checkResult
+}
+
+// posts one chat message per severity with new and resolved checks. slack
+// and mattermost share the same incoming webhook format, teams uses cards.
+type webhookNotifier struct {
+	kind string
+	url  string
+	// channel (slack) or webhook url (teams) per lower case severity
+	routes    map[string]string
+	template  *template.Template
+	rateLimit int
+}
+
+func newWebhookNotifier(kind string) *webhookNotifier {
+	prefix := "notifications." + kind + "."
+
+	text := viper.GetString(prefix + "template")
+	if len(text) == 0 {
+		text = defaultWebhookTemplate
+	}
+
+	routes := viper.GetStringMapString(prefix + "channels")
+	if kind == "teams" {
+		routes = viper.GetStringMapString(prefix + "urls")
+	}
+
+	tmpl, err := template.New(kind).Parse(text)
+	if err != nil {
+		notifyLog.Error("Invalid", kind, "template, using the default:", err)
+		tmpl = template.Must(template.New(kind).Parse(defaultWebhookTemplate))
+	}
+
+	return &webhookNotifier{
+		kind:      kind,
+		url:       viper.GetString(prefix + "url"),
+		routes:    routes,
+		template:  tmpl,
+		rateLimit: viper.GetInt(prefix + "rateLimit"),
+	}
+}
+
+func (n *webhookNotifier) Name() string {
+	return n.kind
+}
+
+func (n *webhookNotifier) Notify(changes runChanges) error {
+	for i := len(severities) - 1; i >= 0; i-- {
+		category := severities[i]
+		var msg = webhookMessage{
+			Hostname: changes.Hostname,
+			NodeType: changes.NodeType,
+			Category: category,
+			New:      filterCategory(changes.New, category),
+			Resolved: filterCategory(changes.Resolved, category),
+		}
+		if len(msg.New) == 0 && len(msg.Resolved) == 0 {
+			continue
+		}
+
+		if !n.allow() {
+			notifyLog.Warning("Rate limit of", n.kind, "notifications reached, dropping", category, "message.")
+			continue
+		}
+
+		var text bytes.Buffer
+		if err := n.template.Execute(&text, msg); err != nil {
+			return err
+		}
+
+		if err := n.post(category, text.String()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (n *webhookNotifier) post(category string, text string) error {
+	route := n.routes[strings.ToLower(category)]
+
+	if n.kind == "teams" {
+		url := n.url
+		if len(route) > 0 {
+			url = route
+		}
+
+		color := "FFA500"
+		if severityRank(category) >= severityRank("MAJOR") {
+			color = "FF0000"
+		}
+		return postJSON(url, nil, map[string]string{
+			"@type":      "MessageCard",
+			"@context":   "http://schema.org/extensions",
+			"summary":    category + " events",
+			"themeColor": color,
+			"text":       strings.Replace(text, "\n", "\n\n", -1),
+		})
+	}
+
+	var body = map[string]string{"text": text}
+	if len(route) > 0 {
+		body["channel"] = route
+	}
+	return postJSON(n.url, nil, body)
+}
+
+// reports if another message may be sent within the rate limit of messages
+// per hour. The sent timestamps are persisted in the result store, so the
+// limit also holds over multiple cron runs.
+func (n *webhookNotifier) allow() bool {
+	if n.rateLimit <= 0 {
+		return true
+	}
+
+	var sent []time.Time
+	var file string
+	if dir := viper.GetString("store.path"); len(dir) > 0 {
+		file = filepath.Join(dir, "ratelimit-"+n.kind+".json")
+		if content, err := ioutil.ReadFile(file); err == nil {
+			json.Unmarshal(content, &sent)
+		}
+	}
+
+	var recent []time.Time
+	for _, t := range sent {
+		if time.Since(t) < time.Hour {
+			recent = append(recent, t)
+		}
+	}
+	if len(recent) >= n.rateLimit {
+		return false
+	}
+	recent = append(recent, time.Now())
+
+	if len(file) > 0 {
+		content, _ := json.Marshal(recent)
+		if err := os.MkdirAll(filepath.Dir(file), 0750); err == nil {
+			ioutil.WriteFile(file, content, 0640)
+		}
+	}
+	return true
+}
@@ -0,0 +1,65 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/oscp/openshift-monitoring-cli/probes"
+)
+
+// the peers of ntpd or the error if ntpd is not answering
+type ntpPeers struct {
+	peers []probes.NtpPeer
+	err   error
+}
+
+// runs the ntpd check: a stopped ntpd is a minor, but a running ntpd which
+// is not synchronized or whose stratum is not below the threshold fails
+// with the category of the threshold, as it keeps the clock drifting
+// unnoticed.
+func evalNtp(ctx context.Context) {
+	evalLevels(ctx, levelCheck{
+		name: "ntpd",
+		threshold: func(category string) string {
+			return fmt.Sprintf("stratum < %d", threshold("ntpd", category))
+		},
+		measure: func(ctx context.Context) (interface{}, error) {
+			// ntpd not answering isn't a failure of the major
+			peers, err := probes.NtpPeers(ctx)
+			return ntpPeers{peers, err}, nil
+		},
+		evaluate: func(ctx context.Context, measurement interface{}, category string) error {
+			p, ok := measurement.(ntpPeers)
+			if !ok {
+				return unexpectedMeasurement("ntpd", measurement)
+			}
+			if category == "MINOR" {
+				if err := checker.CheckNtpd(); err != nil {
+					return err
+				}
+			}
+			if p.err != nil {
+				// ntpd is not answering, which is only reported as minor
+				if category == "MAJOR" {
+					return nil
+				}
+				return p.err
+			}
+			return probes.CheckNtpSync(p.peers, threshold("ntpd", category))
+		},
+	})
+}
@@ -0,0 +1,122 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// the MonitoringCheckConfig resource of deploy/monitoringcheckconfig-crd.yml
+const checkConfigPath = "/apis/monitoring.sbb.ch/v1alpha1/monitoringcheckconfigs"
+
+type checkConfigList struct {
+	Items []checkConfig `json:"items"`
+}
+
+type checkConfig struct {
+	Metadata struct {
+		Name string `json:"name"`
+	} `json:"metadata"`
+	Spec struct {
+		// empty matches all node types
+		NodeTypes    []string               `json:"nodeTypes"`
+		NodeSelector map[string]string      `json:"nodeSelector"`
+		Settings     map[string]interface{} `json:"settings"`
+	} `json:"spec"`
+}
+
+// the settings applied by the last reconcile and the values they replaced
+var appliedSettings = map[string]interface{}{}
+var replacedSettings = map[string]interface{}{}
+
+// applies the settings of the MonitoringCheckConfig objects which match the
+// node, if operator.enabled is set. Settings which are no longer set by any
+// object are reset to their value from config.yml. Runs before every run of
+// the daemon and of single runs, so changed objects take effect with the next run.
+func reconcileCheckConfigs() {
+	if !viper.GetBool("operator.enabled") {
+		return
+	}
+	if apiClient == nil {
+		log.Error("The operator mode needs api.enabled or inCluster.enabled.")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var list checkConfigList
+	if err := apiClient.Get(ctx, checkConfigPath, &list); err != nil {
+		log.Error("Not able to list the check configs, keeping the current settings:", err)
+		return
+	}
+
+	sort.Slice(list.Items, func(i, j int) bool { return list.Items[i].Metadata.Name < list.Items[j].Metadata.Name })
+
+	var settings = map[string]interface{}{}
+	var names []string
+	for _, config := range list.Items {
+		if !checkConfigMatches(config) {
+			continue
+		}
+		names = append(names, config.Metadata.Name)
+		for key, value := range flattenSettings("", config.Spec.Settings) {
+			settings[strings.ToLower(key)] = value
+		}
+	}
+
+	if reflect.DeepEqual(settings, appliedSettings) {
+		return
+	}
+
+	for key := range appliedSettings {
+		if _, ok := settings[key]; !ok {
+			viper.Set(key, replacedSettings[key])
+			delete(replacedSettings, key)
+		}
+	}
+	for key, value := range settings {
+		if _, ok := replacedSettings[key]; !ok {
+			replacedSettings[key] = viper.Get(key)
+		}
+		viper.Set(key, value)
+	}
+	appliedSettings = settings
+
+	loadSeverityMappings()
+	loadQuietHours()
+
+	content, _ := json.Marshal(settings)
+	log.Info("Applied check configs", strings.Join(names, ", "), string(content))
+}
+
+func checkConfigMatches(config checkConfig) bool {
+	if len(config.Spec.NodeTypes) > 0 && !containsString(config.Spec.NodeTypes, viper.GetString("node.type")) {
+		return false
+	}
+	for key, value := range config.Spec.NodeSelector {
+		if nodeLabels[key] != value {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,369 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// the result of a single check run, independent of the output format
+type checkResult struct {
+	Name      string `json:"name"`
+	Category  string `json:"category"`
+	Status    string `json:"status"`
+	Threshold string `json:"threshold,omitempty"`
+	// the measured value if the check records one, see recordValue
+	Value   string `json:"value,omitempty"`
+	Summary string `json:"summary,omitempty"`
+	// the id of the summary in the message catalog, see messageID
+	MessageID string `json:"message_id,omitempty"`
+	// the code of the failure mode, see checkErrorCodes
+	ErrorCode string        `json:"error_code,omitempty"`
+	Duration  time.Duration `json:"duration"`
+	// cpu time and disk blocks of the check and its commands
+	CpuTime    time.Duration `json:"cpu_time,omitempty"`
+	DiskBlocks int64         `json:"disk_blocks,omitempty"`
+	// the result was taken from the cache instead of running the check
+	Cached bool `json:"cached,omitempty"`
+	// the check kept failing in the daemon and is probed less often, see
+	// breaker
+	CircuitOpen bool `json:"circuit_open,omitempty"`
+	// remediation of a failed check, see remediation.<check> in the config
+	RunbookUrl string `json:"runbook_url,omitempty"`
+	Hint       string `json:"hint,omitempty"`
+	// new or still-failing for failed checks compared to the previous run
+	State string `json:"state,omitempty"`
+	// subsystem of a failed check and whether it is a likely root-cause
+	// or a symptom of a failure in another subsystem
+	Subsystem string `json:"subsystem,omitempty"`
+	Cause     string `json:"cause,omitempty"`
+	// the category before the quiet hours downgraded the check to INFO,
+	// see quietHoursCategory
+	QuietCategory string `json:"quiet_category,omitempty"`
+}
+
+// all check results of the current run in execution order
+var results []checkResult
+
+const (
+	colorReset  = "\x1b[0m"
+	colorRed    = "\x1b[31m"
+	colorGreen  = "\x1b[32m"
+	colorYellow = "\x1b[33m"
+)
+
+func validOutputFormat(format string) bool {
+	switch format {
+	case "json", "text", "table", "junit", "facts":
+		return true
+	}
+	return false
+}
+
+// the data available to templates given by --output-template
+type templateData struct {
+	IntegrationData
+	Hostname string
+	NodeType string
+	Time     time.Time
+	Results  []checkResult
+}
+
+func outputResults() {
+	if len(outputTemplate) > 0 {
+		if err := OutputTemplate(os.Stdout, outputTemplate); err != nil {
+			log.Error("Error rendering output template", outputTemplate, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	switch outputFormat {
+	case "text", "table":
+		OutputText(os.Stdout, results, isTerminal(os.Stdout))
+	case "junit":
+		OutputJUnit(os.Stdout, viper.GetString("node.type"), results)
+	case "facts":
+		OutputJSON(factsOf(viper.GetString("node.type"), results))
+	default:
+		OutputJSON(data)
+	}
+}
+
+func OutputJSON(data interface{}) {
+	var output []byte
+	var err error
+
+	if pretty {
+		output, err = json.MarshalIndent(data, "", "\t")
+	} else {
+		output, err = json.Marshal(data)
+	}
+
+	if err != nil {
+		log.Errorf("Error outputting JSON (%s).", err)
+	}
+
+	if string(output) == "null" {
+		fmt.Print("[]")
+	} else {
+		fmt.Print(string(output))
+	}
+}
+
+// renders the current run through the given text/template file
+func OutputTemplate(w io.Writer, path string) error {
+	tmpl, err := template.New(filepath.Base(path)).Funcs(template.FuncMap{
+		"join": strings.Join,
+		"json": func(v interface{}) (string, error) {
+			out, err := json.Marshal(v)
+			return string(out), err
+		},
+	}).ParseFiles(path)
+	if err != nil {
+		return err
+	}
+
+	var td = templateData{
+		IntegrationData: data,
+		NodeType:        viper.GetString("node.type"),
+		Time:            time.Now(),
+		Results:         results,
+	}
+	td.Hostname = nodeName()
+
+	return tmpl.Execute(w, td)
+}
+
+// prints a human readable table of the results, failed checks are colored
+// by their category if color is set
+func OutputText(w io.Writer, results []checkResult, color bool) {
+	// the colors are added after the columns are aligned, tabwriter would
+	// count their escape sequences as width
+	var table bytes.Buffer
+	tw := tabwriter.NewWriter(&table, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "CHECK\tSEVERITY\tSTATUS\tVALUE\tTHRESHOLD\tDURATION\tSUMMARY")
+
+	var failed = map[string]int{}
+	// the line of each result, a summary may span lines
+	colored := map[int]checkResult{}
+	line := 1
+	for _, r := range results {
+		if r.Status == "FAILED" {
+			failed[r.Category]++
+		}
+
+		value := r.Value
+		if len(value) == 0 {
+			value = "-"
+		}
+		threshold := r.Threshold
+		if len(threshold) == 0 {
+			threshold = "-"
+		}
+
+		duration := (r.Duration - r.Duration%time.Millisecond).String()
+		if r.Cached {
+			duration = "cached"
+		}
+
+		row := fmt.Sprintf("%s\t%s\t%s\t%s\t%s\t%s\t%s\n", r.Name, r.Category, r.Status, value, threshold, duration, r.Summary)
+		fmt.Fprint(tw, row)
+		colored[line] = r
+		line += strings.Count(row, "\n")
+	}
+	tw.Flush()
+
+	for i, line := range strings.SplitAfter(table.String(), "\n") {
+		if r, ok := colored[i]; ok && color {
+			line = colorStatus(line, r.Status, statusColor(r))
+		}
+		io.WriteString(w, line)
+	}
+
+	fmt.Fprintf(w, "\n%d checks, %d critical, %d major, %d minor, %d warning and %d info failed, health score %d\n",
+		len(results), failed["CRITICAL"], failed["MAJOR"], failed["MINOR"], failed["WARNING"], failed["INFO"], healthScore(results))
+	if aborted := countStatus(results, "ABORTED"); aborted > 0 {
+		fmt.Fprintf(w, "%d checks were aborted\n", aborted)
+	}
+	if skipped := countStatus(results, "SKIPPED"); skipped > 0 {
+		fmt.Fprintf(w, "%d checks were skipped, see their summary\n", skipped)
+	}
+	outputCorrelation(w, results)
+}
+
+type junitTestSuite struct {
+	XMLName   xml.Name        `xml:"testsuite"`
+	Name      string          `xml:"name,attr"`
+	Hostname  string          `xml:"hostname,attr,omitempty"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	Time      string          `xml:"time,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Skipped   *junitSkipped `xml:"skipped,omitempty"`
+}
+
+type junitSkipped struct {
+	Message string `xml:"message,attr"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// prints the results as junit xml test suite, each check run is a test case
+func OutputJUnit(w io.Writer, nodeType string, results []checkResult) {
+	var suite = junitTestSuite{Name: "openshift-monitoring-cli." + nodeType, Tests: len(results)}
+	suite.Hostname = nodeName()
+
+	var total time.Duration
+	for _, r := range results {
+		var testCase = junitTestCase{
+			Name:      r.Name + " (" + r.Category + ")",
+			ClassName: suite.Name,
+			Time:      junitSeconds(r.Duration),
+		}
+
+		switch r.Status {
+		case "FAILED":
+			suite.Failures++
+			testCase.Failure = &junitFailure{Message: r.Summary, Type: r.Category, Text: r.Summary}
+		case "ABORTED", "SKIPPED":
+			suite.Skipped++
+			testCase.Skipped = &junitSkipped{Message: r.Summary}
+		}
+
+		total += r.Duration
+		suite.TestCases = append(suite.TestCases, testCase)
+	}
+	suite.Time = junitSeconds(total)
+
+	output, err := xml.MarshalIndent(suite, "", "  ")
+	if err != nil {
+		log.Errorf("Error outputting JUnit XML (%s).", err)
+		return
+	}
+
+	fmt.Fprint(w, xml.Header)
+	fmt.Fprintln(w, string(output))
+}
+
+func junitSeconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
+
+var factKeyInvalid = regexp.MustCompile(`[^a-z0-9_]+`)
+
+// flattens the results into key/values usable as ansible local facts
+// (/etc/ansible/facts.d), e.g. check_docker_pool_major: FAILED
+func factsOf(nodeType string, results []checkResult) map[string]interface{} {
+	var facts = map[string]interface{}{
+		"node_type":  nodeType,
+		"checked_at": time.Now().Format(time.RFC3339),
+	}
+
+	status := "HEALTHY"
+	failed := 0
+	for _, r := range results {
+		key := "check_" + factKeyInvalid.ReplaceAllString(strings.ToLower(r.Name+"_"+r.Category), "_")
+		facts[key] = r.Status
+
+		if r.Status == "FAILED" {
+			failed++
+			facts[key+"_summary"] = r.Summary
+			if severityRank(r.Category) > severityRank(status) {
+				status = r.Category
+			}
+		}
+	}
+
+	facts["status"] = status
+	facts["failed_checks"] = failed
+	return facts
+}
+
+// colors the status in the third column of a line of the text output, the
+// check and the severity before it contain no spaces
+func colorStatus(line string, status string, color string) string {
+	start := 0
+	for column := 0; column < 2; column++ {
+		end := strings.IndexByte(line[start:], ' ')
+		if end < 0 {
+			return line
+		}
+		for start += end; start < len(line) && line[start] == ' '; start++ {
+		}
+	}
+	if !strings.HasPrefix(line[start:], status) {
+		return line
+	}
+	return line[:start] + color + status + colorReset + line[start+len(status):]
+}
+
+func statusColor(r checkResult) string {
+	switch {
+	case r.Status == "OK":
+		return colorGreen
+	case r.Status == "ABORTED" || r.Status == "SKIPPED":
+		return colorYellow
+	case severityRank(r.Category) >= severityRank("MAJOR"):
+		return colorRed
+	default:
+		return colorYellow
+	}
+}
+
+// returns the number of results with the status
+func countStatus(results []checkResult, status string) int {
+	count := 0
+	for _, r := range results {
+		if r.Status == status {
+			count++
+		}
+	}
+	return count
+}
+
+// reports if f is a terminal, so colors are not written into files or pipes
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,53 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"time"
+
+	"github.com/oscp/openshift-monitoring-cli/probes"
+)
+
+// runs the process checks of the host, the processes are listed once
+func evalProcesses(ctx context.Context) {
+	measure := func(ctx context.Context) (interface{}, error) {
+		return probes.ReadProcesses(ctx)
+	}
+
+	evalLevels(ctx, levelCheck{
+		name:      "zombie-processes",
+		threshold: thresholdDisplay("zombie-processes", ""),
+		measure:   measure,
+		evaluate: func(ctx context.Context, processes interface{}, category string) error {
+			p, ok := processes.([]probes.Process)
+			if !ok {
+				return unexpectedMeasurement("zombie-processes", processes)
+			}
+			return probes.CheckZombieProcesses(p, threshold("zombie-processes", category))
+		},
+	}, levelCheck{
+		name:      "runtime-processes",
+		threshold: thresholdDisplay("runtime-processes", "m"),
+		measure:   measure,
+		evaluate: func(ctx context.Context, processes interface{}, category string) error {
+			p, ok := processes.([]probes.Process)
+			if !ok {
+				return unexpectedMeasurement("runtime-processes", processes)
+			}
+			return probes.CheckRuntimeProcesses(p, time.Duration(threshold("runtime-processes", category))*time.Minute)
+		},
+	})
+}
@@ -0,0 +1,174 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// the default thresholds of the checks, can be overridden in the config
+// or a profile as thresholds.<check>.<major|minor>
+var defaultThresholds = map[string]map[string]int{
+	"mount-point-sizes": {"major": 90, "minor": 85},
+	"lv-pool-sizes":     {"major": 90, "minor": 80},
+	// a thin pool whose metadata is full gets corrupted
+	"lv-pool-metadata":  {"major": 80, "minor": 70},
+	"vg-sizes":          {"major": 5, "minor": 10},
+	"docker-pool":       {"major": 90, "minor": 80},
+	"kubeconfig-expiry": {"major": 7, "minor": 30},
+	"etcd-cert-expiry":  {"major": 7, "minor": 30},
+	// days until a bootstrap token expires, nodes can't join with it then
+	"bootstrap-token-expiry": {"major": 7, "minor": 30},
+	// latency in milliseconds, etcd recommends below 10ms for the wal fsync
+	// and below 25ms for the backend commit
+	"webconsole":          {"major": 10000, "minor": 3000},
+	"etcd-wal-fsync":      {"major": 100, "minor": 10},
+	"etcd-backend-commit": {"major": 250, "minor": 25},
+	// age in hours, a daily backup may be late for a few hours
+	"etcd-backup":   {"major": 48, "minor": 26},
+	"object-backup": {"major": 48, "minor": 26},
+	// elasticsearch stops allocating shards at 85% disk usage
+	"cassandra-storage":      {"major": 90, "minor": 80},
+	"elasticsearch-storage":  {"major": 85, "minor": 75},
+	"cassandra-restarts":     {"major": 10, "minor": 3},
+	"elasticsearch-restarts": {"major": 10, "minor": 3},
+	// pending compactions and used heap in percent, a cassandra behind on
+	// compactions or in gc pressure drops writes
+	"cassandra-compactions": {"major": 100, "minor": 30},
+	"cassandra-heap":        {"major": 95, "minor": 85},
+	// spread of the clocks in milliseconds, etcd leases and the validity of
+	// fresh certificates suffer from a second
+	"clock-skew": {"major": 1000, "minor": 200},
+	// lag of the geo-replication in minutes
+	"geo-replication": {"major": 360, "minor": 60},
+	// used space of the vSphere datastores in percent
+	"datastore-capacity": {"major": 90, "minor": 80},
+	// backlog of fluentd in megabytes and minutes, the default buffer queue
+	// of the logging holds 32 chunks of 8MB
+	"fluentd-buffer-size": {"major": 200, "minor": 100},
+	"fluentd-buffer-age":  {"major": 60, "minor": 15},
+	// free space in percentage points of the disk above the eviction
+	// threshold of the kubelet
+	"eviction-headroom": {"major": 2, "minor": 5},
+	// pods of the node in percent of the pods the kubelet admits
+	"pod-density": {"major": 95, "minor": 85},
+	// defunct processes and minutes a runc runs or a shim is defunct
+	"zombie-processes":  {"major": 100, "minor": 20},
+	"runtime-processes": {"major": 60, "minor": 10},
+	// dying memory cgroups, nodes slow down noticeably from about 100000
+	"dying-cgroups": {"major": 100000, "minor": 20000},
+	// minutes the indexed logs of the node are behind its journal
+	"ingestion-lag": {"major": 60, "minor": 15},
+	// current connections of haproxy in percent of maxconn
+	"haproxy-sessions": {"major": 90, "minor": 75},
+	// stratum of ntpd, 16 means unsynchronized
+	"ntpd": {"major": 10, "minor": 5},
+	// growth within rates.<check>.window
+	"router-restarts-rate": {"major": 10, "minor": 3},
+	"docker-pool-rate":     {"major": 20, "minor": 10},
+	"etcd-db-size-rate":    {"major": 500, "minor": 200},
+	"kernel-slab-rate":     {"major": 2048, "minor": 512},
+}
+
+func setThresholdDefaults() {
+	for check, levels := range defaultThresholds {
+		for level, value := range levels {
+			viper.SetDefault("thresholds."+check+"."+level, value)
+		}
+	}
+}
+
+// returns the configured threshold of a check for the category, with the
+// hysteresis applied if the check failed in the previous run
+func threshold(check string, category string) int {
+	return clearThreshold(check, category, viper.GetInt("thresholds."+check+"."+strings.ToLower(category)))
+}
+
+// applies the settings of profiles.<name> on top of the configuration
+func applyProfile(name string) error {
+	if !viper.IsSet("profiles." + name) {
+		return fmt.Errorf("Profile %s is not defined.", name)
+	}
+
+	for key, value := range flattenSettings("", viper.GetStringMap("profiles."+name)) {
+		viper.Set(key, value)
+	}
+	return nil
+}
+
+// flattens nested settings into dotted keys
+func flattenSettings(prefix string, settings map[string]interface{}) map[string]interface{} {
+	var flat = map[string]interface{}{}
+
+	for key, value := range settings {
+		switch nested := value.(type) {
+		case map[string]interface{}:
+			for k, v := range flattenSettings(prefix+key+".", nested) {
+				flat[k] = v
+			}
+		case map[interface{}]interface{}:
+			converted := map[string]interface{}{}
+			for k, v := range nested {
+				converted[fmt.Sprint(k)] = v
+			}
+			for k, v := range flattenSettings(prefix+key+".", converted) {
+				flat[k] = v
+			}
+		default:
+			flat[prefix+key] = value
+		}
+	}
+
+	return flat
+}
+
+// reports if a check is selected by checks.include and checks.exclude and
+// by its tags with checks.tags and checks.excludeTags. Checks run per target
+// (like router-health:<ip>) are selected by their name. A targeted run only
+// selects its checks, by name or name:target.
+func checkSelected(name string) bool {
+	if len(targetedChecks) > 0 {
+		return containsString(targetedChecks, name) || containsString(targetedChecks, strings.SplitN(name, ":", 2)[0])
+	}
+	name = strings.SplitN(name, ":", 2)[0]
+
+	if !nodeTypeRunsCheck(name) {
+		return false
+	}
+	if include := viper.GetString("checks.include"); len(include) > 0 && !containsString(strings.Split(include, ","), name) {
+		return false
+	}
+	if containsString(strings.Split(viper.GetString("checks.exclude"), ","), name) {
+		return false
+	}
+
+	info, _ := catalogEntry(name)
+	if tags := viper.GetString("checks.tags"); len(tags) > 0 && !containsAny(info.Tags, strings.Split(tags, ",")) {
+		return false
+	}
+	return !containsAny(info.Tags, strings.Split(viper.GetString("checks.excludeTags"), ","))
+}
+
+func containsAny(list []string, values []string) bool {
+	for _, v := range values {
+		if containsString(list, v) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,117 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// a window of quiet hours in local time, e.g. the nightly batch jobs which
+// cause expected load and disk spikes. From after to spans midnight.
+type quietWindow struct {
+	// mon,tue,... every day if empty
+	Days string
+	From string
+	To   string
+}
+
+var quietWindows []quietWindow
+
+// loads quietHours.windows, invalid windows are ignored. The times are kept
+// as zero padded hh:mm and the days in lower case, so they compare with the
+// formatted time.
+func loadQuietHours() {
+	var windows []quietWindow
+	if err := viper.UnmarshalKey("quietHours.windows", &windows); err != nil {
+		log.Error("Not able to read quietHours.windows:", err)
+		return
+	}
+
+	quietWindows = nil
+	for _, w := range windows {
+		from, fromErr := time.Parse("15:04", strings.TrimSpace(w.From))
+		to, toErr := time.Parse("15:04", strings.TrimSpace(w.To))
+		if fromErr != nil || toErr != nil {
+			log.Error("Ignoring quiet hours", w.From, "to", w.To, ", the times must be hh:mm")
+			continue
+		}
+		w.From, w.To = from.Format("15:04"), to.Format("15:04")
+
+		var days []string
+		for _, day := range strings.Split(w.Days, ",") {
+			if day = strings.ToLower(strings.TrimSpace(day)); len(day) > 0 {
+				days = append(days, day)
+			}
+		}
+		w.Days = strings.Join(days, ",")
+		quietWindows = append(quietWindows, w)
+	}
+}
+
+// reports if now is within one of the quiet windows
+func inQuietHours(now time.Time) bool {
+	clock := now.Format("15:04")
+	today := strings.ToLower(now.Weekday().String()[:3])
+	yesterday := strings.ToLower(now.AddDate(0, 0, -1).Weekday().String()[:3])
+
+	for _, w := range quietWindows {
+		onDay := func(day string) bool {
+			return len(w.Days) == 0 || containsString(strings.Split(w.Days, ","), day)
+		}
+		if w.From <= w.To {
+			if onDay(today) && clock >= w.From && clock < w.To {
+				return true
+			}
+			continue
+		}
+		// the window started on the day before if it is before to
+		if onDay(today) && clock >= w.From || onDay(yesterday) && clock < w.To {
+			return true
+		}
+	}
+	return false
+}
+
+// downgrades a minor of the checks or tags of quietHours to INFO during the
+// quiet hours, majors and the other categories are kept
+func quietHoursCategory(name string, category string) string {
+	if category != "MINOR" || len(quietWindows) == 0 || !inQuietHours(time.Now()) {
+		return category
+	}
+
+	info, _ := catalogEntry(name)
+	name = strings.SplitN(name, ":", 2)[0]
+	checks := viper.GetString("quietHours.checks")
+	tags := viper.GetString("quietHours.tags")
+
+	selected := len(checks) == 0 && len(tags) == 0
+	if len(checks) > 0 && containsString(strings.Split(checks, ","), name) {
+		selected = true
+	}
+	for _, tag := range strings.Split(tags, ",") {
+		if len(tag) > 0 && containsString(info.Tags, tag) {
+			selected = true
+		}
+	}
+
+	if !selected {
+		return category
+	}
+	log.Debug("Downgrading", name, "to INFO during the quiet hours")
+	return "INFO"
+}
@@ -0,0 +1,173 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// a value of a counter, like the restarts of the routers
+type sample struct {
+	Time  time.Time `json:"time"`
+	Value float64   `json:"value"`
+}
+
+// the samples of all counters by check name, kept for the longest window
+var samples map[string][]sample
+
+// samples are kept at most this long
+const maxSampleAge = 24 * time.Hour
+
+// returns the window of a rate check, configured in minutes as
+// rates.<check>.window
+func rateWindow(name string) time.Duration {
+	return time.Duration(viper.GetInt("rates."+name+".window")) * time.Minute
+}
+
+// runs a rate check: the counter is sampled once and its growth within the
+// window is compared with the major and minor thresholds of the check, e.g.
+// thresholds.router-restarts-rate.major. The first run only records a sample.
+func evalRate(ctx context.Context, name string, counterName string, unit string, counter func(ctx context.Context) (float64, error)) {
+	minutes := int(rateWindow(name).Minutes())
+
+	// a level without threshold is not checked
+	var categories []string
+	for _, category := range []string{"MAJOR", "MINOR"} {
+		if threshold(name, category) > 0 {
+			categories = append(categories, category)
+		}
+	}
+	if len(categories) == 0 {
+		return
+	}
+
+	evalLevels(ctx, levelCheck{
+		name:       name,
+		categories: categories,
+		threshold: func(category string) string {
+			return fmt.Sprintf("+%d%s/%dm", threshold(name, category), unit, minutes)
+		},
+		measure: func(ctx context.Context) (interface{}, error) {
+			return recordSample(ctx, name, counter)
+		},
+		evaluate: func(ctx context.Context, measurement interface{}, category string) error {
+			growth, ok := measurement.(float64)
+			if !ok {
+				return unexpectedMeasurement(name, measurement)
+			}
+			limit := threshold(name, category)
+			if growth > float64(limit) {
+				return fmt.Errorf("%s grew by %s%s in the last %d minutes, threshold is %d%s", counterName, strconv.FormatFloat(growth, 'f', 1, 64), unit, minutes, limit, unit)
+			}
+			return nil
+		},
+		value: func(measurement interface{}) string {
+			growth, _ := measurement.(float64)
+			return fmt.Sprintf("+%s%s/%dm", strconv.FormatFloat(growth, 'f', 1, 64), unit, minutes)
+		},
+	})
+}
+
+// samples the counter and returns its growth since the oldest sample within
+// the window of the check
+func recordSample(ctx context.Context, name string, counter func(ctx context.Context) (float64, error)) (float64, error) {
+	value, err := counter(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	if samples == nil {
+		loadSamples()
+	}
+
+	now := time.Now()
+	var kept []sample
+	var growth float64
+	var oldest *sample
+	for i, s := range samples[name] {
+		if now.Sub(s.Time) > maxSampleAge {
+			continue
+		}
+		kept = append(kept, s)
+		if oldest == nil && now.Sub(s.Time) <= rateWindow(name) {
+			oldest = &samples[name][i]
+		}
+	}
+	if oldest != nil {
+		growth = value - oldest.Value
+	}
+	samples[name] = append(kept, sample{Time: now, Value: value})
+
+	return growth, nil
+}
+
+func samplesFile() string {
+	if dir := viper.GetString("store.path"); len(dir) > 0 {
+		return filepath.Join(dir, "samples.json")
+	}
+	return ""
+}
+
+func loadSamples() {
+	samples = map[string][]sample{}
+
+	file := samplesFile()
+	if len(file) == 0 {
+		return
+	}
+
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Warning("Not able to read samples", err)
+		}
+		return
+	}
+	if err := json.Unmarshal(content, &samples); err != nil {
+		log.Warning("Not able to parse samples", err)
+		samples = map[string][]sample{}
+	}
+}
+
+// persists the samples if a counter was sampled in this run
+func saveSamples() {
+	file := samplesFile()
+	if samples == nil || len(file) == 0 {
+		return
+	}
+
+	content, err := json.Marshal(samples)
+	if err != nil {
+		log.Error("Not able to serialize samples", err)
+		return
+	}
+
+	if err := os.MkdirAll(filepath.Dir(file), 0750); err != nil {
+		log.Error("Not able to create result store", err)
+		return
+	}
+	if err := ioutil.WriteFile(file, content, 0640); err != nil {
+		log.Error("Not able to save samples", err)
+	}
+}
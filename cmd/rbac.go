@@ -0,0 +1,171 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v2"
+)
+
+// a rule of a ClusterRole
+type policyRule struct {
+	APIGroups []string `json:"apiGroups" yaml:"apiGroups"`
+	Resources []string `json:"resources" yaml:"resources"`
+	Verbs     []string `json:"verbs" yaml:"verbs"`
+}
+
+// the rules of the checks, the empty group is the legacy api of openshift 3.x
+var (
+	readPods     = policyRule{[]string{""}, []string{"pods"}, []string{"get", "list"}}
+	execPods     = policyRule{[]string{""}, []string{"pods/exec"}, []string{"create"}}
+	readNodes    = policyRule{[]string{""}, []string{"nodes"}, []string{"get", "list"}}
+	readProjects = policyRule{[]string{"", "project.openshift.io"}, []string{"projects"}, []string{"get", "list"}}
+	manageCanary = policyRule{[]string{""}, []string{"pods", "services"}, []string{"get", "list", "create", "delete"}}
+	manageRoutes = policyRule{[]string{"", "route.openshift.io"}, []string{"routes"}, []string{"get", "list", "create", "delete"}}
+	readQuotas   = policyRule{[]string{""}, []string{"namespaces", "limitranges", "resourcequotas"}, []string{"list"}}
+	readSccs     = policyRule{[]string{"", "security.openshift.io"}, []string{"securitycontextconstraints"}, []string{"get", "list"}}
+	readBindings = policyRule{[]string{"", "rbac.authorization.k8s.io"}, []string{"clusterrolebindings"}, []string{"get", "list"}}
+	readGroups   = policyRule{[]string{"", "user.openshift.io"}, []string{"groups"}, []string{"list"}}
+	// oc adm diagnostics only runs as cluster-admin
+	allResources  = policyRule{[]string{"*"}, []string{"*"}, []string{"*"}}
+	patchNodes    = policyRule{[]string{""}, []string{"nodes"}, []string{"get", "patch"}}
+	patchStatus   = policyRule{[]string{""}, []string{"nodes/status"}, []string{"patch"}}
+	writeEvents   = policyRule{[]string{""}, []string{"events"}, []string{"get", "create", "patch"}}
+	writeReports  = policyRule{[]string{"monitoring.sbb.ch"}, []string{"healthreports"}, []string{"get", "create", "patch"}}
+	readOperators = policyRule{[]string{"monitoring.sbb.ch"}, []string{"monitoringcheckconfigs"}, []string{"get", "list"}}
+	readEndpoints = policyRule{[]string{""}, []string{"endpoints"}, []string{"get"}}
+	// the bootstrap tokens in kube-system
+	listSecrets = policyRule{[]string{""}, []string{"secrets"}, []string{"list"}}
+)
+
+var rbacName string
+var rbacNodeTypes string
+
+var rbacCmd = &cobra.Command{
+	Use:   "rbac",
+	Short: "Prints the ClusterRole the configured checks need.",
+	Long: `Prints a ClusterRole with the rules the selected and enabled checks of the node types and
+the configured integrations (nodeStatus, kubernetesEvents, healthReport, operator, inCluster)
+need, so the service account of the cli can be granted least privileges.`,
+	RunE: runRbac,
+}
+
+func init() {
+	rootCmd.AddCommand(rbacCmd)
+
+	rbacCmd.Flags().StringVar(&rbacName, "name", "openshift-monitoring-cli", "name of the ClusterRole")
+	rbacCmd.Flags().StringVar(&rbacNodeTypes, "node-types", "", "node types whose checks are included, comma separated (default node.type)")
+}
+
+func runRbac(cmd *cobra.Command, args []string) error {
+	var nodeTypes []string
+	for _, nodeType := range strings.Split(rbacNodeTypes, ",") {
+		if len(rbacNodeTypes) == 0 {
+			nodeType = viper.GetString("node.type")
+		}
+		nodeTypes = append(nodeTypes, nodeTypeBases(nodeType)...)
+	}
+
+	var rules []policyRule
+	for _, c := range checkCatalog {
+		if containsAny(c.NodeTypes, nodeTypes) && checkSelected(c.Name) && checkEnabled(c) && len(skipReason(c.Name)) == 0 {
+			rules = append(rules, c.Rules...)
+		}
+	}
+
+	if viper.GetBool("inCluster.enabled") {
+		rules = append(rules, readNodes)
+	}
+	if viper.GetBool("nodeStatus.annotations") {
+		rules = append(rules, patchNodes)
+	}
+	if viper.GetBool("nodeStatus.condition") {
+		rules = append(rules, patchStatus)
+	}
+	if viper.GetBool("notifications.kubernetesEvents.enabled") {
+		rules = append(rules, writeEvents)
+	}
+	if viper.GetBool("healthReport.enabled") {
+		rules = append(rules, writeReports)
+	}
+	if viper.GetBool("operator.enabled") {
+		rules = append(rules, readOperators)
+	}
+
+	var role = struct {
+		APIVersion string `yaml:"apiVersion"`
+		Kind       string `yaml:"kind"`
+		Metadata   struct {
+			Name string `yaml:"name"`
+		} `yaml:"metadata"`
+		Rules []policyRule `yaml:"rules"`
+	}{APIVersion: "rbac.authorization.k8s.io/v1", Kind: "ClusterRole", Rules: mergeRules(rules)}
+	role.Metadata.Name = rbacName
+
+	out, err := yaml.Marshal(role)
+	if err != nil {
+		return err
+	}
+	fmt.Print(string(out))
+	return nil
+}
+
+// reports if a check is enabled, checks with an <name>.enabled or a
+// features.<name> parameter only run if it is set
+func checkEnabled(c checkInfo) bool {
+	for _, param := range c.Params {
+		if strings.HasSuffix(param, ".enabled") && !viper.GetBool(param) {
+			return false
+		}
+		if strings.HasPrefix(param, "features.") && !featureEnabled(strings.TrimPrefix(param, "features.")) {
+			return false
+		}
+	}
+	return true
+}
+
+// merges the verbs of rules with the same api groups and resources
+func mergeRules(rules []policyRule) []policyRule {
+	var merged []policyRule
+	index := map[string]int{}
+
+	for _, rule := range rules {
+		key := strings.Join(rule.APIGroups, ",") + "/" + strings.Join(rule.Resources, ",")
+		i, ok := index[key]
+		if !ok {
+			index[key] = len(merged)
+			merged = append(merged, policyRule{APIGroups: rule.APIGroups, Resources: rule.Resources})
+			i = len(merged) - 1
+		}
+		for _, verb := range rule.Verbs {
+			if !containsString(merged[i].Verbs, verb) {
+				merged[i].Verbs = append(merged[i].Verbs, verb)
+			}
+		}
+	}
+
+	for i := range merged {
+		sort.Strings(merged[i].Verbs)
+	}
+	sort.Slice(merged, func(i, j int) bool {
+		return strings.Join(merged[i].Resources, ",") < strings.Join(merged[j].Resources, ",")
+	})
+	return merged
+}
@@ -0,0 +1,184 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/oscp/openshift-monitoring-cli/probes"
+	"github.com/spf13/viper"
+)
+
+// merges the config of remoteConfig.url into the config, so many nodes can
+// share centrally managed settings. It is merged over the config file and
+// under configDir, so the files of a node override it. A config which was
+// merged is kept in remoteConfig.cache and used while the remote source is
+// not reachable or its config can't be merged, so a broken config pushed
+// centrally doesn't stop all nodes.
+func mergeRemoteConfig() error {
+	source := viper.GetString("remoteConfig.url")
+	if len(source) == 0 {
+		return nil
+	}
+
+	cache := remoteConfigCache()
+	content, err := fetchRemoteConfig()
+	if err == nil {
+		if err = mergeConfigContent(source, content, false); err == nil {
+			if len(cache) > 0 {
+				if err := ioutil.WriteFile(cache, []byte(content), 0640); err != nil {
+					log.Warning("Not able to cache the remote config in", cache, err)
+				}
+			}
+			return nil
+		}
+	}
+
+	if len(cache) == 0 {
+		return fmt.Errorf("Not able to use the remote config of %s: %v", source, err)
+	}
+	log.Warning("Not able to use the remote config of", source, err, "- using", cache)
+	cached, readErr := ioutil.ReadFile(cache)
+	if readErr != nil {
+		return fmt.Errorf("Not able to use the remote config of %s: %v, no cached copy: %v", source, err, readErr)
+	}
+	return mergeConfigContent(cache, string(cached), false)
+}
+
+// returns remoteConfig.cache, by default remote-config.yml in store.path
+func remoteConfigCache() string {
+	if cache := viper.GetString("remoteConfig.cache"); len(cache) > 0 {
+		return cache
+	}
+	if dir := viper.GetString("store.path"); len(dir) > 0 {
+		return filepath.Join(dir, "remote-config.yml")
+	}
+	return ""
+}
+
+// fetches the yaml config from a https url, the key of a consul kv store or
+// the key of etcd over its v3 json api
+func fetchRemoteConfig() (string, error) {
+	client, err := remoteConfigClient()
+	if err != nil {
+		return "", err
+	}
+	base := strings.TrimSuffix(viper.GetString("remoteConfig.url"), "/")
+	key := strings.TrimPrefix(viper.GetString("remoteConfig.key"), "/")
+
+	var req *http.Request
+	switch source := viper.GetString("remoteConfig.type"); source {
+	case "http":
+		req, err = http.NewRequest("GET", base, nil)
+	case "consul":
+		req, err = http.NewRequest("GET", base+"/v1/kv/"+key+"?raw", nil)
+	case "etcd":
+		body, _ := json.Marshal(map[string]string{"key": base64.StdEncoding.EncodeToString([]byte("/" + key))})
+		req, err = http.NewRequest("POST", base+"/v3/kv/range", bytes.NewReader(body))
+	default:
+		return "", fmt.Errorf("Unknown remoteConfig.type %s, expected http, consul or etcd", source)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	// the secrets of the config are resolved after the remote config is
+	// merged
+	token, err := resolveReferences("remoteConfig.token", viper.GetString("remoteConfig.token"))
+	if err != nil {
+		return "", err
+	}
+	password, err := resolveReferences("remoteConfig.password", viper.GetString("remoteConfig.password"))
+	if err != nil {
+		return "", err
+	}
+	if len(token) > 0 {
+		if viper.GetString("remoteConfig.type") == "consul" {
+			req.Header.Set("X-Consul-Token", token)
+		} else {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+	} else if len(viper.GetString("remoteConfig.username")) > 0 {
+		req.SetBasicAuth(viper.GetString("remoteConfig.username"), password)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s %s returned %s", req.Method, req.URL.Path, resp.Status)
+	}
+
+	if viper.GetString("remoteConfig.type") != "etcd" {
+		return string(body), nil
+	}
+	var etcdResp struct {
+		Kvs []struct {
+			Value string `json:"value"`
+		} `json:"kvs"`
+	}
+	if err := json.Unmarshal(body, &etcdResp); err != nil {
+		return "", fmt.Errorf("Could not parse the etcd response: %v", err)
+	}
+	if len(etcdResp.Kvs) == 0 {
+		return "", fmt.Errorf("etcd has no key /%s", key)
+	}
+	value, err := base64.StdEncoding.DecodeString(etcdResp.Kvs[0].Value)
+	return string(value), err
+}
+
+// returns the client for the remote config with the ca and the client
+// certificate of remoteConfig, if set
+func remoteConfigClient() (*http.Client, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: viper.GetBool("remoteConfig.insecure")}
+
+	if ca := viper.GetString("remoteConfig.ca"); len(ca) > 0 {
+		pem, err := ioutil.ReadFile(ca)
+		if err != nil {
+			return nil, fmt.Errorf("Not able to read remoteConfig.ca: %v", err)
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		tlsConfig.RootCAs.AppendCertsFromPEM(pem)
+	}
+	if cert := viper.GetString("remoteConfig.clientCert"); len(cert) > 0 {
+		pair, err := tls.LoadX509KeyPair(cert, viper.GetString("remoteConfig.clientKey"))
+		if err != nil {
+			return nil, fmt.Errorf("Not able to read remoteConfig.clientCert: %v", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{pair}
+	}
+
+	// the config may contain credentials, the trace must not keep it
+	return &http.Client{
+		Timeout:   time.Duration(viper.GetInt("remoteConfig.timeout")) * time.Second,
+		Transport: probes.SensitiveTraceTransport(&http.Transport{TLSClientConfig: tlsConfig, Proxy: http.ProxyFromEnvironment}),
+	}, nil
+}
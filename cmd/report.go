@@ -0,0 +1,243 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var reportFormat string
+var reportFrom string
+var reportTo string
+
+var reportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "Renders stored check results into a Markdown or HTML report.",
+	Long: `Renders the latest run from the result store (store.path) or, if --from or --to
+is given, all runs in that range into a report with sections per node type and severity.`,
+	RunE: runReport,
+}
+
+func init() {
+	rootCmd.AddCommand(reportCmd)
+
+	reportCmd.Flags().StringVarP(&reportFormat, "format", "f", "markdown", "report format (markdown|html)")
+	reportCmd.Flags().StringVar(&reportFrom, "from", "", "start of the range (2006-01-02 or RFC3339)")
+	reportCmd.Flags().StringVar(&reportTo, "to", "", "end of the range (2006-01-02 or RFC3339)")
+}
+
+// a failed check aggregated over all runs of the report
+type reportFinding struct {
+	Name      string
+	Summary   string
+	Count     int
+	FirstSeen time.Time
+	LastSeen  time.Time
+}
+
+type reportSeverity struct {
+	Category string
+	Findings []*reportFinding
+}
+
+type reportSection struct {
+	NodeType   string
+	Hosts      []string
+	Severities []reportSeverity
+}
+
+type reportData struct {
+	Generated time.Time
+	From      time.Time
+	To        time.Time
+	Runs      int
+	Sections  []reportSection
+}
+
+const markdownReport = `# OpenShift monitoring report
+
+Generated {{ .Generated.Format "2006-01-02 15:04:05 MST" }} from {{ .Runs }} run(s) between {{ .From.Format "2006-01-02 15:04" }} and {{ .To.Format "2006-01-02 15:04" }}.
+{{ range .Sections }}
+## Node type {{ .NodeType }}
+
+Hosts: {{ join .Hosts ", " }}
+{{ range .Severities }}
+### {{ .Category }}
+{{ if .Findings }}
+| Check | Summary | Count | First seen | Last seen |
+|-------|---------|-------|------------|-----------|
+{{ range .Findings }}| {{ .Name }} | {{ .Summary }} | {{ .Count }} | {{ .FirstSeen.Format "2006-01-02 15:04" }} | {{ .LastSeen.Format "2006-01-02 15:04" }} |
+{{ end }}{{ else }}
+No findings.
+{{ end }}{{ end }}{{ end }}`
+
+const htmlReport = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>OpenShift monitoring report</title>
+<style>
+body { font-family: sans-serif; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; }
+</style>
+</head>
+<body>
+<h1>OpenShift monitoring report</h1>
+<p>Generated {{ .Generated.Format "2006-01-02 15:04:05 MST" }} from {{ .Runs }} run(s) between {{ .From.Format "2006-01-02 15:04" }} and {{ .To.Format "2006-01-02 15:04" }}.</p>
+{{ range .Sections }}
+<h2>Node type {{ .NodeType }}</h2>
+<p>Hosts: {{ join .Hosts ", " }}</p>
+{{ range .Severities }}
+<h3>{{ .Category }}</h3>
+{{ if .Findings }}
+<table>
+<tr><th>Check</th><th>Summary</th><th>Count</th><th>First seen</th><th>Last seen</th></tr>
+{{ range .Findings }}<tr><td>{{ .Name }}</td><td>{{ .Summary }}</td><td>{{ .Count }}</td><td>{{ .FirstSeen.Format "2006-01-02 15:04" }}</td><td>{{ .LastSeen.Format "2006-01-02 15:04" }}</td></tr>
+{{ end }}</table>
+{{ else }}
+<p>No findings.</p>
+{{ end }}{{ end }}{{ end }}
+</body>
+</html>
+`
+
+func runReport(cmd *cobra.Command, args []string) error {
+	from, err := parseReportTime(reportFrom, false)
+	if err != nil {
+		return err
+	}
+	to, err := parseReportTime(reportTo, true)
+	if err != nil {
+		return err
+	}
+
+	runs, err := loadRuns(from, to)
+	if err != nil {
+		return fmt.Errorf("Not able to read result store: %v", err)
+	}
+	if len(runs) == 0 {
+		return errors.New("No stored runs found for the report.")
+	}
+
+	// without a range only the latest run is reported
+	if from.IsZero() && to.IsZero() {
+		runs = runs[len(runs)-1:]
+	}
+
+	return renderReport(os.Stdout, reportFormat, buildReport(runs))
+}
+
+// parses --from or --to, a date without time is the start of the day or,
+// with end, the last moment of the day
+func parseReportTime(value string, end bool) (time.Time, error) {
+	if len(value) == 0 {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("Invalid time %s, expected 2006-01-02 or RFC3339.", value)
+	}
+	if end {
+		return t.AddDate(0, 0, 1).Add(-time.Nanosecond), nil
+	}
+	return t, nil
+}
+
+// aggregates the failed checks of all runs per node type and severity
+func buildReport(runs []storedRun) reportData {
+	var report = reportData{Generated: time.Now(), From: runs[0].Time, To: runs[len(runs)-1].Time, Runs: len(runs)}
+
+	sections := map[string]*reportSection{}
+	findings := map[string]*reportFinding{}
+	var nodeTypes []string
+
+	for _, run := range runs {
+		section, ok := sections[run.NodeType]
+		if !ok {
+			section = &reportSection{NodeType: run.NodeType}
+			for i := len(severities) - 1; i >= 0; i-- {
+				section.Severities = append(section.Severities, reportSeverity{Category: severities[i]})
+			}
+			sections[run.NodeType] = section
+			nodeTypes = append(nodeTypes, run.NodeType)
+		}
+		if !containsString(section.Hosts, run.Hostname) {
+			section.Hosts = append(section.Hosts, run.Hostname)
+		}
+
+		for _, r := range run.Results {
+			if r.Status != "FAILED" {
+				continue
+			}
+
+			key := run.NodeType + "/" + r.Category + "/" + r.Name
+			finding, ok := findings[key]
+			if !ok {
+				finding = &reportFinding{Name: r.Name, FirstSeen: run.Time}
+				findings[key] = finding
+				for i := range section.Severities {
+					if section.Severities[i].Category == r.Category {
+						section.Severities[i].Findings = append(section.Severities[i].Findings, finding)
+					}
+				}
+			}
+			finding.Count++
+			finding.Summary = r.Summary
+			finding.LastSeen = run.Time
+		}
+	}
+
+	sort.Strings(nodeTypes)
+	for _, nodeType := range nodeTypes {
+		report.Sections = append(report.Sections, *sections[nodeType])
+	}
+
+	return report
+}
+
+func renderReport(w io.Writer, format string, report reportData) error {
+	funcs := map[string]interface{}{"join": strings.Join}
+
+	switch format {
+	case "markdown", "md":
+		return template.Must(template.New("report").Funcs(funcs).Parse(markdownReport)).Execute(w, report)
+	case "html":
+		return htmltemplate.Must(htmltemplate.New("report").Funcs(funcs).Parse(htmlReport)).Execute(w, report)
+	}
+	return fmt.Errorf("Unknown report format %s.", format)
+}
+
+func containsString(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
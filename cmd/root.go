@@ -15,22 +15,37 @@
 package cmd
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
-	"runtime"
 	"strings"
-	"errors"
+	"syscall"
+	"time"
 
 	"github.com/op/go-logging"
-	"github.com/oscp/openshift-monitoring-checks/checks"
+	"github.com/oscp/openshift-monitoring-cli/probes"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
 
 var pretty	bool
 var debug	bool
+var logLevel string
+var traceFile string
+var outputFormat string
+var outputTemplate string
+var profile string
+var clusterName string
+var readOnly bool
+var fakeChecks bool
+var containerized bool
+var configFile string
+var tags string
+var excludeTags string
+var runTimeout time.Duration
+var splay time.Duration
 
 var log = logging.MustGetLogger("openshift-monitoring-cli")
 
@@ -43,6 +58,10 @@ type IntegrationData struct {
 	ProtocolVersion    string      `json:"protocol_version"`
 	IntegrationVersion string      `json:"integration_version"`
 	Events             []EventData `json:"events"`
+	// 100 if no check failed, see score.weights
+	HealthScore int `json:"health_score"`
+	// the state of the features during the run, see features
+	Features map[string]bool `json:"features"`
 }
 
 var data = IntegrationData{
@@ -72,59 +91,222 @@ func init() {
 
 	rootCmd.Flags().BoolVarP(&pretty, "pretty", "p", false, "print pretty json output")
 	rootCmd.Flags().BoolVarP(&debug, "debug", "d", false, "print debug messages")
+	rootCmd.Flags().StringVarP(&outputFormat, "output", "o", "json", "output format (json|text|table|junit|facts)")
+	rootCmd.PersistentFlags().StringVar(&logLevel, "log-level", "", "log level (trace|debug|info|warning|error), overrides logging.level")
+	rootCmd.PersistentFlags().StringVar(&configFile, "config", "", "config file (default config.yml next to the executable)")
+	rootCmd.PersistentFlags().StringVar(&profile, "profile", "", "apply the settings of profiles.<name> from the config")
+	rootCmd.PersistentFlags().BoolVar(&fakeChecks, "fake-checks", false, "don't run the library checks, fail the ones in fakeChecks.failures instead")
+	rootCmd.PersistentFlags().BoolVar(&containerized, "containerized", false, "run in a container with the host mounted at hostRoot (default containerized.enabled)")
+	rootCmd.PersistentFlags().BoolVar(&readOnly, "read-only", false, "skip checks which create resources or exec into pods (default readOnly)")
+	rootCmd.PersistentFlags().StringVar(&clusterName, "cluster", "", "run against clusters.<name> from the config")
+	rootCmd.Flags().StringVar(&tags, "tags", "", "only run checks with one of these tags (comma separated)")
+	rootCmd.Flags().StringVar(&excludeTags, "exclude-tags", "", "don't run checks with one of these tags (comma separated)")
+	rootCmd.Flags().DurationVar(&runTimeout, "timeout", 0, "deadline for the whole run, e.g. 5m (default run.timeout)")
+	rootCmd.Flags().StringVar(&tierFlag, "tier", "auto", "tiers to run (auto|fast|all), auto runs the deep checks as configured in tiers")
+	rootCmd.Flags().DurationVar(&splay, "splay", 0, "sleep a random interval up to this before running, e.g. 2m (default run.splay)")
+	rootCmd.Flags().StringVar(&traceFile, "trace", "", "write every command and http request of the checks with their output into this file")
+	rootCmd.Flags().StringVar(&outputTemplate, "output-template", "", "render the output with this go text/template file instead")
 }
 
-func initLogging() {
-	var format = logging.MustStringFormatter(
-		`%{color}%{time:15:04:05.000} %{shortfunc} - %{level:.4s} %{id:03x}%{color:reset} %{message}`,
-	)
-	stdOutBackend := logging.NewLogBackend(os.Stdout, "", 0)
-	logging.SetBackend(logging.NewBackendFormatter(stdOutBackend, format))
+func initConfig() {
+	setThresholdDefaults()
+	viper.SetDefault("logging.format", "text")
+	viper.SetDefault("logging.maxSize", 10)
+	viper.SetDefault("logging.maxBackups", 5)
+	viper.SetDefault("canary.image", "openshift/hello-openshift")
+	viper.SetDefault("canary.timeout", 120)
+	viper.SetDefault("diagnostics.timeout", 300)
+	viper.SetDefault("audit.maxAge", 60)
+	viper.SetDefault("ldapSync.maxAge", 24)
+	viper.SetDefault("master.url", "https://localhost:8443")
+	viper.SetDefault("webconsole.assets", "scripts/vendor.js,scripts/scripts.js,styles/main.css")
+	viper.SetDefault("storageComponents.cassandra.namespace", "openshift-infra")
+	viper.SetDefault("storageComponents.cassandra.selector", "type=hawkular-cassandra")
+	viper.SetDefault("storageComponents.cassandra.path", "/cassandra_data")
+	viper.SetDefault("storageComponents.elasticsearch.namespace", "logging")
+	viper.SetDefault("storageComponents.elasticsearch.selector", "component=es")
+	viper.SetDefault("storageComponents.elasticsearch.path", "/elasticsearch/persistent")
+	viper.SetDefault("store.keep", 100)
+	for category, weight := range defaultScoreWeights {
+		viper.SetDefault("score.weights."+category, weight)
+	}
+	viper.SetDefault("etcdLatency.quantile", 0.99)
+	viper.SetDefault("etcdBackup.minSize", 1)
+	viper.SetDefault("objectBackup.minObjects", 10)
+	viper.SetDefault("objectBackup.kinds", "Template,Secret,PersistentVolumeClaim")
+	viper.SetDefault("lock.enabled", true)
+	viper.SetDefault("tiers.deepEvery", 1)
+	viper.SetDefault("apiLoadBalancer.requests", 20)
+	viper.SetDefault("haproxy.socket", "/var/lib/haproxy/stats")
+	viper.SetDefault("diskHealth.window", 60)
+	viper.SetDefault("nfsMounts.timeout", 5)
+	viper.SetDefault("events.summary.maxLength", 1000)
+	viper.SetDefault("hysteresis.enabled", true)
+	viper.SetDefault("hysteresis.margin", 5)
+	viper.SetDefault("breaker.openAfter", 60)
+	viper.SetDefault("breaker.backoff", 10)
+	viper.SetDefault("breaker.maxBackoff", 240)
+	viper.SetDefault("breaker.reportWindow", 1440)
+	viper.SetDefault("events.summary.collapseLines", true)
+	viper.SetDefault("fluentdBuffer.path", "/var/lib/fluentd")
+	viper.SetDefault("ingestionLag.index", ".operations.*")
+	viper.SetDefault("dnsSrv.domain", "cluster.local")
+	viper.SetDefault("registryTrust.host", "docker-registry.default.svc:5000")
+	viper.SetDefault("kubeletDisks.nodefs", "/var/lib/origin")
+	viper.SetDefault("kubeletDisks.imagefs", "/var/lib/docker")
+	viper.SetDefault("lock.maxAge", 60)
+	viper.SetDefault("notifications.email.port", 25)
+	viper.SetDefault("notifications.email.tls", "starttls")
+	viper.SetDefault("notifications.pagerduty.url", "https://events.pagerduty.com/v2/enqueue")
+	viper.SetDefault("notifications.opsgenie.url", "https://api.opsgenie.com")
+	viper.SetDefault("notifications.kubernetesEvents.namespace", "default")
+	viper.SetDefault("notifications.snmp.port", 162)
+	viper.SetDefault("notifications.snmp.version", "2c")
+	viper.SetDefault("notifications.snmp.community", "public")
+	viper.SetDefault("notifications.snmp.enterpriseOid", ".1.3.6.1.4.1.32473.1")
+	viper.SetDefault("secrets.sops", "sops")
+	viper.SetDefault("baseline.sysctls", "vm.max_map_count,vm.overcommit_memory,vm.swappiness,net.ipv4.ip_forward,net.ipv4.ip_local_port_range,net.bridge.bridge-nf-call-iptables,kernel.pid_max,fs.file-max,fs.inotify.max_user_watches")
+	for _, name := range []string{"router-restarts-rate", "docker-pool-rate", "etcd-db-size-rate"} {
+		viper.SetDefault("rates."+name+".window", 60)
+	}
+	// the kernel memory of dying cgroups leaks slowly
+	viper.SetDefault("rates.kernel-slab-rate.window", 1440)
+	viper.SetDefault("accounting.top", 5)
+	viper.SetDefault("remoteConfig.type", "http")
+	viper.SetDefault("remoteConfig.timeout", 10)
+	viper.SetDefault("daemon.interval", 300)
+	viper.SetDefault("daemon.listen", ":8099")
+	viper.SetDefault("inCluster.nodeNameEnv", "NODE_NAME")
+	viper.SetDefault("inCluster.nodeTypeLabels.master", "node-role.kubernetes.io/master")
+	viper.SetDefault("inCluster.nodeTypeLabels.storage", "glusterfs")
+	viper.SetDefault("inCluster.nodeTypeLabels.infra", "node-role.kubernetes.io/infra,region=infra")
+	viper.SetDefault("security.sccAllowedNamespaces", "default,kube-system,openshift-infra,management-infra")
+	viper.SetDefault("anonymousAccess.paths", "/api/v1/secrets,/api/v1/namespaces,/api/v1/nodes,/oapi/v1/users")
 
-	if runtime.GOOS != "windows" {
-		sysLogBackend, err := logging.NewSyslogBackend("openshift-monitoring-cli")
+	if len(configFile) > 0 {
+		viper.SetConfigFile(configFile)
+		if err := viper.ReadInConfig(); err != nil {
+			log.Error("Not able to read config file", configFile, err)
+		}
+	} else {
+		ex, err := os.Executable()
 
 		if err != nil {
-			log.Warning("Wasn't able to initialize syslog.", err)
-		} else {
-			if debug {
-				sysLogFormatter := logging.NewBackendFormatter(sysLogBackend, format)
-				stdOutFormatter := logging.NewBackendFormatter(stdOutBackend, format)
-				logging.SetBackend(logging.MultiLogger(sysLogFormatter, stdOutFormatter))
-			} else {
-				logging.SetBackend(logging.NewBackendFormatter(sysLogBackend, format))
-			}
+			log.Critical(err)
+			os.Exit(1)
+		}
+
+		viper.AddConfigPath(filepath.Dir(ex))
+		viper.SetConfigName("config")
+
+		if err := viper.ReadInConfig(); err != nil {
+			log.Error("Not able to read config file (path of script is", filepath.Dir(ex)+")", "config.yml.")
 		}
 	}
 
-	if viper.GetString("logging.level") == "debug" {
-		logging.SetLevel(logging.DEBUG, "openshift-monitoring-cli")
-	} else {
-		logging.SetLevel(logging.INFO, "openshift-monitoring-cli")
+	if err := mergeConfigFile(); err != nil {
+		log.Critical(err)
+		os.Exit(1)
+	}
+	if err := mergeRemoteConfig(); err != nil {
+		log.Critical(err)
+		os.Exit(1)
+	}
+	if err := mergeConfigDir(); err != nil {
+		log.Critical(err)
+		os.Exit(1)
+	}
+	if err := mergeSopsFile(); err != nil {
+		log.Critical(err)
+		os.Exit(1)
 	}
 
-}
+	if len(profile) == 0 {
+		profile = viper.GetString("profile")
+	}
+	if len(profile) > 0 {
+		if err := applyProfile(profile); err != nil {
+			log.Critical(err)
+			os.Exit(1)
+		}
+	}
 
-func initConfig() {
-	ex, err := os.Executable()
+	if len(clusterName) == 0 {
+		clusterName = viper.GetString("cluster")
+	}
+	if len(clusterName) > 0 {
+		if err := applyCluster(clusterName); err != nil {
+			log.Critical(err)
+			os.Exit(1)
+		}
+	}
 
-	if err != nil {
+	applyNodeTypeDefaults()
+	warnUnknownFeatures()
+
+	if readOnly {
+		viper.Set("readOnly", true)
+	}
+	probes.SetReadOnly(viper.GetBool("readOnly"))
+	probes.SetCommandPaths(viper.GetStringMapString("commands"))
+	probes.SetMessages(viper.GetStringMapString("messages"))
+	probes.SetEtcdCertificates(viper.GetString("etcd.clientCert"), viper.GetString("etcd.clientKey"), viper.GetString("etcd.ca"))
+
+	if containerized {
+		viper.Set("containerized.enabled", true)
+	}
+	if viper.GetBool("containerized.enabled") {
+		if !viper.IsSet("hostRoot") {
+			viper.Set("hostRoot", "/host")
+		}
+		if err := probes.SetContainerized(viper.GetString("hostRoot")); err != nil {
+			log.Critical(err)
+			os.Exit(1)
+		}
+	}
+	probes.SetHostRoot(viper.GetString("hostRoot"))
+
+	if fakeChecks {
+		viper.Set("fakeChecks.enabled", true)
+	}
+	if viper.GetBool("fakeChecks.enabled") {
+		log.Warning("Faking the checks of the library, fakeChecks.enabled is set.")
+		checker = NewFakeChecker()
+	}
+
+	if err := resolveSecrets(); err != nil {
 		log.Critical(err)
 		os.Exit(1)
 	}
 
-	viper.AddConfigPath(filepath.Dir(ex))
-	viper.SetConfigName("config")
+	loadSeverityMappings()
+	loadQuietHours()
 
-	if err := viper.ReadInConfig(); err != nil {
-		log.Error("Not able to read config file (path of script is", filepath.Dir(ex)+")", "config.yml.")
+	if len(tags) > 0 {
+		viper.Set("checks.tags", tags)
+	}
+	if len(excludeTags) > 0 {
+		viper.Set("checks.excludeTags", excludeTags)
 	}
 
 }
 
 func createEvent(err error) map[string]interface{} {
 	var event = map[string]interface{}{}
-	event["summary"] = err.Error()
+	event["summary"] = summarize(err.Error())
+	if id := probes.MessageID(err); len(id) > 0 {
+		event["message_id"] = id
+	}
+	if len(clusterName) > 0 {
+		event["cluster"] = clusterName
+	}
+
+	// the fields of the event itself are never overwritten
+	for key, value := range viper.GetStringMapString("events.extraFields") {
+		if _, ok := event[key]; !ok && key != "category" {
+			event[key] = value
+		}
+	}
 	return event
 }
 
@@ -135,75 +317,464 @@ func createHealthyEvent(err error) map[string]interface{} {
 	return event
 }
 
-func evalMajor(fn func() error) {
-	if err := fn(); err != nil {
-		var event= createEvent(err)
-		event["category"] = "MAJOR"
-		log.Error("MAJOR:", err.Error())
+func evalMajor(ctx context.Context, name string, threshold string, fn func(ctx context.Context) error) {
+	eval(ctx, "MAJOR", name, threshold, fn)
+}
+
+func evalMinor(ctx context.Context, name string, threshold string, fn func(ctx context.Context) error) {
+	eval(ctx, "MINOR", name, threshold, fn)
+}
+
+// runs a check, records its result and adds an event if it failed
+func eval(ctx context.Context, category string, name string, threshold string, fn func(ctx context.Context) error) {
+	if !checkSelected(name) {
+		log.Debug("Skipping unselected check", name)
+		return
+	}
+
+	mapped := mapSeverity(name, category)
+	category = quietHoursCategory(name, mapped)
+	var result = checkResult{Name: name, Category: category, Threshold: threshold, Status: "OK"}
+	if category != mapped {
+		result.QuietCategory = mapped
+	}
+
+	if reason := skipReason(name); len(reason) > 0 {
+		log.Info("Skipping", name, reason)
+		result.Status = "SKIPPED"
+		result.Summary = reason
+		results = append(results, result)
+		return
+	}
+
+	// a missing command is reported, the check would only fail with a cryptic error
+	if err := missingCommand(name); err != nil {
+		log.Warning("Skipping", name, err.Error())
+		result.Status = "SKIPPED"
+		result.Summary = err.Error()
+		results = append(results, result)
+
+		var event = createEvent(errors.New(name + ": " + err.Error()))
+		event["category"] = category
+		event["message_id"] = probes.MessageID(err)
+		event["error_code"] = missingCommandCode
 		data.Events = append(data.Events, event)
+		return
 	}
-}
 
-func evalMinor(fn func() error) {
-	if err := fn(); err != nil {
+	setLogField("check", name)
+	setLogField("category", category)
+	probes.SetTraceCheck(name)
+	defer setLogField("check", "")
+	defer setLogField("category", "")
+	defer probes.SetTraceCheck("")
+
+	start := time.Now()
+	usage := currentUsage()
+	err, cached := takeReplayed(ctx, name, category)
+	if !cached {
+		err, cached = circuitResult(name, category)
+	}
+	value := &measuredValue{}
+	if !cached {
+		cached, err = runCached(category, name, func() error {
+			return runWithTimeout(context.WithValue(ctx, measuredValueKey{}, value), name, fn)
+		})
+	}
+	result.Duration = time.Since(start)
+	used := currentUsage().since(usage)
+	result.CpuTime, result.DiskBlocks = used.cpu, used.diskBlocks
+	result.Cached = cached
+	result.Value = value.get()
+
+	report := true
+	if err != errAborted {
+		report = recordCircuit(name, category, err, !cached)
+		result.CircuitOpen = openCircuit(name, category) != nil
+	}
+
+	if err == errAborted {
+		result.Status = "ABORTED"
+		result.Summary = summarize(err.Error())
+	} else if err != nil {
 		var event = createEvent(err)
-		event["category"] = "MINOR"
-		log.Error("MINOR:", err.Error())
-		data.Events = append(data.Events, event)
+		event["category"] = category
+		log.Error(category+":", err.Error())
+
+		result.Status = "FAILED"
+		result.Summary = summarize(err.Error())
+		result.MessageID = messageID(name, err)
+		result.ErrorCode = errorCodeOf(name, result.MessageID)
+		result.RunbookUrl, result.Hint = remediation(name)
+		event["message_id"] = result.MessageID
+		if len(result.ErrorCode) > 0 {
+			event["error_code"] = result.ErrorCode
+		}
+
+		if len(result.RunbookUrl) > 0 {
+			event["runbook_url"] = result.RunbookUrl
+		}
+		if len(result.Hint) > 0 {
+			event["hint"] = result.Hint
+		}
+		if report {
+			data.Events = append(data.Events, event)
+		} else {
+			unreportedCircuits = append(unreportedCircuits, category+"/"+name)
+		}
+		failedEvents[category+"/"+name] = event
 	}
+
+	results = append(results, result)
 }
 
 func runChecks(cmd *cobra.Command, args []string) {
-	log.Info("Running", viper.GetString("node.type"), "checks for OpenShift.")
+	sleepSplay()
+
+	ctx, cancel := runContext(context.Background())
+	defer cancel()
+	defer cancelOnSignal(cancel)()
+
+	start := time.Now()
+	if len(traceFile) > 0 {
+		probes.StartTrace()
+	}
+
+	if !validOutputFormat(outputFormat) {
+		log.Fatal("Unknown output format", outputFormat)
+	}
+	initInCluster()
+	validateConfig()
 
-	if viper.GetString("node.type") == "master" {
+	release := func() {}
+	if viper.GetBool("lock.enabled") {
+		var holder *runLockHolder
+		var err error
+		release, holder, err = acquireRunLock()
+		if err == errLocked {
+			reportLockedRun(holder)
+			return
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	defer release()
+
+	initAPIClient()
+	reconcileCheckConfigs()
+
+	executeRun(ctx)
+
+	outputResults()
+	saveTrace(start)
+
+	if sig, ok := abortSignal.(syscall.Signal); ok {
+		// os.Exit skips the deferred calls
+		release()
+		os.Exit(128 + int(sig))
+	}
+}
+
+// stops if the config misses settings the checks of the node type need
+func validateConfig() {
+	detectMasterDefaults()
+
+	if isNodeType("master") {
 		if len(viper.GetString("etcd.ips")) == 0 || len(viper.GetString("router.ips")) == 0 {
 			log.Fatal("Can't read service IPs from configuration file.")
 		}
 	}
+	if isNodeType("etcd") && len(viper.GetString("etcd.ips")) == 0 {
+		log.Fatal("Can't read the etcd member from the configuration file or", etcdConfigPath)
+	}
+}
+
+// runs all checks of the node type, stores the results and sends the
+// notifications. The results and events of a previous run are replaced.
+func executeRun(ctx context.Context) {
+	log.Info("Running", viper.GetString("node.type"), "checks for OpenShift.")
+
+	results = nil
+	unreportedCircuits = nil
+	data.Events = make([]EventData, 0)
+	data.Features = featureStates()
+	failedEvents = map[string]EventData{}
+	probes.ResetClients()
+
+	previous := previousRun()
+	planTiers(previous)
+	planHysteresis(previous)
+	planCircuits()
+	evalChecks(ctx)
+
+	if abortSignal != nil {
+		var event = createEvent(probes.NewMessage("run-aborted", abortSignal, countStatus(results, "ABORTED")))
+		event["category"] = "MINOR"
+		data.Events = append(data.Events, event)
+	}
+
+	// the run is not healthy while checks with an open circuit fail
+	if len(unreportedCircuits) > 0 {
+		var event = createEvent(probes.NewMessage("run-circuits-open", len(unreportedCircuits), strings.Join(unreportedCircuits, ", ")))
+		event["category"] = "INFO"
+		data.Events = append(data.Events, event)
+	}
+
+	if len(data.Events) == 0 {
+		data.Events = append(data.Events, createHealthyEvent(probes.NewMessage("run-healthy")))
+	}
 
+	correlateResults()
+	logTopChecks(results)
+	data.HealthScore = healthScore(results)
+	annotateStates(previous)
+	saveCache()
+	saveSamples()
+	saveTierState()
+	saveCircuits()
+	saveRun()
+	saveScoreTextfile()
+	sendNotifications(previous)
+	pushRun()
+	publishNodeStatus()
+	publishHealthReport()
+}
+
+// infra nodes run the node checks as well
+func runsNodeChecks() bool {
+	return isNodeType("node") || isNodeType("infra")
+}
+
+// the router, registry, metrics and logging checks run on the infra nodes
+// and, unless infra.dedicated is set, on the masters
+func runsInfraChecks() bool {
+	return isNodeType("infra") || isNodeType("master") && !viper.GetBool("infra.dedicated")
+}
+
+// evaluates all selected checks of the node type
+func evalChecks(ctx context.Context) {
 	/////////////////
 	//// MAJORS ////
 	////////////////
 	log.Debug("Running major checks.")
 
 	// majors on storage
-	if viper.GetString("node.type") == "storage" {
+	if isNodeType("storage") {
 		log.Debug("Running major checks for storage.")
 
-		evalMajor(func() error { return checks.CheckIfGlusterdIsRunning() })
-		evalMajor(func() error { return checks.CheckMountPointSizes(90) })
-		evalMajor(func() error { return checks.CheckLVPoolSizes(90) })
-		evalMajor(func() error { return checks.CheckVGSizes(5) })
+		evalMajor(ctx, "glusterd", "", func(ctx context.Context) error { return checker.CheckIfGlusterdIsRunning() })
+
+		// majors and minors
+		evalLevels(ctx, thresholdCheck("mount-point-sizes", "%", func(ctx context.Context, category string) error {
+			return checker.CheckMountPointSizes(threshold("mount-point-sizes", category))
+		}))
+		lvPools := thresholdCheck("lv-pool-sizes", "", checkLVPools)
+		lvPools.threshold = lvPoolThresholds
+		evalLevels(ctx, lvPools)
+		evalLevels(ctx, thresholdCheck("vg-sizes", "% free", func(ctx context.Context, category string) error {
+			return checker.CheckVGSizes(threshold("vg-sizes", category))
+		}))
+	}
+
+	// majors and minors of the geo-replication on storage
+	if isNodeType("storage") && viper.GetBool("geoReplication.enabled") {
+		evalGeoReplication(ctx)
+	}
+
+	// majors and minors on lb
+	if isNodeType("lb") {
+		log.Debug("Running checks for lb.")
+
+		evalHaproxy(ctx)
 	}
 
 	// majors on node
-	if viper.GetString("node.type") == "node" {
+	if runsNodeChecks() {
 		log.Debug("Running major checks for node.")
 
-		evalMajor(func() error { return checks.CheckDockerPool(90) })
-		evalMajor(func() error { return checks.CheckDnsNslookupOnKubernetes() })
-		evalMajor(func() error { return checks.CheckDnsServiceNode() })
+		evalMajor(ctx, "dns-nslookup-kubernetes", "", func(ctx context.Context) error { return checker.CheckDnsNslookupOnKubernetes() })
+		evalMajor(ctx, "dns-service-node", "", func(ctx context.Context) error { return checker.CheckDnsServiceNode() })
+
+		// majors and minors
+		evalLevels(ctx, thresholdCheck("docker-pool", "%", func(ctx context.Context, category string) error {
+			return checker.CheckDockerPool(threshold("docker-pool", category))
+		}))
+		evalLevels(ctx, kubeconfigExpiryCheck())
+	}
+
+	// the routers and the registry before the master checks, webconsole and
+	// canary-app are skipped if a router failed
+	if runsInfraChecks() {
+		log.Debug("Running router and registry checks for infra.")
+
+		if len(viper.GetString("registry.ip")) > 0 {
+			evalMajor(ctx, "registry-health", "", func(ctx context.Context) error { return checker.CheckRegistryHealth(viper.GetString("registry.ip")) })
+		}
+
+		for _, rip := range strings.Split(viper.GetString("router.ips"), ",") {
+			evalMajor(ctx, "router-health:"+rip, "", func(ctx context.Context) error { return checker.CheckRouterHealth(rip) })
+		}
 	}
 
 	// majors on master
-	if viper.GetString("node.type") == "master" {
+	if isNodeType("master") {
 		log.Debug("Running major checks for master.")
 
-		evalMajor(func() error { return checks.CheckOcGetNodes() })
-		evalMajor(func() error { return checks.CheckEtcdHealth(viper.GetString("etcd.ips"), "") })
+		// the control plane first, the checks depending on it are skipped if it failed
+		evalMajor(ctx, "etcd-health", "", func(ctx context.Context) error { return checker.CheckEtcdHealth(viper.GetString("etcd.ips"), "") })
+		evalMajor(ctx, "master-apis", "", func(ctx context.Context) error {
+			return checker.CheckMasterApis(viper.GetString("master.url") + "/api")
+		})
+		evalMajor(ctx, "oc-get-nodes", "", func(ctx context.Context) error { return checker.CheckOcGetNodes() })
 
-		if len(viper.GetString("registry.ip")) > 0 {
-			evalMajor(func() error { return checks.CheckRegistryHealth(viper.GetString("registry.ip")) })
+		evalMajor(ctx, "anonymous-access", "", func(ctx context.Context) error {
+			return probes.CheckAnonymousAccess(ctx, viper.GetString("master.url"), strings.Split(viper.GetString("anonymousAccess.paths"), ","))
+		})
+		if viper.GetBool("oauthLogin.enabled") {
+			evalMajor(ctx, "oauth-login", "", func(ctx context.Context) error {
+				return probes.CheckOAuthLogin(ctx, oauthUrl(), viper.GetString("oauthLogin.username"), viper.GetString("oauthLogin.password"))
+			})
+		}
+		if len(viper.GetString("apiLoadBalancer.url")) > 0 {
+			evalMajor(ctx, "api-load-balancer", "", func(ctx context.Context) error {
+				masters := etcdHosts()
+				if len(viper.GetString("apiLoadBalancer.masters")) > 0 {
+					masters = strings.Split(viper.GetString("apiLoadBalancer.masters"), ",")
+				}
+				return probes.CheckApiLoadBalancer(ctx, viper.GetString("apiLoadBalancer.url"), masters, viper.GetInt("apiLoadBalancer.requests"))
+			})
 		}
+		if viper.GetBool("webconsole.enabled") {
+			evalLevels(ctx, thresholdCheck("webconsole", "ms", checkWebconsole))
+		}
+		evalMajor(ctx, "dns-nslookup-kubernetes", "", func(ctx context.Context) error { return checker.CheckDnsNslookupOnKubernetes() })
+		evalMajor(ctx, "dns-service-node", "", func(ctx context.Context) error { return checker.CheckDnsServiceNode() })
+		evalLevels(ctx, kubeconfigExpiryCheck())
+		evalLevels(ctx, bootstrapTokenExpiryCheck())
 
-		for _, rip := range strings.Split(viper.GetString("router.ips"), ",") {
-			evalMajor(func() error { return checks.CheckRouterHealth(rip) })
+		if featureEnabled("canary") {
+			evalMajor(ctx, "canary-app", viper.GetString("canary.timeout")+"s", func(ctx context.Context) error {
+				return probes.CheckCanaryApp(ctx, viper.GetString("canary.namespace"), viper.GetString("canary.image"),
+					strings.Split(viper.GetString("router.ips"), ","), time.Duration(viper.GetInt("canary.timeout"))*time.Second)
+			})
+		}
+
+		// diagnostics are run once, warnings are reported as minors right away
+		if featureEnabled("diagnostics") && checkSelected("diagnostics") && (len(skipReason("diagnostics")) > 0 || missingCommand("diagnostics") != nil) {
+			// only records the result as skipped
+			evalMajor(ctx, "diagnostics", "", func(ctx context.Context) error { return nil })
+		} else if featureEnabled("diagnostics") && checkSelected("diagnostics") && len(replayCategories("diagnostics")) > 0 {
+			// the deep run is not due, the previous findings are reported again
+			replayDiagnostics(ctx)
+		} else if featureEnabled("diagnostics") && checkSelected("diagnostics") {
+			var modules []string
+			if len(viper.GetString("diagnostics.modules")) > 0 {
+				modules = strings.Split(viper.GetString("diagnostics.modules"), ",")
+			}
+
+			warnings, errs := probes.RunDiagnostics(ctx, modules, time.Duration(viper.GetInt("diagnostics.timeout"))*time.Second)
+			for _, err := range errs {
+				evalMajor(ctx, "diagnostics", "", func(ctx context.Context) error { return err })
+			}
+			for _, warning := range warnings {
+				evalMinor(ctx, "diagnostics", "", func(ctx context.Context) error { return warning })
+			}
+			if len(errs) == 0 && len(warnings) == 0 {
+				evalMajor(ctx, "diagnostics", "", func(ctx context.Context) error { return nil })
+			}
 		}
+	}
+
+	// majors on etcd
+	if isNodeType("etcd") {
+		log.Debug("Running major checks for etcd.")
+
+		evalMajor(ctx, "etcd-member-health", "", func(ctx context.Context) error {
+			return probes.CheckEtcdMemberHealth(ctx, strings.Split(viper.GetString("etcd.ips"), ","))
+		})
+		evalMajor(ctx, "etcd-member-list", "", func(ctx context.Context) error {
+			return probes.CheckEtcdMemberList(ctx, strings.Split(viper.GetString("etcd.ips"), ","))
+		})
+		evalLevels(ctx, levelCheck{
+			name:      "etcd-cert-expiry",
+			threshold: thresholdDisplay("etcd-cert-expiry", " days"),
+			measure: func(ctx context.Context) (interface{}, error) {
+				return probes.CertificateExpiries(strings.Split(viper.GetString("etcd.certificates"), ","))
+			},
+			evaluate: func(ctx context.Context, expiries interface{}, category string) error {
+				e, ok := expiries.([]probes.Expiry)
+				if !ok {
+					return unexpectedMeasurement("etcd-cert-expiry", expiries)
+				}
+				return probes.CheckCertificateExpiry(e, threshold("etcd-cert-expiry", category))
+			},
+			value: expiryValue,
+		})
+	}
+
+	// majors on infra
+	if runsInfraChecks() {
+		log.Debug("Running major checks for infra.")
 
-		evalMajor(func() error { return checks.CheckMasterApis("https://localhost:8443/api") })
-		evalMajor(func() error { return checks.CheckDnsNslookupOnKubernetes() })
-		evalMajor(func() error { return checks.CheckDnsServiceNode() })
+		if viper.GetBool("storageComponents.enabled") {
+			for _, c := range storageComponents() {
+				c := c
+				evalLevels(ctx, levelCheck{
+					name:      c.Name + "-storage",
+					threshold: thresholdDisplay(c.Name+"-storage", "%"),
+					measure: func(ctx context.Context) (interface{}, error) {
+						return probes.ComponentStorageUsage(ctx, c)
+					},
+					evaluate: func(ctx context.Context, usages interface{}, category string) error {
+						u, ok := usages.(map[string]int)
+						if !ok {
+							return unexpectedMeasurement(c.Name+"-storage", usages)
+						}
+						return probes.CheckComponentStorage(c, u, threshold(c.Name+"-storage", category))
+					},
+					value: func(usages interface{}) string {
+						u, _ := usages.(map[string]int)
+						max := 0
+						for _, usage := range u {
+							if usage > max {
+								max = usage
+							}
+						}
+						return fmt.Sprintf("%d%%", max)
+					},
+				})
+				evalLevels(ctx, levelCheck{
+					name:      c.Name + "-restarts",
+					threshold: thresholdDisplay(c.Name+"-restarts", ""),
+					measure: func(ctx context.Context) (interface{}, error) {
+						return c.Pods(ctx)
+					},
+					evaluate: func(ctx context.Context, pods interface{}, category string) error {
+						p, ok := pods.(probes.ComponentPods)
+						if !ok {
+							return unexpectedMeasurement(c.Name+"-restarts", pods)
+						}
+						return probes.CheckComponentRestarts(c, p, threshold(c.Name+"-restarts", category))
+					},
+					value: func(pods interface{}) string {
+						p, _ := pods.(probes.ComponentPods)
+						max := 0
+						for _, pod := range p.Items {
+							for _, status := range pod.Status.ContainerStatuses {
+								if status.RestartCount > max {
+									max = status.RestartCount
+								}
+							}
+						}
+						return fmt.Sprint(max)
+					},
+				})
+				if c.Name == "cassandra" {
+					evalCassandraHealth(ctx, c)
+				}
+			}
+		}
 	}
 
 	/////////////////
@@ -212,63 +783,347 @@ func runChecks(cmd *cobra.Command, args []string) {
 	log.Debug("Running minor checks.")
 
 	// minors on storage
-	if viper.GetString("node.type") == "storage" {
+	if isNodeType("storage") {
 		log.Debug("Running minor checks for storage.")
 
-		evalMinor(func() error { return checks.CheckOpenFileCount() })
-		evalMinor(func() error { return checks.CheckMountPointSizes(85) })
-		evalMinor(func() error { return checks.CheckLVPoolSizes(80) })
-		evalMinor(func() error { return checks.CheckVGSizes(10) })
+		evalMinor(ctx, "open-file-count", "", func(ctx context.Context) error { return checker.CheckOpenFileCount() })
 	}
 
 	// minors on node
-	if viper.GetString("node.type") == "node" {
+	if runsNodeChecks() {
 		log.Debug("Running minor checks for node.")
 
-		evalMinor(func() error { return checks.CheckDockerPool(80) })
-		evalMinor(func() error { return checks.CheckHttpService(false) })
+		evalMinor(ctx, "http-service", "", func(ctx context.Context) error { return checker.CheckHttpService(false) })
 	}
 
 	// minors on master
-	if viper.GetString("node.type") == "master" {
+	if isNodeType("master") {
 		log.Debug("Running minor checks for master.")
 
-		evalMinor(func() error { return checks.CheckExternalSystem(viper.GetString("externalSystemUrl")) })
-		evalMinor(func() error { return checks.CheckHawcularHealth(viper.GetString("hawcularIP")) })
-		evalMinor(func() error { return checks.CheckRouterRestartCount() })
-		evalMinor(func() error { return checks.CheckLimitsAndQuotas(viper.GetInt("projectsWithoutLimits")) })
-		evalMinor(func() error { return checks.CheckHttpService(false) })
-		evalMinor(func() error { return checks.CheckLoggingRestartsCount() })
+		evalMinor(ctx, "external-system", "", func(ctx context.Context) error {
+			return checker.CheckExternalSystem(viper.GetString("externalSystem.url"))
+		})
+		evalMinor(ctx, "limits-and-quotas", viper.GetString("limitsAndQuotas.projectsWithoutLimits"), func(ctx context.Context) error {
+			return checker.CheckLimitsAndQuotas(viper.GetInt("limitsAndQuotas.projectsWithoutLimits"))
+		})
+		evalMinor(ctx, "http-service", "", func(ctx context.Context) error { return checker.CheckHttpService(false) })
+
+		evalMinor(ctx, "privileged-sccs", "", func(ctx context.Context) error {
+			return probes.CheckPrivilegedSccs(ctx, strings.Split(viper.GetString("security.sccAllowedNamespaces"), ","))
+		})
+		evalMinor(ctx, "cluster-admin-service-accounts", "", func(ctx context.Context) error {
+			return probes.CheckClusterAdminServiceAccounts(ctx, strings.Split(viper.GetString("security.clusterAdminAllowed"), ","))
+		})
+
+		if len(viper.GetString("audit.logPath")) > 0 {
+			evalMinor(ctx, "audit-log", viper.GetString("audit.maxAge")+"m", func(ctx context.Context) error {
+				return probes.CheckAuditLogGrowing(viper.GetString("audit.logPath"), time.Duration(viper.GetInt("audit.maxAge"))*time.Minute)
+			})
+		}
+
+		if viper.GetBool("ldapSync.enabled") {
+			evalMinor(ctx, "ldap-sync", viper.GetString("ldapSync.maxAge")+"h", func(ctx context.Context) error {
+				return probes.CheckLdapSync(ctx, viper.GetString("ldapSync.url"), viper.GetString("ldapSync.bindDN"), viper.GetString("ldapSync.bindPassword"),
+					viper.GetBool("ldapSync.insecure"), time.Duration(viper.GetInt("ldapSync.maxAge"))*time.Hour)
+			})
+		}
+	}
+
+	// minors on infra
+	if runsInfraChecks() {
+		log.Debug("Running minor checks for infra.")
+
+		evalMinor(ctx, "hawcular-health", "", func(ctx context.Context) error { return checker.CheckHawcularHealth(viper.GetString("hawkular.ip")) })
+		evalMinor(ctx, "router-restart-count", "", func(ctx context.Context) error { return checker.CheckRouterRestartCount() })
+		evalMinor(ctx, "logging-restart-count", "", func(ctx context.Context) error { return checker.CheckLoggingRestartsCount() })
+	}
+
+	if viper.GetBool("rates.enabled") {
+		if runsInfraChecks() {
+			evalRate(ctx, "router-restarts-rate", "Router restarts", "", probes.RouterRestarts)
+		}
+		if isNodeType("master") {
+			evalRate(ctx, "etcd-db-size-rate", "etcd database size", "MB", func(ctx context.Context) (float64, error) {
+				return probes.EtcdDbSize(ctx, strings.Split(viper.GetString("etcd.ips"), ","))
+			})
+		}
+		if runsNodeChecks() {
+			evalRate(ctx, "docker-pool-rate", "Docker pool usage", "%", probes.DockerPoolUsage)
+		}
+		if isNodeType("master") || runsNodeChecks() {
+			evalRate(ctx, "kernel-slab-rate", "Unreclaimable slab memory", "MB", func(ctx context.Context) (float64, error) {
+				return probes.SlabUnreclaimable()
+			})
+		}
+	}
+
+	if isNodeType("master") && viper.GetBool("clockSkew.enabled") {
+		evalClockSkew(ctx)
+	}
+	if (isNodeType("master") || isNodeType("etcd")) && viper.GetBool("etcdLatency.enabled") {
+		evalEtcdLatency(ctx)
+	}
+	if isNodeType("master") && viper.GetBool("etcdBackup.enabled") {
+		evalBackup(ctx, "etcd-backup", "etcdBackup", "etcd", false)
+	}
+	if isNodeType("master") && viper.GetBool("objectBackup.enabled") {
+		evalBackup(ctx, "object-backup", "objectBackup", "object", true)
 	}
 
 	log.Debug("Running minor checks for all node types.")
 	// minor for all server types
-	evalMinor(func() error { return checks.CheckNtpd() })
+	evalNtp(ctx)
 
-	if len(data.Events) == 0 {
-		data.Events = append(data.Events, createHealthyEvent(errors.New("System healthy, nothing to do.")));
+	if (isNodeType("master") || runsNodeChecks()) && viper.GetBool("nfsMounts.enabled") {
+		evalMajor(ctx, "nfs-mounts", viper.GetString("nfsMounts.timeout")+"s", func(ctx context.Context) error {
+			return probes.CheckNfsMounts(time.Duration(viper.GetInt("nfsMounts.timeout")) * time.Second)
+		})
+	}
+	if (isNodeType("master") || runsNodeChecks()) && viper.GetBool("fluentdBuffer.enabled") {
+		evalFluentdBuffer(ctx)
+	}
+	if (isNodeType("master") || runsNodeChecks()) && len(viper.GetString("ingestionLag.url")) > 0 {
+		evalIngestionLag(ctx)
+	}
+	if runsNodeChecks() && viper.GetBool("registryTrust.enabled") {
+		evalMinor(ctx, "registry-trust", "", func(ctx context.Context) error {
+			return probes.CheckRegistryTrust(ctx, viper.GetString("registryTrust.host"), viper.GetString("registry.ip"))
+		})
+	}
+	if runsNodeChecks() && viper.GetBool("kubeletDisks.enabled") {
+		evalKubeletDisks(ctx)
+	}
+	if (isNodeType("master") || runsNodeChecks()) && viper.GetBool("processes.enabled") {
+		evalProcesses(ctx)
+	}
+	if (isNodeType("master") || runsNodeChecks()) && viper.GetBool("memoryCgroups.enabled") {
+		evalLevels(ctx, levelCheck{
+			name:      "dying-cgroups",
+			threshold: thresholdDisplay("dying-cgroups", ""),
+			measure: func(ctx context.Context) (interface{}, error) {
+				return probes.ReadMemoryCgroups()
+			},
+			evaluate: func(ctx context.Context, measurement interface{}, category string) error {
+				cgroups, ok := measurement.(probes.MemoryCgroups)
+				if !ok {
+					return unexpectedMeasurement("dying-cgroups", measurement)
+				}
+				return probes.CheckDyingCgroups(cgroups, threshold("dying-cgroups", category))
+			},
+			value: func(measurement interface{}) string {
+				cgroups, _ := measurement.(probes.MemoryCgroups)
+				return fmt.Sprint(cgroups.Dying)
+			},
+		})
+	}
+	if runsNodeChecks() && viper.GetBool("podDensity.enabled") {
+		evalLevels(ctx, levelCheck{
+			name:      "pod-density",
+			threshold: thresholdDisplay("pod-density", "%"),
+			measure: func(ctx context.Context) (interface{}, error) {
+				return probes.ReadPodDensity(ctx, nodeConfigPath)
+			},
+			evaluate: func(ctx context.Context, measurement interface{}, category string) error {
+				density, ok := measurement.(probes.PodDensity)
+				if !ok {
+					return unexpectedMeasurement("pod-density", measurement)
+				}
+				return probes.CheckPodDensity(density, threshold("pod-density", category))
+			},
+			value: func(measurement interface{}) string {
+				density, _ := measurement.(probes.PodDensity)
+				if density.Limit() <= 0 {
+					return fmt.Sprint(density.Pods)
+				}
+				return fmt.Sprintf("%d%%", density.Pods*100/density.Limit())
+			},
+		})
+	}
+	if (isNodeType("storage") || runsNodeChecks()) && viper.GetBool("diskHealth.enabled") {
+		evalDiskHealth(ctx)
+	}
+
+	if isNodeType("master") && viper.GetBool("cloudProvider.enabled") && len(viper.GetString("cloudProvider.type")) > 0 {
+		evalMinor(ctx, "cloud-provider", viper.GetString("cloudProvider.type"), func(ctx context.Context) error {
+			return probes.CheckCloudProvider(ctx, viper.GetString("cloudProvider.type"), viper.GetString("cloudProvider.config"))
+		})
+	}
+	if isNodeType("master") && len(viper.GetString("vsphereDatastores.url")) > 0 {
+		evalVsphereDatastores(ctx)
 	}
+	if isNodeType("master") && len(viper.GetString("prometheus.url")) > 0 {
+		prometheus := probes.Prometheus{Url: viper.GetString("prometheus.url"), Token: viper.GetString("prometheus.token"), Insecure: viper.GetBool("prometheus.insecure")}
+		evalMinor(ctx, "prometheus-targets", "", func(ctx context.Context) error {
+			var jobs []string
+			if len(viper.GetString("prometheus.jobs")) > 0 {
+				jobs = strings.Split(viper.GetString("prometheus.jobs"), ",")
+			}
+			return probes.CheckPrometheusTargets(ctx, prometheus, jobs)
+		})
+		evalMinor(ctx, "prometheus-rules", "", func(ctx context.Context) error { return probes.CheckPrometheusRules(ctx, prometheus) })
+		evalMinor(ctx, "prometheus-alertmanagers", "", func(ctx context.Context) error { return probes.CheckPrometheusAlertmanagers(ctx, prometheus) })
+	}
+	if viper.GetBool("dnsConsistency.enabled") {
+		evalMinor(ctx, "dns-consistency", "", func(ctx context.Context) error {
+			nodeIp := viper.GetString("dnsConsistency.nodeIp")
+			if len(nodeIp) == 0 {
+				var err error
+				if nodeIp, err = probes.LocalIp(viper.GetString("master.url")); err != nil {
+					log.Warning("Not able to detect the node ip, only checking that the hostname resolves", err)
+				}
+			}
+			return probes.CheckDnsConsistency(ctx, strings.Split(viper.GetString("dnsConsistency.hosts"), ","), nodeIp)
+		})
+	}
+	if isNodeType("master") && len(viper.GetString("dnsSrv.service")) > 0 {
+		evalMinor(ctx, "dns-srv", "", func(ctx context.Context) error {
+			return probes.CheckDnsSrv(ctx, viper.GetString("dnsSrv.namespace"), viper.GetString("dnsSrv.service"), viper.GetString("dnsSrv.port"), viper.GetString("dnsSrv.domain"))
+		})
+	}
+
+	if viper.GetBool("baseline.enabled") {
+		evalMinor(ctx, "baseline-drift", "", func(ctx context.Context) error {
+			path, err := baselineFile()
+			if err != nil {
+				return err
+			}
+			return probes.CheckBaselineDrift(ctx, path, baselineOptions())
+		})
+	}
+}
 
-	OutputJSON(data)
+// checks the data usage of the thin pools with the library and their
+// metadata usage with thresholds.lv-pool-metadata
+func checkLVPools(ctx context.Context, category string) error {
+	if err := checker.CheckLVPoolSizes(threshold("lv-pool-sizes", category)); err != nil {
+		return err
+	}
+	pools, err := probes.ThinPools(ctx)
+	if err != nil {
+		return err
+	}
+	return probes.CheckThinPoolMetadata(pools, threshold("lv-pool-metadata", category))
 }
 
-func OutputJSON(data interface{}) {
-	var output []byte
-	var err error
+func lvPoolThresholds(category string) string {
+	return fmt.Sprintf("%d%% data, %d%% metadata", threshold("lv-pool-sizes", category), threshold("lv-pool-metadata", category))
+}
 
-	if pretty {
-		output, err = json.MarshalIndent(data, "", "\t")
-	} else {
-		output, err = json.Marshal(data)
+// returns the runbook url and remediation hint configured for a check
+func remediation(name string) (string, string) {
+	name = strings.SplitN(name, ":", 2)[0]
+	return viper.GetString("remediation." + name + ".runbookUrl"), viper.GetString("remediation." + name + ".hint")
+}
+
+// lets the checks call the api directly instead of running oc if api.enabled
+// is set. Without api.kubeconfig the service account of the pod is used.
+func initAPIClient() {
+	if !viper.GetBool("api.enabled") {
+		return
 	}
 
+	client, err := probes.NewAPIClient(viper.GetString("api.kubeconfig"))
 	if err != nil {
-		log.Errorf("Error outputting JSON (%s).", err)
+		log.Error("Not able to use the api, falling back to oc:", err)
+		return
 	}
+	probes.UseAPIClient(client)
+	apiClient = client
+}
 
-	if string(output) == "null" {
-		fmt.Print("[]")
-	} else {
-		fmt.Print(string(output))
+// the client of the api, nil if api.enabled is not set
+var apiClient *probes.APIClient
+
+// returns the configured kubeconfigs, see nodeTypeDefaults for the defaults
+func kubeconfigPaths() []string {
+	return strings.Split(viper.GetString("kubeconfigs.paths"), ",")
+}
+
+// the kubeconfigs are read once for the major and the minor
+func kubeconfigExpiryCheck() levelCheck {
+	return levelCheck{
+		name:      "kubeconfig-expiry",
+		threshold: thresholdDisplay("kubeconfig-expiry", " days"),
+		measure: func(ctx context.Context) (interface{}, error) {
+			return probes.KubeconfigExpiries(kubeconfigPaths())
+		},
+		evaluate: func(ctx context.Context, expiries interface{}, category string) error {
+			e, ok := expiries.([]probes.Expiry)
+			if !ok {
+				return unexpectedMeasurement("kubeconfig-expiry", expiries)
+			}
+			return probes.CheckKubeconfigExpiry(e, threshold("kubeconfig-expiry", category))
+		},
+		value: expiryValue,
+	}
+}
+
+// the bootstrap tokens of the cluster are read once for the major and the minor
+func bootstrapTokenExpiryCheck() levelCheck {
+	return levelCheck{
+		name:      "bootstrap-token-expiry",
+		threshold: thresholdDisplay("bootstrap-token-expiry", " days"),
+		measure: func(ctx context.Context) (interface{}, error) {
+			return probes.BootstrapTokenExpiries(ctx)
+		},
+		evaluate: func(ctx context.Context, expiries interface{}, category string) error {
+			e, ok := expiries.([]probes.Expiry)
+			if !ok {
+				return unexpectedMeasurement("bootstrap-token-expiry", expiries)
+			}
+			return probes.CheckBootstrapTokenExpiry(e, threshold("bootstrap-token-expiry", category))
+		},
+		value: expiryValue,
+	}
+}
+
+// returns the days until the first of the expiries
+func expiryValue(expiries interface{}) string {
+	e, _ := expiries.([]probes.Expiry)
+	if len(e) == 0 {
+		return ""
+	}
+	first := e[0].NotAfter
+	for _, expiry := range e {
+		if expiry.NotAfter.Before(first) {
+			first = expiry.NotAfter
+		}
+	}
+	return fmt.Sprintf("%d days", int(time.Until(first).Hours()/24))
+}
+
+// checks the web console through the configured routers with the latency threshold of the category
+func checkWebconsole(ctx context.Context, category string) error {
+	var routerIps []string
+	if len(viper.GetString("router.ips")) > 0 {
+		routerIps = strings.Split(viper.GetString("router.ips"), ",")
+	}
+	return probes.CheckWebconsole(ctx, viper.GetString("webconsole.url"), routerIps,
+		strings.Split(viper.GetString("webconsole.assets"), ","), time.Duration(threshold("webconsole", category))*time.Millisecond)
+}
+
+// returns the metrics and logging components of storageComponents which are not disabled
+func storageComponents() []probes.Component {
+	var components []probes.Component
+	for _, name := range []string{"cassandra", "elasticsearch"} {
+		key := "storageComponents." + name
+		if viper.IsSet(key+".enabled") && !viper.GetBool(key+".enabled") {
+			continue
+		}
+		components = append(components, probes.Component{
+			Name:      name,
+			Namespace: viper.GetString(key + ".namespace"),
+			Selector:  viper.GetString(key + ".selector"),
+			Path:      viper.GetString(key + ".path"),
+		})
+	}
+	return components
+}
+
+// returns oauthLogin.url or the master url
+func oauthUrl() string {
+	if url := viper.GetString("oauthLogin.url"); len(url) > 0 {
+		return url
 	}
+	return viper.GetString("master.url")
 }
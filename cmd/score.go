@@ -0,0 +1,96 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// the points a failed check of the category costs, see score.weights
+var defaultScoreWeights = map[string]int{"critical": 50, "major": 20, "minor": 5, "warning": 1, "info": 0}
+
+// returns the health score of the results, 100 minus the weights of the
+// failed checks but at least 0. A check can have its own weight in
+// score.checks.<check>, otherwise the weight of its category is used.
+func healthScore(results []checkResult) int {
+	score := 100
+	for _, r := range results {
+		if r.Status != "FAILED" {
+			continue
+		}
+
+		key := "score.checks." + strings.SplitN(r.Name, ":", 2)[0]
+		if viper.IsSet(key) {
+			score -= viper.GetInt(key)
+		} else {
+			score -= viper.GetInt("score.weights." + strings.ToLower(r.Category))
+		}
+	}
+
+	if score < 0 {
+		return 0
+	}
+	return score
+}
+
+// writes the health score as prometheus gauge
+func writeScoreMetrics(w io.Writer, score int) {
+	fmt.Fprintln(w, "# HELP openshift_monitoring_health_score Health score of the latest run, 100 if no check failed.")
+	fmt.Fprintln(w, "# TYPE openshift_monitoring_health_score gauge")
+	fmt.Fprintf(w, "openshift_monitoring_health_score{node=%q,node_type=%q} %d\n", nodeName(), viper.GetString("node.type"), score)
+}
+
+// writes the health score into score.textfile for the textfile collector
+// of the node exporter. The file is replaced atomically, so the collector
+// never reads a partial file.
+func saveScoreTextfile() {
+	path := viper.GetString("score.textfile")
+	if len(path) == 0 {
+		return
+	}
+
+	var out bytes.Buffer
+	writeScoreMetrics(&out, data.HealthScore)
+	writeCheckMetrics(&out, results)
+
+	if err := ioutil.WriteFile(path+".tmp", out.Bytes(), 0644); err != nil {
+		log.Error("Not able to write health score", path, err)
+		return
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		log.Error("Not able to write health score", path, err)
+	}
+}
+
+// serves the health score of the latest run of the daemon
+func handleMetrics(w http.ResponseWriter, r *http.Request) {
+	run := latestRun()
+	if run == nil {
+		http.Error(w, "No run finished yet", http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	writeScoreMetrics(w, run.HealthScore)
+	writeCheckMetrics(w, run.Results)
+}
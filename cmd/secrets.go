@@ -0,0 +1,104 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// matches references to secrets in config values, e.g. ${env:HEKETI_KEY}
+// or ${file:/etc/openshift-monitoring-cli/heketi-key}
+var secretReference = regexp.MustCompile(`\$\{(env|file):([^}]+)\}`)
+
+// merges the sops encrypted file secrets.sopsFile into the config. The file
+// has the same structure as config.yml and is decrypted with the sops binary,
+// which supports age, pgp and the cloud kms.
+func mergeSopsFile() error {
+	path := viper.GetString("secrets.sopsFile")
+	if len(path) == 0 {
+		return nil
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.Command(viper.GetString("secrets.sops"), "--decrypt", "--output-type", "yaml", path)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return fmt.Errorf("Not able to decrypt %s: %v %s", path, err, strings.TrimSpace(stderr.String()))
+	}
+
+	viper.SetConfigType("yaml")
+	if err := viper.MergeConfig(bytes.NewReader(out)); err != nil {
+		return fmt.Errorf("Not able to merge %s: %v", path, err)
+	}
+	return nil
+}
+
+// replaces the secret references in all config values
+func resolveSecrets() error {
+	for key, value := range flattenSettings("", viper.AllSettings()) {
+		s, ok := value.(string)
+		if !ok || !secretReference.MatchString(s) {
+			continue
+		}
+
+		resolved, err := resolveReferences(key, s)
+		if err != nil {
+			return err
+		}
+		viper.Set(key, resolved)
+	}
+	return nil
+}
+
+// replaces the secret references in the value of the key
+func resolveReferences(key string, value string) (string, error) {
+	var resolveErr error
+	resolved := secretReference.ReplaceAllStringFunc(value, func(ref string) string {
+		m := secretReference.FindStringSubmatch(ref)
+		secret, err := resolveSecret(m[1], m[2])
+		if err != nil && resolveErr == nil {
+			resolveErr = fmt.Errorf("Not able to resolve secret of %s: %v", key, err)
+		}
+		return secret
+	})
+	return resolved, resolveErr
+}
+
+func resolveSecret(source string, name string) (string, error) {
+	switch source {
+	case "env":
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", name)
+		}
+		return value, nil
+	case "file":
+		content, err := ioutil.ReadFile(name)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(string(content)), nil
+	}
+	return "", fmt.Errorf("unknown secret source %s", source)
+}
@@ -0,0 +1,217 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// the checks of a run share the results and events, so the scheduled runs
+// and the targeted runs of the server must not overlap
+var runLock sync.Mutex
+
+// the latest scheduled run of the daemon. It is only replaced under runLock
+// and lastRunLock, the handlers read it with latestRun without waiting for
+// a run to finish.
+var lastRun *storedRun
+var lastRunLock sync.Mutex
+
+// the checks of the targeted run which is running, nil for all checks
+var targetedChecks []string
+
+// keeps the results of the scheduled run which just finished
+func rememberRun() {
+	run := &storedRun{
+		Time:     time.Now().UTC(),
+		Hostname: nodeName(),
+		NodeType: viper.GetString("node.type"),
+		Events:   data.Events,
+		Results:  results,
+
+		HealthScore: data.HealthScore,
+		Features:    data.Features,
+	}
+
+	lastRunLock.Lock()
+	lastRun = run
+	lastRunLock.Unlock()
+}
+
+// returns the latest scheduled run, nil if none finished yet
+func latestRun() *storedRun {
+	lastRunLock.Lock()
+	defer lastRunLock.Unlock()
+	return lastRun
+}
+
+// runs only the given checks, by name or name:target, and returns their
+// results. The results are neither stored nor notified and don't replace
+// the results of the latest scheduled run.
+func runTargeted(ctx context.Context, names []string) []checkResult {
+	runLock.Lock()
+	defer runLock.Unlock()
+
+	targetedChecks = names
+	defer func() { targetedChecks = nil }()
+
+	log.Info("Running targeted checks", strings.Join(names, ", "))
+	results = nil
+	data.Events = make([]EventData, 0)
+	failedEvents = map[string]EventData{}
+
+	runCtx, cancel := runContext(ctx)
+	defer cancel()
+	evalChecks(runCtx)
+
+	targeted := results
+	if lastRun != nil {
+		results, data.Events = lastRun.Results, lastRun.Events
+	}
+	return targeted
+}
+
+// serves the results api of the daemon on server.listen with mutual tls.
+// GET /v1/results returns the latest scheduled run, GET /v1/checks the check
+// catalog and POST /v1/checks/<name>/run runs a check and returns its results.
+func serveResults(ctx context.Context, cancel context.CancelFunc) {
+	tlsConfig, err := serverTLSConfig()
+	if err != nil {
+		log.Error("Not able to start the results api:", err)
+		cancel()
+		return
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/results", handleResults)
+	mux.HandleFunc("/v1/checks", handleChecks)
+	mux.HandleFunc("/v1/checks/", handleRunCheck(ctx))
+
+	server := &http.Server{Addr: viper.GetString("server.listen"), Handler: mux, TLSConfig: tlsConfig}
+	go func() {
+		<-ctx.Done()
+		server.Close()
+	}()
+
+	log.Info("Serving results api on", server.Addr)
+	if err := server.ListenAndServeTLS("", ""); err != nil && err != http.ErrServerClosed {
+		log.Error("Results api failed:", err)
+		cancel()
+	}
+}
+
+// requires client certificates signed by server.clientCA
+func serverTLSConfig() (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(viper.GetString("server.tlsCert"), viper.GetString("server.tlsKey"))
+	if err != nil {
+		return nil, err
+	}
+
+	ca, err := ioutil.ReadFile(viper.GetString("server.clientCA"))
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	pool.AppendCertsFromPEM(ca)
+
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientCAs:    pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		MinVersion:   tls.VersionTLS12,
+	}, nil
+}
+
+func handleResults(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	run := latestRun()
+	if run == nil {
+		http.Error(w, "No run finished yet", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, run)
+}
+
+func handleChecks(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	writeJSON(w, checkCatalog)
+}
+
+func handleRunCheck(ctx context.Context) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/v1/checks/"), "/run")
+		if r.Method != "POST" || !strings.HasSuffix(r.URL.Path, "/run") || len(name) == 0 {
+			http.NotFound(w, r)
+			return
+		}
+
+		writeJSON(w, map[string]interface{}{"results": runTargeted(ctx, []string{name})})
+	}
+}
+
+// runs the checks of the comma separated checks parameter. The request must
+// carry daemon.runToken as bearer token, without token the endpoint is disabled.
+func handleRun(ctx context.Context) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := viper.GetString("daemon.runToken")
+		if len(token) == 0 {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Method != "POST" {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte("Bearer "+token)) != 1 {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		names := strings.Split(r.URL.Query().Get("checks"), ",")
+		if len(names[0]) == 0 {
+			http.Error(w, "Parameter checks is missing", http.StatusBadRequest)
+			return
+		}
+
+		writeJSON(w, map[string]interface{}{"results": runTargeted(ctx, names)})
+	}
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	content, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(content)
+}
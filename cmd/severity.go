@@ -0,0 +1,109 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// the categories of events from the lowest to the highest severity. Checks
+// are run as MAJOR or MINOR, severities.mappings can move them to the others
+// and quietHours minors to INFO.
+var severities = []string{"INFO", "WARNING", "MINOR", "MAJOR", "CRITICAL"}
+
+// changes the category of matching checks, e.g.
+//   - check: etcd-health
+//     from: MAJOR
+//     to: CRITICAL
+type severityMapping struct {
+	// name of the check, empty matches all checks
+	Check string
+	// tag of the check, empty matches all checks
+	Tag string
+	// category to map, empty matches all categories
+	From string
+	To   string
+}
+
+var severityMappings []severityMapping
+
+// loads severities.mappings, invalid mappings are ignored
+func loadSeverityMappings() {
+	var mappings []severityMapping
+	if err := viper.UnmarshalKey("severities.mappings", &mappings); err != nil {
+		log.Error("Not able to read severities.mappings:", err)
+		return
+	}
+
+	severityMappings = nil
+	for _, m := range mappings {
+		m.From = strings.ToUpper(m.From)
+		m.To = strings.ToUpper(m.To)
+		if severityRank(m.To) < 0 {
+			log.Error("Ignoring severity mapping of", m.Check, m.Tag, "to unknown category", m.To)
+			continue
+		}
+		severityMappings = append(severityMappings, m)
+	}
+}
+
+// returns the category of a check after applying the first matching mapping
+func mapSeverity(name string, category string) string {
+	info, _ := catalogEntry(name)
+	name = strings.SplitN(name, ":", 2)[0]
+
+	for _, m := range severityMappings {
+		if (len(m.Check) == 0 || m.Check == name) &&
+			(len(m.Tag) == 0 || containsString(info.Tags, m.Tag)) &&
+			(len(m.From) == 0 || m.From == category) {
+			return m.To
+		}
+	}
+	return category
+}
+
+// returns the rank of a category, higher is more severe, -1 if unknown
+func severityRank(category string) int {
+	for i, s := range severities {
+		if s == category {
+			return i
+		}
+	}
+	return -1
+}
+
+// returns the results with a category of at least min
+func filterAtLeast(results []checkResult, min string) []checkResult {
+	var filtered []checkResult
+	for _, r := range results {
+		if severityRank(r.Category) >= severityRank(min) {
+			filtered = append(filtered, r)
+		}
+	}
+	return filtered
+}
+
+// returns the highest category of the results or an empty string
+func worstSeverity(results []checkResult) string {
+	var worst string
+	for _, r := range results {
+		if severityRank(r.Category) > severityRank(worst) {
+			worst = r.Category
+		}
+	}
+	return worst
+}
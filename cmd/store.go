@@ -0,0 +1,134 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// file name layout of stored runs, sorts chronologically
+const runFileLayout = "run-20060102T150405.000000000Z.json"
+
+// a single run of the checks as persisted in the result store
+type storedRun struct {
+	Time     time.Time     `json:"time"`
+	Hostname string        `json:"hostname"`
+	NodeType string        `json:"node_type"`
+	Events   []EventData   `json:"events"`
+	Results  []checkResult `json:"results"`
+	// the health score of the run, see score.weights
+	HealthScore int `json:"health_score"`
+	// the state of the features during the run, see features
+	Features map[string]bool `json:"features,omitempty"`
+}
+
+// saves the current run into the result store, if one is configured, and
+// removes the oldest runs above store.keep
+func saveRun() {
+	dir := viper.GetString("store.path")
+	if len(dir) == 0 {
+		return
+	}
+
+	var run = storedRun{
+		Time:     time.Now().UTC(),
+		NodeType: viper.GetString("node.type"),
+		Events:   data.Events,
+		Hostname: nodeName(),
+		Results:  results,
+
+		HealthScore: data.HealthScore,
+		Features:    data.Features,
+	}
+
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		log.Error("Not able to create result store", dir, err)
+		return
+	}
+
+	content, err := json.Marshal(run)
+	if err != nil {
+		log.Error("Not able to serialize run", err)
+		return
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dir, run.Time.Format(runFileLayout)), content, 0640); err != nil {
+		log.Error("Not able to save run into result store", err)
+		return
+	}
+
+	files, _ := runFiles(dir)
+	for keep := viper.GetInt("store.keep"); len(files) > keep; files = files[1:] {
+		if err := os.Remove(files[0]); err != nil {
+			log.Warning("Not able to remove old run", files[0], err)
+		}
+	}
+}
+
+// loads all stored runs between from and to (both inclusive, zero means
+// unbounded), sorted from oldest to newest
+func loadRuns(from time.Time, to time.Time) ([]storedRun, error) {
+	files, err := runFiles(viper.GetString("store.path"))
+	if err != nil {
+		return nil, err
+	}
+
+	var runs []storedRun
+	for _, file := range files {
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		var run storedRun
+		if err := json.Unmarshal(content, &run); err != nil {
+			log.Warning("Skipping unreadable run", file, err)
+			continue
+		}
+
+		if (!from.IsZero() && run.Time.Before(from)) || (!to.IsZero() && run.Time.After(to)) {
+			continue
+		}
+		runs = append(runs, run)
+	}
+
+	return runs, nil
+}
+
+// returns the stored run files sorted from oldest to newest
+func runFiles(dir string) ([]string, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), "run-") && strings.HasSuffix(entry.Name(), ".json") {
+			files = append(files, filepath.Join(dir, entry.Name()))
+		}
+	}
+	sort.Strings(files)
+
+	return files, nil
+}
@@ -0,0 +1,73 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/oscp/openshift-monitoring-cli/probes"
+	"github.com/spf13/viper"
+)
+
+// ansi escape sequences like the colors of some commands
+var ansiPattern = regexp.MustCompile(`\x1b\[[0-9;?]*[ -/]*[@-~]`)
+
+// bounds the summary of a result or event, a check failing with the pages
+// of output of a command would otherwise blow up the events. Escape
+// sequences are stripped, the lines are collapsed into one unless
+// events.summary.collapseLines is false and the summary is truncated to
+// events.summary.maxLength characters.
+func summarize(summary string) string {
+	summary = ansiPattern.ReplaceAllString(summary, "")
+
+	if viper.GetBool("events.summary.collapseLines") {
+		var lines []string
+		for _, line := range strings.Split(summary, "\n") {
+			if line = strings.TrimSpace(line); len(line) > 0 {
+				lines = append(lines, line)
+			}
+		}
+		summary = strings.Join(lines, " | ")
+	}
+
+	// the remaining control characters would break line based consumers
+	summary = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) && r != '\n' && r != '\t' {
+			return -1
+		}
+		return r
+	}, summary)
+
+	max := viper.GetInt("events.summary.maxLength")
+	if length := utf8.RuneCountInString(summary); max > 0 && length > max {
+		runes := []rune(summary)
+		summary = fmt.Sprintf("%s... (%d more characters)", string(runes[:max]), length-max)
+	}
+	return summary
+}
+
+// returns the id of the message of a failed check in the message catalog.
+// Failures which are not in the catalog, like those of the library, get
+// the id <check>-failed.
+func messageID(name string, err error) string {
+	if id := probes.MessageID(err); len(id) > 0 {
+		return id
+	}
+	return strings.SplitN(name, ":", 2)[0] + "-failed"
+}
@@ -0,0 +1,223 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/oscp/openshift-monitoring-cli/probes"
+	"github.com/spf13/viper"
+)
+
+// the tiers of the checks. Fast checks run in every run, deep checks only
+// if a deep run is due, otherwise their previous results are reported again.
+const (
+	tierFast = "fast"
+	tierDeep = "deep"
+)
+
+// when the deep checks ran last, persisted in the result store
+type tierState struct {
+	// runs since the last deep run
+	Runs     int       `json:"runs"`
+	LastDeep time.Time `json:"last_deep"`
+}
+
+var tiers *tierState
+
+// the tiers run, --tier
+var tierFlag string
+
+// if the deep checks run in the current run
+var deepDue = true
+
+// results of the previous run which are reported again for deep checks
+// which are not due, each result is reported once
+var replayResults []checkResult
+
+// returns the tier of a check from tiers.checks.<check>, checks tagged
+// deep are deep by default
+func checkTier(name string) string {
+	name = strings.SplitN(name, ":", 2)[0]
+	if tier := viper.GetString("tiers.checks." + name); len(tier) > 0 {
+		return tier
+	}
+	if info, ok := catalogEntry(name); ok && containsString(info.Tags, "deep") {
+		return tierDeep
+	}
+	return tierFast
+}
+
+// decides if the deep checks run: always with --tier all, never with
+// --tier fast and otherwise every tiers.deepEvery runs or, if set, every
+// tiers.deepInterval seconds
+func planTiers(previous *storedRun) {
+	if previous == nil {
+		previous = lastRun
+	}
+	replayResults = nil
+	if previous != nil {
+		replayResults = append(replayResults, previous.Results...)
+	}
+	if tiers == nil {
+		loadTierState()
+	}
+
+	switch {
+	case tierFlag == "all" || len(targetedChecks) > 0:
+		deepDue = true
+	case tierFlag == tierFast:
+		deepDue = false
+	case viper.GetInt("tiers.deepInterval") > 0:
+		deepDue = time.Since(tiers.LastDeep) >= time.Duration(viper.GetInt("tiers.deepInterval"))*time.Second
+	default:
+		deepDue = tiers.Runs+1 >= viper.GetInt("tiers.deepEvery")
+	}
+
+	if deepDue {
+		log.Info("Running the fast and deep checks.")
+	} else {
+		log.Info("Running the fast checks, the deep checks report their previous results.")
+	}
+}
+
+// returns the previous result of a deep check which is not due, so it is
+// reported again instead of running the check
+func replayedResult(name string, category string) (error, bool) {
+	if deepDue || checkTier(name) != tierDeep {
+		return nil, false
+	}
+
+	for i, r := range replayResults {
+		if r.Name != name || r.Category != category || (r.Status != "OK" && r.Status != "FAILED") {
+			continue
+		}
+		replayResults = append(replayResults[:i], replayResults[i+1:]...)
+		if r.Status == "FAILED" && len(r.MessageID) > 0 {
+			return &probes.Message{ID: r.MessageID, Text: r.Summary}, true
+		}
+		if r.Status == "FAILED" {
+			return errors.New(r.Summary), true
+		}
+		return nil, true
+	}
+	return nil, false
+}
+
+// the key of a result the caller of eval already took with replayedResult,
+// eval reports it instead of taking the next result of the category
+type replayedKey struct{}
+
+type replayed struct {
+	err error
+}
+
+// returns ctx with the result taken by the caller of eval
+func withReplayed(ctx context.Context, err error) context.Context {
+	return context.WithValue(ctx, replayedKey{}, replayed{err})
+}
+
+// returns the result the caller of eval took or otherwise the next
+// previous result of the check, see replayedResult
+func takeReplayed(ctx context.Context, name string, category string) (error, bool) {
+	if r, ok := ctx.Value(replayedKey{}).(replayed); ok {
+		return r.err, true
+	}
+	return replayedResult(name, category)
+}
+
+// reports the previous findings of the diagnostics again, one result for
+// each of them
+func replayDiagnostics(ctx context.Context) {
+	for _, category := range replayCategories("diagnostics") {
+		// taken before eval maps the category, which may miss the replayed result
+		err, _ := replayedResult("diagnostics", category)
+		eval(withReplayed(ctx, err), category, "diagnostics", "", func(ctx context.Context) error { return err })
+	}
+}
+
+// returns the categories of the previous results of a deep check which
+// would be reported again
+func replayCategories(name string) []string {
+	var categories []string
+	if deepDue || checkTier(name) != tierDeep {
+		return categories
+	}
+	for _, r := range replayResults {
+		if r.Name == name && (r.Status == "OK" || r.Status == "FAILED") {
+			categories = append(categories, r.Category)
+		}
+	}
+	return categories
+}
+
+func tierFile() string {
+	if dir := viper.GetString("store.path"); len(dir) > 0 {
+		return filepath.Join(dir, "tiers.json")
+	}
+	return ""
+}
+
+func loadTierState() {
+	tiers = &tierState{}
+
+	file := tierFile()
+	if len(file) == 0 {
+		return
+	}
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Warning("Not able to read tier state", err)
+		}
+		return
+	}
+	if err := json.Unmarshal(content, tiers); err != nil {
+		log.Warning("Not able to parse tier state", err)
+	}
+}
+
+// counts the run and persists when the deep checks ran last
+func saveTierState() {
+	if tiers == nil || len(targetedChecks) > 0 {
+		return
+	}
+	if deepDue {
+		tiers.Runs = 0
+		tiers.LastDeep = time.Now()
+	} else {
+		tiers.Runs++
+	}
+
+	file := tierFile()
+	if len(file) == 0 {
+		return
+	}
+	content, err := json.Marshal(tiers)
+	if err != nil {
+		log.Error("Not able to serialize tier state", err)
+		return
+	}
+	if err := ioutil.WriteFile(file, content, 0640); err != nil {
+		log.Error("Not able to save tier state", err)
+	}
+}
@@ -0,0 +1,61 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/oscp/openshift-monitoring-cli/probes"
+	"github.com/spf13/viper"
+)
+
+// every previous finding of the diagnostics is reported again once
+func TestReplayDiagnostics(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+	// the diagnostics are skipped without oc
+	probes.SetCommandPaths(map[string]string{"oc": "true"})
+	defer probes.SetCommandPaths(nil)
+
+	deepDue = false
+	failedEvents = map[string]EventData{}
+	results = nil
+	replayResults = []checkResult{
+		{Name: "diagnostics", Category: "MAJOR", Status: "FAILED", Summary: "A"},
+		{Name: "diagnostics", Category: "MAJOR", Status: "FAILED", Summary: "B"},
+		{Name: "diagnostics", Category: "MINOR", Status: "FAILED", Summary: "C"},
+		{Name: "diagnostics", Category: "MINOR", Status: "FAILED", Summary: "D"},
+	}
+	defer func() { results, replayResults = nil, nil }()
+
+	replayDiagnostics(context.Background())
+
+	var reported []string
+	for _, r := range results {
+		if r.Status != "FAILED" {
+			t.Errorf("The replayed result %s %q is %s", r.Category, r.Summary, r.Status)
+		}
+		reported = append(reported, r.Category+" "+r.Summary)
+	}
+	expected := "MAJOR A, MAJOR B, MINOR C, MINOR D"
+	if strings.Join(reported, ", ") != expected {
+		t.Errorf("Expected %s to be reported, got %s", expected, strings.Join(reported, ", "))
+	}
+	if len(replayResults) > 0 {
+		t.Errorf("Not all findings were replayed, left %v", replayResults)
+	}
+}
@@ -0,0 +1,133 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// returns the context of a run, limited by --timeout or run.timeout (seconds)
+func runContext(parent context.Context) (context.Context, context.CancelFunc) {
+	timeout := runTimeout
+	if timeout == 0 {
+		timeout = time.Duration(viper.GetInt("run.timeout")) * time.Second
+	}
+
+	if timeout > 0 {
+		return context.WithTimeout(parent, timeout)
+	}
+	return context.WithCancel(parent)
+}
+
+// sleeps a random interval up to --splay or run.splay (seconds), so the
+// nodes started by the same cron minute don't query the masters at once
+func sleepSplay() {
+	max := splay
+	if max == 0 {
+		max = time.Duration(viper.GetInt("run.splay")) * time.Second
+	}
+	if max <= 0 {
+		return
+	}
+
+	rand.Seed(time.Now().UnixNano() + int64(os.Getpid()))
+	wait := time.Duration(rand.Int63n(int64(max)))
+	log.Info("Sleeping", wait, "before running the checks.")
+	time.Sleep(wait)
+}
+
+// returned for checks which were cancelled or not run because of a signal
+var errAborted = errors.New("Aborted by signal")
+
+// the signal which aborted the run, nil if there was none
+var abortSignal os.Signal
+
+// cancels the run on SIGINT or SIGTERM. The returned function stops
+// listening for the signals.
+func cancelOnSignal(cancel context.CancelFunc) func() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+
+	done := make(chan struct{})
+	go func() {
+		select {
+		case sig := <-signals:
+			log.Warning("Received", sig, "aborting the running checks.")
+			abortSignal = sig
+			cancel()
+		case <-done:
+		}
+	}()
+
+	return func() {
+		signal.Stop(signals)
+		close(done)
+	}
+}
+
+// returns the timeout of a check, configured in seconds as timeouts.<check>
+// or timeouts.default. Zero means the check is only limited by the run.
+func checkTimeout(name string) time.Duration {
+	name = strings.SplitN(name, ":", 2)[0]
+	if viper.IsSet("timeouts." + name) {
+		return time.Duration(viper.GetInt("timeouts."+name)) * time.Second
+	}
+	return time.Duration(viper.GetInt("timeouts.default")) * time.Second
+}
+
+// runs fn with the timeout of the check. Checks of openshift-monitoring-checks
+// don't know about the context, they are abandoned when it is done and their
+// result is ignored. Returns errAborted if the run was cancelled by a signal.
+func runWithTimeout(ctx context.Context, name string, fn func(ctx context.Context) error) error {
+	if timeout := checkTimeout(name); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	if ctx.Err() != nil {
+		return contextError(ctx, fmt.Errorf("Check %s was not run: %v", name, ctx.Err()))
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- fn(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		return contextError(ctx, err)
+	case <-ctx.Done():
+		return contextError(ctx, fmt.Errorf("Check %s did not finish: %v", name, ctx.Err()))
+	}
+}
+
+// replaces err with errAborted if ctx was cancelled, timeouts are kept
+func contextError(ctx context.Context, err error) error {
+	if err != nil && ctx.Err() == context.Canceled {
+		return errAborted
+	}
+	return err
+}
@@ -0,0 +1,66 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/oscp/openshift-monitoring-cli/probes"
+	"github.com/spf13/viper"
+)
+
+// the commands and http requests of a run together with its results. Checks
+// of openshift-monitoring-checks run their commands themselves and are not
+// part of the trace.
+type traceBundle struct {
+	Time     time.Time           `json:"time"`
+	Hostname string              `json:"hostname"`
+	NodeType string              `json:"nodeType"`
+	Args     []string            `json:"args"`
+	Results  []checkResult       `json:"results"`
+	Entries  []probes.TraceEntry `json:"entries"`
+	Events   []EventData         `json:"events"`
+}
+
+// writes the trace bundle to the --trace file
+func saveTrace(start time.Time) {
+	if len(traceFile) == 0 {
+		return
+	}
+
+	var bundle = traceBundle{
+		Time:     start,
+		Hostname: nodeName(),
+		NodeType: viper.GetString("node.type"),
+		Args:     os.Args,
+		Results:  results,
+		Entries:  probes.TraceEntries(),
+		Events:   data.Events,
+	}
+
+	content, err := json.MarshalIndent(bundle, "", "  ")
+	if err != nil {
+		log.Error("Not able to serialize trace", err)
+		return
+	}
+	if err := ioutil.WriteFile(traceFile, content, 0600); err != nil {
+		log.Error("Not able to write trace", traceFile, err)
+		return
+	}
+	log.Info("Wrote trace of", len(bundle.Entries), "commands and requests to", traceFile)
+}
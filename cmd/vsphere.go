@@ -0,0 +1,48 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"strings"
+
+	"github.com/oscp/openshift-monitoring-cli/probes"
+	"github.com/spf13/viper"
+)
+
+// runs the datastore capacity check of vSphere, the datastores are read
+// once for both levels
+func evalVsphereDatastores(ctx context.Context) {
+	evalLevels(ctx, levelCheck{
+		name:      "datastore-capacity",
+		threshold: thresholdDisplay("datastore-capacity", "%"),
+		measure: func(ctx context.Context) (interface{}, error) {
+			var names []string
+			if len(viper.GetString("vsphereDatastores.datastores")) > 0 {
+				names = strings.Split(viper.GetString("vsphereDatastores.datastores"), ",")
+			}
+			return probes.VsphereDatastores(ctx, viper.GetString("vsphereDatastores.url"),
+				viper.GetString("vsphereDatastores.username"), viper.GetString("vsphereDatastores.password"),
+				names, viper.GetBool("vsphereDatastores.insecure"))
+		},
+		evaluate: func(ctx context.Context, datastores interface{}, category string) error {
+			d, ok := datastores.([]probes.Datastore)
+			if !ok {
+				return unexpectedMeasurement("datastore-capacity", datastores)
+			}
+			return probes.CheckDatastoreCapacity(d, threshold("datastore-capacity", category))
+		},
+	})
+}
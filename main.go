@@ -16,8 +16,11 @@ package main
 
 import (
 	"github.com/oscp/openshift-monitoring-cli/cmd"
+	"github.com/oscp/openshift-monitoring-cli/probes"
 )
 
 func main() {
+	// the host commands of the containerized mode are links to this executable
+	probes.RunHostCommand()
 	cmd.Execute()
 }
@@ -0,0 +1,296 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package probes
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+const serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+// APIClient calls the master api with the credentials of a kubeconfig or,
+// without kubeconfig, of the service account of the pod. The credentials are
+// read again after an authentication failure, so long running processes pick
+// up rotated tokens and certificates.
+type APIClient struct {
+	kubeconfig string
+
+	lock   sync.Mutex
+	server string
+	token  string
+	client *http.Client
+}
+
+// NewAPIClient returns a client for the kubeconfig or, if it is empty, for
+// the service account of the pod.
+func NewAPIClient(kubeconfig string) (*APIClient, error) {
+	c := &APIClient{kubeconfig: kubeconfig}
+	if err := c.load(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// the client used by the checks instead of oc, nil if oc is used
+var apiClient *APIClient
+
+// UseAPIClient makes the checks call the api with c instead of running oc.
+func UseAPIClient(c *APIClient) {
+	apiClient = c
+}
+
+// Get requests path and decodes the json response into v.
+func (c *APIClient) Get(ctx context.Context, path string, v interface{}) error {
+	return c.Do(ctx, "GET", path, "", nil, v)
+}
+
+// Do sends body as json with the content type, e.g. for the different
+// patch types, and decodes the json response into v if it is not nil.
+// Requests which are rejected with 401 are retried once with freshly read
+// credentials.
+func (c *APIClient) Do(ctx context.Context, method string, path string, contentType string, body interface{}, v interface{}) error {
+	var content []byte
+	if body != nil {
+		var err error
+		if content, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+
+	resp, err := c.do(ctx, method, path, contentType, content)
+	if err == nil && resp.StatusCode == http.StatusUnauthorized {
+		resp.Body.Close()
+		log.Info("Api rejected the credentials, reading them again")
+		if err := c.load(); err != nil {
+			return err
+		}
+		resp, err = c.do(ctx, method, path, contentType, content)
+	}
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s %s returned status code %d", method, path, resp.StatusCode)
+	}
+	if v == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+func (c *APIClient) do(ctx context.Context, method string, path string, contentType string, content []byte) (*http.Response, error) {
+	c.lock.Lock()
+	server, token, client := c.server, c.token, c.client
+	c.lock.Unlock()
+
+	var body io.Reader
+	if content != nil {
+		body = bytes.NewReader(content)
+	}
+
+	req, err := http.NewRequest(method, server+path, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if len(contentType) > 0 {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if len(token) > 0 {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return client.Do(req.WithContext(ctx))
+}
+
+// reads the server, token and certificates from the kubeconfig or the
+// service account
+func (c *APIClient) load() error {
+	var server, token string
+	var tlsConfig *tls.Config
+	var err error
+
+	if len(c.kubeconfig) > 0 {
+		server, token, tlsConfig, err = loadKubeconfigCredentials(c.kubeconfig)
+	} else {
+		server, token, tlsConfig, err = loadServiceAccountCredentials()
+	}
+	if err != nil {
+		return err
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.server = strings.TrimSuffix(server, "/")
+	c.token = token
+	c.client = &http.Client{Timeout: 30 * time.Second, Transport: TraceTransport(&http.Transport{TLSClientConfig: tlsConfig})}
+	return nil
+}
+
+func loadServiceAccountCredentials() (string, string, *tls.Config, error) {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if len(host) == 0 || len(port) == 0 {
+		return "", "", nil, errors.New("Not running in a pod, KUBERNETES_SERVICE_HOST is not set")
+	}
+
+	token, err := ioutil.ReadFile(serviceAccountDir + "/token")
+	if err != nil {
+		return "", "", nil, fmt.Errorf("Could not read service account token: %v", err)
+	}
+
+	ca, err := ioutil.ReadFile(serviceAccountDir + "/ca.crt")
+	if err != nil {
+		return "", "", nil, fmt.Errorf("Could not read service account ca: %v", err)
+	}
+	pool := x509.NewCertPool()
+	pool.AppendCertsFromPEM(ca)
+
+	return "https://" + net.JoinHostPort(host, port), strings.TrimSpace(string(token)), &tls.Config{RootCAs: pool}, nil
+}
+
+type apiKubeconfig struct {
+	CurrentContext string `yaml:"current-context"`
+	Contexts       []struct {
+		Name    string `yaml:"name"`
+		Context struct {
+			Cluster string `yaml:"cluster"`
+			User    string `yaml:"user"`
+		} `yaml:"context"`
+	} `yaml:"contexts"`
+	Clusters []struct {
+		Name    string `yaml:"name"`
+		Cluster struct {
+			Server                   string `yaml:"server"`
+			CertificateAuthority     string `yaml:"certificate-authority"`
+			CertificateAuthorityData string `yaml:"certificate-authority-data"`
+			InsecureSkipTLSVerify    bool   `yaml:"insecure-skip-tls-verify"`
+		} `yaml:"cluster"`
+	} `yaml:"clusters"`
+	Users []struct {
+		Name string `yaml:"name"`
+		User struct {
+			Token                 string `yaml:"token"`
+			TokenFile             string `yaml:"tokenFile"`
+			ClientCertificate     string `yaml:"client-certificate"`
+			ClientCertificateData string `yaml:"client-certificate-data"`
+			ClientKey             string `yaml:"client-key"`
+			ClientKeyData         string `yaml:"client-key-data"`
+		} `yaml:"user"`
+	} `yaml:"users"`
+}
+
+// reads the credentials of the current context of a kubeconfig
+func loadKubeconfigCredentials(path string) (string, string, *tls.Config, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", "", nil, fmt.Errorf("Could not read kubeconfig %s: %v", path, err)
+	}
+
+	var config apiKubeconfig
+	if err := yaml.Unmarshal(content, &config); err != nil {
+		return "", "", nil, fmt.Errorf("Could not parse kubeconfig %s: %v", path, err)
+	}
+
+	var clusterName, userName string
+	for _, ctx := range config.Contexts {
+		if ctx.Name == config.CurrentContext {
+			clusterName, userName = ctx.Context.Cluster, ctx.Context.User
+		}
+	}
+
+	var tlsConfig = &tls.Config{}
+	var server, token string
+
+	for _, cluster := range config.Clusters {
+		if cluster.Name != clusterName {
+			continue
+		}
+		server = cluster.Cluster.Server
+		tlsConfig.InsecureSkipVerify = cluster.Cluster.InsecureSkipTLSVerify
+
+		ca, err := kubeconfigData(cluster.Cluster.CertificateAuthorityData, cluster.Cluster.CertificateAuthority)
+		if err != nil {
+			return "", "", nil, err
+		}
+		if len(ca) > 0 {
+			tlsConfig.RootCAs = x509.NewCertPool()
+			tlsConfig.RootCAs.AppendCertsFromPEM(ca)
+		}
+	}
+	if len(server) == 0 {
+		return "", "", nil, fmt.Errorf("Kubeconfig %s has no cluster for the current context", path)
+	}
+
+	for _, user := range config.Users {
+		if user.Name != userName {
+			continue
+		}
+		token = user.User.Token
+		if len(user.User.TokenFile) > 0 {
+			content, err := ioutil.ReadFile(user.User.TokenFile)
+			if err != nil {
+				return "", "", nil, fmt.Errorf("Could not read token file %s: %v", user.User.TokenFile, err)
+			}
+			token = strings.TrimSpace(string(content))
+		}
+
+		cert, err := kubeconfigData(user.User.ClientCertificateData, user.User.ClientCertificate)
+		if err != nil {
+			return "", "", nil, err
+		}
+		key, err := kubeconfigData(user.User.ClientKeyData, user.User.ClientKey)
+		if err != nil {
+			return "", "", nil, err
+		}
+		if len(cert) > 0 && len(key) > 0 {
+			pair, err := tls.X509KeyPair(cert, key)
+			if err != nil {
+				return "", "", nil, fmt.Errorf("Could not load client certificate of %s: %v", path, err)
+			}
+			tlsConfig.Certificates = []tls.Certificate{pair}
+		}
+	}
+
+	return server, token, tlsConfig, nil
+}
+
+// returns the base64 encoded data or, if empty, the content of the file
+func kubeconfigData(data string, file string) ([]byte, error) {
+	if len(data) > 0 {
+		return base64.StdEncoding.DecodeString(data)
+	}
+	if len(file) > 0 {
+		return ioutil.ReadFile(file)
+	}
+	return nil, nil
+}
@@ -0,0 +1,233 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package probes
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Backup is the latest backup found at a location.
+type Backup struct {
+	Location string
+	Time     time.Time
+	// bytes of the file or of all files in the directory
+	Size int64
+}
+
+// LatestBackup returns the newest file or directory matching the glob
+// pattern, e.g. /backup/etcd/etcd-backup-*.
+func LatestBackup(pattern string) (Backup, error) {
+	matches, err := filepath.Glob(HostPath(pattern))
+	if err != nil {
+		return Backup{}, fmt.Errorf("Invalid backup path %s: %v", pattern, err)
+	}
+
+	var latest Backup
+	for _, match := range matches {
+		info, err := os.Stat(match)
+		if err != nil {
+			continue
+		}
+		if info.ModTime().After(latest.Time) {
+			latest = Backup{Location: match, Time: info.ModTime(), Size: info.Size()}
+			if info.IsDir() {
+				latest.Size = directorySize(match)
+			}
+		}
+	}
+
+	if len(latest.Location) == 0 {
+		return latest, newMessage("backup-not-found", pattern)
+	}
+	return latest, nil
+}
+
+func directorySize(dir string) int64 {
+	var size int64
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
+
+// RemoteBackup returns the backup at the url of an object store from the
+// Last-Modified and Content-Length of a HEAD request. The url must point to
+// the latest backup, like a pre-signed url or an object which is
+// overwritten by every backup. The token is sent as bearer token if set.
+func RemoteBackup(ctx context.Context, url string, token string) (Backup, error) {
+	req, err := http.NewRequest("HEAD", url, nil)
+	if err != nil {
+		return Backup{}, err
+	}
+	if len(token) > 0 {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := sharedClient(false).Do(req.WithContext(ctx))
+	if err != nil {
+		return Backup{}, fmt.Errorf("Could not get backup %s: %v", url, err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return Backup{}, fmt.Errorf("Backup %s returned status code %d", url, resp.StatusCode)
+	}
+
+	modified, err := http.ParseTime(resp.Header.Get("Last-Modified"))
+	if err != nil {
+		return Backup{}, errors.New("Backup " + url + " has no valid Last-Modified header")
+	}
+	return Backup{Location: url, Time: modified, Size: resp.ContentLength}, nil
+}
+
+// CheckBackup fails if the backup is older than maxAge or smaller than
+// minSize bytes.
+func CheckBackup(name string, backup Backup, maxAge time.Duration, minSize int64) error {
+	if age := time.Since(backup.Time); age > maxAge {
+		return newMessage("backup-too-old", name, backup.Location, int(age.Hours()), int(maxAge.Hours()))
+	}
+	if backup.Size >= 0 && backup.Size < minSize {
+		return newMessage("backup-too-small", name, backup.Location, backup.Size, minSize)
+	}
+	return nil
+}
+
+// matches the kind of an object in yaml or json exports
+var kindField = regexp.MustCompile(`(?:^|[\s{,])"?kind"?\s*:\s*"?([A-Z]\w*)`)
+
+// CountBackupObjects counts the objects by kind in the yaml and json files
+// of a backup, which can be a file, a directory or a tar.gz archive. If
+// location is an url, the backup is downloaded with the token. Every kind
+// field is counted, lists are not.
+func CountBackupObjects(ctx context.Context, location string, token string) (map[string]int, error) {
+	counts := map[string]int{}
+
+	if strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://") {
+		req, err := http.NewRequest("GET", location, nil)
+		if err != nil {
+			return nil, err
+		}
+		if len(token) > 0 {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := (&http.Client{Transport: TraceTransport(nil)}).Do(req.WithContext(ctx))
+		if err != nil {
+			return nil, fmt.Errorf("Could not download backup %s: %v", location, err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("Backup %s returned status code %d", location, resp.StatusCode)
+		}
+		return counts, countObjects(resp.Body, location, counts)
+	}
+
+	err := filepath.Walk(location, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		return countObjects(f, path, counts)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Could not read backup %s: %v", location, err)
+	}
+	return counts, nil
+}
+
+func countObjects(r io.Reader, name string, counts map[string]int) error {
+	switch {
+	case strings.HasSuffix(name, ".tar.gz") || strings.HasSuffix(name, ".tgz"):
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return err
+		}
+		archive := tar.NewReader(gz)
+		for {
+			header, err := archive.Next()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if header.Typeflag == tar.TypeReg {
+				if err := countObjects(archive, header.Name, counts); err != nil {
+					return err
+				}
+			}
+		}
+	case strings.HasSuffix(name, ".gz"):
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return err
+		}
+		return countObjects(gz, strings.TrimSuffix(name, ".gz"), counts)
+	case strings.HasSuffix(name, ".yaml") || strings.HasSuffix(name, ".yml") || strings.HasSuffix(name, ".json"):
+		scanner := bufio.NewScanner(r)
+		// secrets contain long base64 lines
+		scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+		for scanner.Scan() {
+			for _, m := range kindField.FindAllStringSubmatch(scanner.Text(), -1) {
+				if !strings.HasSuffix(m[1], "List") {
+					counts[m[1]]++
+				}
+			}
+		}
+		return scanner.Err()
+	}
+	return nil
+}
+
+// CheckBackupObjects fails if the backup contains less than minObjects
+// objects or no object of one of the kinds.
+func CheckBackupObjects(name string, location string, counts map[string]int, minObjects int, kinds []string) error {
+	total := 0
+	for _, count := range counts {
+		total += count
+	}
+	if total < minObjects {
+		return newMessage("backup-too-few-objects", name, location, total, minObjects)
+	}
+
+	var missing []string
+	for _, kind := range kinds {
+		if len(kind) > 0 && counts[kind] == 0 {
+			missing = append(missing, kind)
+		}
+	}
+	if len(missing) > 0 {
+		return newMessage("backup-kinds-missing", name, location, strings.Join(missing, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,243 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package probes
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Baseline is a snapshot of configuration facts of a host, grouped by kind
+// (packages, sysctls, mounts and certificates).
+type Baseline struct {
+	Time  time.Time                    `json:"time"`
+	Facts map[string]map[string]string `json:"facts"`
+}
+
+// BaselineOptions selects the facts of a baseline.
+type BaselineOptions struct {
+	// sysctl keys like vm.max_map_count
+	Sysctls []string
+	// glob patterns of pem certificates
+	Certificates []string
+}
+
+// the file systems whose mounts are part of the baseline, virtual file
+// systems and the volumes of pods change all the time
+var baselineFsTypes = []string{"xfs", "ext3", "ext4", "btrfs", "nfs", "nfs4", "fuse.glusterfs"}
+
+// CollectBaseline collects the facts of the local host.
+func CollectBaseline(ctx context.Context, options BaselineOptions) (Baseline, error) {
+	var baseline = Baseline{Time: time.Now().UTC(), Facts: map[string]map[string]string{}}
+
+	packages, err := installedPackages(ctx)
+	if err != nil {
+		return baseline, err
+	}
+	baseline.Facts["packages"] = packages
+	baseline.Facts["sysctls"] = sysctls(options.Sysctls)
+	if baseline.Facts["mounts"], err = mounts(); err != nil {
+		return baseline, err
+	}
+	baseline.Facts["certificates"] = certificateFingerprints(options.Certificates)
+
+	return baseline, nil
+}
+
+// LoadBaseline reads a baseline written by SaveBaseline.
+func LoadBaseline(path string) (Baseline, error) {
+	var baseline Baseline
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return baseline, err
+	}
+	err = json.Unmarshal(content, &baseline)
+	return baseline, err
+}
+
+// SaveBaseline writes the baseline as json.
+func SaveBaseline(path string, baseline Baseline) error {
+	content, err := json.MarshalIndent(baseline, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, content, 0640)
+}
+
+// BaselineDrift returns the differences of current to the baseline, like
+// "sysctls vm.swappiness: 0 -> 60" or "packages docker: added 1.13.1".
+func BaselineDrift(baseline Baseline, current Baseline) []string {
+	var drift []string
+
+	var kinds []string
+	for kind := range baseline.Facts {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+
+	for _, kind := range kinds {
+		before, after := baseline.Facts[kind], current.Facts[kind]
+		for _, key := range sortedKeys(before, after) {
+			old, hadOld := before[key]
+			value, hasValue := after[key]
+			switch {
+			case !hadOld:
+				drift = append(drift, fmt.Sprintf("%s %s: added %s", kind, key, value))
+			case !hasValue:
+				drift = append(drift, fmt.Sprintf("%s %s: removed %s", kind, key, old))
+			case old != value:
+				drift = append(drift, fmt.Sprintf("%s %s: %s -> %s", kind, key, old, value))
+			}
+		}
+	}
+	return drift
+}
+
+// CheckBaselineDrift fails if the facts of the host differ from the baseline
+// saved at path.
+func CheckBaselineDrift(ctx context.Context, path string, options BaselineOptions) error {
+	log.Info("Checking drift from baseline", path)
+
+	baseline, err := LoadBaseline(path)
+	if err != nil {
+		return fmt.Errorf("Could not read baseline %s: %v", path, err)
+	}
+	current, err := CollectBaseline(ctx, options)
+	if err != nil {
+		return fmt.Errorf("Could not collect facts for the baseline: %v", err)
+	}
+
+	if drift := BaselineDrift(baseline, current); len(drift) > 0 {
+		return newMessage("baseline-drifted", baseline.Time.Format("2006-01-02"), strings.Join(drift, ", "))
+	}
+	return nil
+}
+
+func sortedKeys(maps ...map[string]string) []string {
+	seen := map[string]bool{}
+	var keys []string
+	for _, m := range maps {
+		for key := range m {
+			if !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func installedPackages(ctx context.Context) (map[string]string, error) {
+	cmd := command(ctx, "rpm", "-qa", "--queryformat", "%{NAME} %{VERSION}-%{RELEASE}\n")
+	start := time.Now()
+	out, err := cmd.Output()
+	TraceCommand(cmd, out, nil, err, time.Since(start))
+	if err != nil {
+		return nil, fmt.Errorf("rpm -qa failed: %v", err)
+	}
+
+	var packages = map[string]string{}
+	for _, line := range strings.Split(string(out), "\n") {
+		if fields := strings.Fields(line); len(fields) == 2 {
+			packages[fields[0]] = fields[1]
+		}
+	}
+	return packages, nil
+}
+
+func sysctls(keys []string) map[string]string {
+	var values = map[string]string{}
+	for _, key := range keys {
+		content, err := ioutil.ReadFile(HostPath("/proc/sys/" + strings.Replace(key, ".", "/", -1)))
+		if err != nil {
+			continue
+		}
+		values[key] = strings.Join(strings.Fields(string(content)), " ")
+	}
+	return values
+}
+
+func mounts() (map[string]string, error) {
+	table, err := mountTable()
+	if err != nil {
+		return nil, err
+	}
+
+	var mounts = map[string]string{}
+	for _, fields := range table {
+		if contains(baselineFsTypes, fields[2]) {
+			mounts[fields[1]] = fields[0] + " " + fields[2]
+		}
+	}
+	return mounts, nil
+}
+
+// returns the fields of the mounts of the host: device, mount point, type,
+// options, ...
+func mountTable() ([][]string, error) {
+	// /proc/mounts are the mounts of the container if the host root is set,
+	// the host pid namespace shows the ones of init instead
+	mountsFile := "/proc/mounts"
+	if hostRoot != "/" {
+		mountsFile = "/proc/1/mounts"
+	}
+	file, err := os.Open(mountsFile)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var table [][]string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if fields := strings.Fields(scanner.Text()); len(fields) >= 4 {
+			table = append(table, fields)
+		}
+	}
+	return table, scanner.Err()
+}
+
+func certificateFingerprints(patterns []string) map[string]string {
+	var fingerprints = map[string]string{}
+	for _, pattern := range patterns {
+		files, _ := filepath.Glob(HostPath(pattern))
+		for _, file := range files {
+			content, err := ioutil.ReadFile(file)
+			if err != nil {
+				continue
+			}
+			// keyed by the path on the host, so baselines of containerized
+			// and direct runs are comparable
+			if hostRoot != "/" {
+				file = strings.TrimPrefix(file, hostRoot)
+			}
+			if block, _ := pem.Decode(content); block != nil && block.Type == "CERTIFICATE" {
+				fingerprints[file] = fmt.Sprintf("%x", sha256.Sum256(block.Bytes))
+			}
+		}
+	}
+	return fingerprints
+}
@@ -0,0 +1,152 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package probes
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const canaryTemplate = `{
+  "kind": "List",
+  "apiVersion": "v1",
+  "items": [
+    {
+      "kind": "Pod",
+      "apiVersion": "v1",
+      "metadata": {"name": "%[1]s", "labels": {"app": "%[1]s"}},
+      "spec": {
+        "restartPolicy": "Never",
+        "containers": [{
+          "name": "canary",
+          "image": "%[2]s",
+          "ports": [{"containerPort": 8080}],
+          "readinessProbe": {"httpGet": {"path": "/", "port": 8080}}
+        }]
+      }
+    },
+    {
+      "kind": "Service",
+      "apiVersion": "v1",
+      "metadata": {"name": "%[1]s", "labels": {"app": "%[1]s"}},
+      "spec": {"selector": {"app": "%[1]s"}, "ports": [{"port": 8080}]}
+    },
+    {
+      "kind": "Route",
+      "apiVersion": "v1",
+      "metadata": {"name": "%[1]s", "labels": {"app": "%[1]s"}},
+      "spec": {"to": {"kind": "Service", "name": "%[1]s"}}
+    }
+  ]
+}`
+
+// how long the cleanup of the canary objects may take
+const canaryCleanupTimeout = 30 * time.Second
+
+// CheckCanaryApp deploys a pod, service and route into the given namespace,
+// waits for the pod to become ready, calls the route through every router
+// and removes all created objects again. The whole probe must finish within timeout.
+func CheckCanaryApp(ctx context.Context, namespace string, image string, routerIps []string, timeout time.Duration) error {
+	log.Info("Checking canary app deployment in namespace", namespace)
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	if _, err := runOc(ctx, "", "get", "project", namespace); err != nil {
+		return fmt.Errorf("Canary namespace %s is not accessible: %v", namespace, err)
+	}
+
+	name := fmt.Sprintf("canary-%d", time.Now().Unix())
+	defer cleanupCanaryApp(namespace, name)
+
+	if _, err := runOc(ctx, fmt.Sprintf(canaryTemplate, name, image), "create", "-n", namespace, "-f", "-"); err != nil {
+		return fmt.Errorf("Could not create canary app: %v", err)
+	}
+
+	if err := waitForCanaryPod(ctx, namespace, name); err != nil {
+		return err
+	}
+
+	host, err := runOc(ctx, "", "get", "route", name, "-n", namespace, "-o", "jsonpath={.spec.host}")
+	if err != nil || len(host) == 0 {
+		return fmt.Errorf("Could not get host of canary route: %v", err)
+	}
+
+	for _, ip := range routerIps {
+		if err := waitForCanaryRoute(ctx, ip, host); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func waitForCanaryPod(ctx context.Context, namespace string, name string) error {
+	for {
+		ready, _ := runOc(ctx, "", "get", "pod", name, "-n", namespace,
+			"-o", `jsonpath={.status.conditions[?(@.type=="Ready")].status}`)
+		if ready == "True" {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return newMessage("canary-pod-not-ready", name)
+		case <-time.After(2 * time.Second):
+		}
+	}
+}
+
+var canaryClient = &http.Client{Transport: TraceTransport(nil)}
+
+func waitForCanaryRoute(ctx context.Context, routerIp string, host string) error {
+	req, err := http.NewRequest("GET", "http://"+routerIp+"/", nil)
+	if err != nil {
+		return err
+	}
+	req.Host = host
+	req = req.WithContext(ctx)
+
+	var lastErr error
+	for {
+		resp, err := canaryClient.Do(req)
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode == http.StatusOK {
+				return nil
+			}
+			err = fmt.Errorf("status code %d", resp.StatusCode)
+		}
+		lastErr = err
+
+		select {
+		case <-ctx.Done():
+			return newMessage("canary-route-unreachable", host, routerIp, lastErr)
+		case <-time.After(2 * time.Second):
+		}
+	}
+}
+
+// the cleanup gets its own context, so it also runs if the probe was cancelled
+func cleanupCanaryApp(namespace string, name string) {
+	ctx, cancel := context.WithTimeout(context.Background(), canaryCleanupTimeout)
+	defer cancel()
+
+	if _, err := runOc(ctx, "", "delete", "pod,service,route", "-l", "app="+name, "-n", namespace); err != nil {
+		log.Warning("Could not remove canary app", name, err)
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package probes
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// CassandraStats are the pending compactions and the heap usage of a
+// cassandra pod as reported by nodetool.
+type CassandraStats struct {
+	Pod                string
+	PendingCompactions int
+	HeapUsedMB         float64
+	HeapMaxMB          float64
+}
+
+var (
+	pendingTasksRegexp = regexp.MustCompile(`(?m)^pending tasks:\s*(\d+)`)
+	heapMemoryRegexp   = regexp.MustCompile(`(?m)^Heap Memory \(MB\)\s*:\s*([\d.]+)\s*/\s*([\d.]+)`)
+)
+
+// ReadCassandraStats runs nodetool in every running pod of the component.
+func ReadCassandraStats(ctx context.Context, c Component) ([]CassandraStats, error) {
+	log.Info("Reading compactions and heap of", c.Name)
+
+	pods, err := c.Pods(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var stats []CassandraStats
+	for _, pod := range pods.Items {
+		if pod.Status.Phase != "Running" {
+			continue
+		}
+
+		s := CassandraStats{Pod: pod.Metadata.Name}
+		out, err := runOc(ctx, "", "exec", pod.Metadata.Name, "-n", c.Namespace, "--", "nodetool", "compactionstats")
+		if err != nil {
+			return nil, fmt.Errorf("Could not get the compactions of %s: %v", pod.Metadata.Name, err)
+		}
+		if m := pendingTasksRegexp.FindStringSubmatch(out); m != nil {
+			s.PendingCompactions, _ = strconv.Atoi(m[1])
+		}
+
+		out, err = runOc(ctx, "", "exec", pod.Metadata.Name, "-n", c.Namespace, "--", "nodetool", "info")
+		if err != nil {
+			return nil, fmt.Errorf("Could not get the heap of %s: %v", pod.Metadata.Name, err)
+		}
+		m := heapMemoryRegexp.FindStringSubmatch(out)
+		if m == nil {
+			return nil, fmt.Errorf("Could not parse the heap of %s: %q", pod.Metadata.Name, out)
+		}
+		s.HeapUsedMB, _ = strconv.ParseFloat(m[1], 64)
+		s.HeapMaxMB, _ = strconv.ParseFloat(m[2], 64)
+
+		stats = append(stats, s)
+	}
+	return stats, nil
+}
+
+// CheckCassandraCompactions fails if a pod has maxPending or more pending
+// compactions.
+func CheckCassandraCompactions(stats []CassandraStats, maxPending int) error {
+	var behind []string
+	for _, s := range stats {
+		if s.PendingCompactions >= maxPending {
+			behind = append(behind, fmt.Sprintf("%s %d", s.Pod, s.PendingCompactions))
+		}
+	}
+	if len(behind) > 0 {
+		return newMessage("cassandra-compactions-pending", maxPending, strings.Join(behind, ", "))
+	}
+	return nil
+}
+
+// CheckCassandraHeap fails if a pod uses percent or more of its heap.
+func CheckCassandraHeap(stats []CassandraStats, percent int) error {
+	var full []string
+	for _, s := range stats {
+		if s.HeapMaxMB == 0 {
+			continue
+		}
+		if used := int(s.HeapUsedMB * 100 / s.HeapMaxMB); used >= percent {
+			full = append(full, fmt.Sprintf("%s %d%% of %.0fMB", s.Pod, used, s.HeapMaxMB))
+		}
+	}
+	if len(full) > 0 {
+		return newMessage("cassandra-heap-exhausted", percent, strings.Join(full, ", "))
+	}
+	return nil
+}
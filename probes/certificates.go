@@ -0,0 +1,80 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package probes
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Expiry is the end of the validity of a certificate or token.
+type Expiry struct {
+	Name     string
+	NotAfter time.Time
+}
+
+// returns the expiries ending within days as "<name> (<date>)"
+func expiringWithin(expiries []Expiry, days int) []string {
+	limit := time.Now().AddDate(0, 0, days)
+
+	var expiring []string
+	for _, expiry := range expiries {
+		if expiry.NotAfter.Before(limit) {
+			expiring = append(expiring, fmt.Sprintf("%s (%s)", expiry.Name, expiry.NotAfter.Format("2006-01-02")))
+		}
+	}
+	return expiring
+}
+
+// CertificateExpiries reads the expiry of the pem certificates matching the
+// glob patterns.
+func CertificateExpiries(patterns []string) ([]Expiry, error) {
+	log.Info("Reading expiry of certificates", strings.Join(patterns, ", "))
+
+	var expiries []Expiry
+	for _, pattern := range patterns {
+		files, _ := filepath.Glob(HostPath(pattern))
+		for _, file := range files {
+			content, err := ioutil.ReadFile(file)
+			if err != nil {
+				return nil, fmt.Errorf("Could not read certificate %s: %v", file, err)
+			}
+			block, _ := pem.Decode(content)
+			if block == nil || block.Type != "CERTIFICATE" {
+				continue
+			}
+			cert, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("Could not parse certificate %s: %v", file, err)
+			}
+			expiries = append(expiries, Expiry{file, cert.NotAfter})
+		}
+	}
+	return expiries, nil
+}
+
+// CheckCertificateExpiry fails if any of the certificates expires within
+// days.
+func CheckCertificateExpiry(expiries []Expiry, days int) error {
+	if expiring := expiringWithin(expiries, days); len(expiring) > 0 {
+		return newMessage("certificates-expiring", days, strings.Join(expiring, ", "))
+	}
+	return nil
+}
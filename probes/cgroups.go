@@ -0,0 +1,123 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package probes
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// MemoryCgroups is the number of memory cgroups of the host. A removed
+// cgroup is dying until the kernel memory charged to it, mostly slab and
+// page cache, is freed. The kernel of RHEL 7 frees it late or never, which
+// slows down the reclaim and the creation of containers over weeks.
+type MemoryCgroups struct {
+	Live  int
+	Dying int
+}
+
+// ReadMemoryCgroups counts the memory cgroups. The dying cgroups are read
+// from cgroup.stat on newer kernels, else they are those counted in
+// /proc/cgroups without a directory in the hierarchy.
+func ReadMemoryCgroups() (MemoryCgroups, error) {
+	var c MemoryCgroups
+
+	root := HostPath("/sys/fs/cgroup/memory")
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			// a cgroup removed while walking
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if info.IsDir() {
+			c.Live++
+		}
+		return nil
+	})
+	if err != nil {
+		return c, fmt.Errorf("Could not read the memory cgroups: %v", err)
+	}
+
+	if content, err := ioutil.ReadFile(filepath.Join(root, "cgroup.stat")); err == nil {
+		for _, line := range strings.Split(string(content), "\n") {
+			fields := strings.Fields(line)
+			if len(fields) == 2 && fields[0] == "nr_dying_descendants" {
+				c.Dying, err = strconv.Atoi(fields[1])
+				return c, err
+			}
+		}
+	}
+
+	file, err := os.Open(HostPath("/proc/cgroups"))
+	if err != nil {
+		return c, fmt.Errorf("Could not read /proc/cgroups: %v", err)
+	}
+	defer file.Close()
+	// subsys_name hierarchy num_cgroups enabled
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 4 && fields[0] == "memory" {
+			total, err := strconv.Atoi(fields[2])
+			if err != nil {
+				return c, fmt.Errorf("Could not parse the memory cgroups of /proc/cgroups: %v", err)
+			}
+			if total > c.Live {
+				c.Dying = total - c.Live
+			}
+			return c, nil
+		}
+	}
+	return c, fmt.Errorf("No memory controller in /proc/cgroups")
+}
+
+// CheckDyingCgroups fails if max or more memory cgroups are dying.
+func CheckDyingCgroups(c MemoryCgroups, max int) error {
+	if c.Dying >= max {
+		return newMessage("memory-cgroups-dying", c.Dying, c.Live, max)
+	}
+	return nil
+}
+
+// SlabUnreclaimable returns the unreclaimable slab memory of the kernel in
+// megabytes, it grows with the leaked kernel memory of dying cgroups.
+func SlabUnreclaimable() (float64, error) {
+	file, err := os.Open(HostPath("/proc/meminfo"))
+	if err != nil {
+		return 0, fmt.Errorf("Could not read /proc/meminfo: %v", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		// SUnreclaim:       123456 kB
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 3 && fields[0] == "SUnreclaim:" {
+			kb, err := strconv.ParseFloat(fields[1], 64)
+			if err != nil {
+				return 0, fmt.Errorf("Could not parse SUnreclaim of /proc/meminfo: %v", err)
+			}
+			return kb / 1024, nil
+		}
+	}
+	return 0, fmt.Errorf("No SUnreclaim in /proc/meminfo")
+}
@@ -0,0 +1,145 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package probes
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// the clients shared by the checks, each is created on first use and keeps
+// its connections alive, so the checks of a run don't connect again to the
+// same endpoints
+var clients struct {
+	lock sync.Mutex
+	// by whether the certificate verification is skipped
+	http map[bool]*http.Client
+	etcd *http.Client
+	// the transport of etcd, its idle connections are closed on reset
+	etcdTransport *http.Transport
+	// the responses of getResource by api path, only kept for a run
+	resources map[string]json.RawMessage
+}
+
+// ResetClients forgets the api responses and the etcd client of the
+// previous run, so a run sees the current objects and rotated etcd
+// certificates. The http connections are kept, only the ones of the etcd
+// client are closed.
+func ResetClients() {
+	clients.lock.Lock()
+	defer clients.lock.Unlock()
+
+	if clients.etcdTransport != nil {
+		clients.etcdTransport.CloseIdleConnections()
+	}
+	clients.etcd = nil
+	clients.etcdTransport = nil
+	clients.resources = nil
+}
+
+// returns the shared http client, skipping the verification of the server
+// certificate if insecure
+func sharedClient(insecure bool) *http.Client {
+	clients.lock.Lock()
+	defer clients.lock.Unlock()
+
+	if clients.http == nil {
+		clients.http = map[bool]*http.Client{}
+	}
+	if client, ok := clients.http[insecure]; ok {
+		return client
+	}
+
+	transport := &http.Transport{
+		Proxy:               http.ProxyFromEnvironment,
+		TLSClientConfig:     &tls.Config{InsecureSkipVerify: insecure},
+		MaxIdleConnsPerHost: 4,
+		IdleConnTimeout:     90 * time.Second,
+	}
+	client := &http.Client{Timeout: 30 * time.Second, Transport: TraceTransport(transport)}
+	clients.http[insecure] = client
+	return client
+}
+
+// returns the shared client for the etcd members with the client certificate
+// of the master
+func etcdClient() (*http.Client, error) {
+	clients.lock.Lock()
+	defer clients.lock.Unlock()
+
+	if clients.etcd != nil {
+		return clients.etcd, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(HostPath(etcdClientCert), HostPath(etcdClientKey))
+	if err != nil {
+		return nil, fmt.Errorf("Could not load etcd client certificate: %v", err)
+	}
+	ca, err := ioutil.ReadFile(HostPath(etcdCA))
+	if err != nil {
+		return nil, fmt.Errorf("Could not read etcd ca: %v", err)
+	}
+	pool := x509.NewCertPool()
+	pool.AppendCertsFromPEM(ca)
+
+	clients.etcdTransport = &http.Transport{
+		TLSClientConfig: &tls.Config{Certificates: []tls.Certificate{cert}, RootCAs: pool},
+		IdleConnTimeout: 90 * time.Second,
+	}
+	clients.etcd = &http.Client{Timeout: 10 * time.Second, Transport: TraceTransport(clients.etcdTransport)}
+	return clients.etcd, nil
+}
+
+// gets a resource over the api client if one is used, otherwise with oc get
+// ocArgs -o json. The response is kept for the run, so checks reading the
+// same objects only cause one call. It is meant for the reads of the checks,
+// a check waiting for a change must call the api itself.
+func getResource(ctx context.Context, apiPath string, v interface{}, ocArgs ...string) error {
+	clients.lock.Lock()
+	raw, ok := clients.resources[apiPath]
+	clients.lock.Unlock()
+	if ok {
+		log.Debug("Using the response of", apiPath, "of this run")
+		return json.Unmarshal(raw, v)
+	}
+
+	if apiClient != nil {
+		if err := apiClient.Get(ctx, apiPath, &raw); err != nil {
+			return err
+		}
+	} else {
+		out, err := runOc(ctx, "", append(append([]string{"get"}, ocArgs...), "-o", "json")...)
+		if err != nil {
+			return err
+		}
+		raw = json.RawMessage(out)
+	}
+
+	clients.lock.Lock()
+	if clients.resources == nil {
+		clients.resources = map[string]json.RawMessage{}
+	}
+	clients.resources[apiPath] = raw
+	clients.lock.Unlock()
+
+	return json.Unmarshal(raw, v)
+}
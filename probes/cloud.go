@@ -0,0 +1,261 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package probes
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// CheckCloudProvider checks that the api of the cloud provider configured
+// for the cluster (aws, azure, openstack or vsphere) can be reached and
+// accepts the credentials of the cloud config, as dynamic provisioning and
+// service load balancers depend on it.
+func CheckCloudProvider(ctx context.Context, provider string, configPath string) error {
+	log.Info("Checking the", provider, "cloud provider api with", configPath)
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	var err error
+	switch provider {
+	case "aws":
+		err = checkAws(ctx)
+	case "azure":
+		err = checkAzure(ctx, configPath)
+	case "openstack":
+		err = checkOpenstack(ctx, configPath)
+	case "vsphere":
+		err = checkVsphere(ctx, configPath)
+	default:
+		return fmt.Errorf("Cloud provider %s is not supported", provider)
+	}
+	if err != nil {
+		return newMessage("cloud-provider-degraded", provider, err)
+	}
+	return nil
+}
+
+// the instance profile provides the credentials on aws, they must not be
+// expired and the ec2 api of the region must answer
+func checkAws(ctx context.Context) error {
+	const metadata = "http://169.254.169.254/latest"
+
+	zone, err := cloudGet(ctx, metadata+"/meta-data/placement/availability-zone")
+	if err != nil {
+		return err
+	}
+	role, err := cloudGet(ctx, metadata+"/meta-data/iam/security-credentials/")
+	if err != nil {
+		return fmt.Errorf("no instance profile: %v", err)
+	}
+	content, err := cloudGet(ctx, metadata+"/meta-data/iam/security-credentials/"+strings.TrimSpace(role))
+	if err != nil {
+		return err
+	}
+	var credentials struct {
+		Code       string    `json:"Code"`
+		Expiration time.Time `json:"Expiration"`
+	}
+	if err := json.Unmarshal([]byte(content), &credentials); err != nil {
+		return fmt.Errorf("could not parse the credentials of the instance profile: %v", err)
+	}
+	if credentials.Code != "Success" || time.Now().After(credentials.Expiration) {
+		return fmt.Errorf("the credentials of the instance profile %s are not valid (%s, expiring %s)", role, credentials.Code, credentials.Expiration)
+	}
+
+	// the region is the zone without its letter, an unsigned call is
+	// rejected but proves that the api answers
+	region := strings.TrimSpace(zone)
+	region = region[:len(region)-1]
+	return cloudReachable(ctx, "https://ec2."+region+".amazonaws.com/")
+}
+
+// azure gets a token for the service principal of the cloud config
+func checkAzure(ctx context.Context, configPath string) error {
+	content, err := ioutil.ReadFile(HostPath(configPath))
+	if err != nil {
+		return fmt.Errorf("could not read the cloud config: %v", err)
+	}
+	var config struct {
+		TenantId        string `json:"tenantId"`
+		AadClientId     string `json:"aadClientId"`
+		AadClientSecret string `json:"aadClientSecret"`
+	}
+	if err := json.Unmarshal(content, &config); err != nil {
+		return fmt.Errorf("could not parse the cloud config: %v", err)
+	}
+
+	form := url.Values{
+		"grant_type":    {"client_credentials"},
+		"client_id":     {config.AadClientId},
+		"client_secret": {config.AadClientSecret},
+		"resource":      {"https://management.azure.com/"},
+	}
+	_, err = cloudPost(ctx, "https://login.microsoftonline.com/"+config.TenantId+"/oauth2/token", "application/x-www-form-urlencoded", []byte(form.Encode()))
+	if err != nil {
+		return err
+	}
+	return cloudReachable(ctx, "https://management.azure.com/")
+}
+
+// openstack gets a token from keystone with the credentials of the cloud config
+func checkOpenstack(ctx context.Context, configPath string) error {
+	config, err := readCloudConfig(configPath)
+	if err != nil {
+		return err
+	}
+
+	domain := config["domain-name"]
+	if len(domain) == 0 {
+		domain = "Default"
+	}
+	project := map[string]interface{}{"name": config["tenant-name"], "domain": map[string]string{"name": domain}}
+	if id := config["tenant-id"]; len(id) > 0 {
+		project = map[string]interface{}{"id": id}
+	}
+	body, _ := json.Marshal(map[string]interface{}{
+		"auth": map[string]interface{}{
+			"identity": map[string]interface{}{
+				"methods": []string{"password"},
+				"password": map[string]interface{}{
+					"user": map[string]interface{}{"name": config["username"], "password": config["password"], "domain": map[string]string{"name": domain}},
+				},
+			},
+			"scope": map[string]interface{}{"project": project},
+		},
+	})
+
+	authUrl := strings.TrimSuffix(config["auth-url"], "/")
+	if !strings.HasSuffix(authUrl, "/v3") {
+		authUrl += "/v3"
+	}
+	_, err = cloudPost(ctx, authUrl+"/auth/tokens", "application/json", body)
+	return err
+}
+
+// vsphere logs in to the sdk of the vcenter of the cloud config
+func checkVsphere(ctx context.Context, configPath string) error {
+	config, err := readCloudConfig(configPath)
+	if err != nil {
+		return err
+	}
+
+	var login bytes.Buffer
+	fmt.Fprintf(&login, `<?xml version="1.0" encoding="UTF-8"?>
+<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/" xmlns:vim="urn:vim25">
+<soapenv:Body><vim:Login><vim:_this type="SessionManager">SessionManager</vim:_this><vim:userName>%s</vim:userName><vim:password>%s</vim:password></vim:Login></soapenv:Body>
+</soapenv:Envelope>`, xmlEscape(config["user"]), xmlEscape(config["password"]))
+
+	port := config["port"]
+	if len(port) == 0 {
+		port = "443"
+	}
+	_, err = cloudPost(ctx, "https://"+config["server"]+":"+port+"/sdk", "text/xml", login.Bytes())
+	return err
+}
+
+// reads the settings of the gcfg cloud config, the sections are merged
+func readCloudConfig(path string) (map[string]string, error) {
+	file, err := os.Open(HostPath(path))
+	if err != nil {
+		return nil, fmt.Errorf("could not read the cloud config: %v", err)
+	}
+	defer file.Close()
+
+	var config = map[string]string{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			// newer vsphere configs name the vcenter in its section
+			if strings.HasPrefix(line, "[VirtualCenter ") {
+				config["server"] = strings.Trim(strings.TrimPrefix(line, "[VirtualCenter "), `"] `)
+			}
+			continue
+		}
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) == 2 {
+			config[strings.ToLower(strings.TrimSpace(parts[0]))] = strings.Trim(strings.TrimSpace(parts[1]), `"`)
+		}
+	}
+	return config, scanner.Err()
+}
+
+func xmlEscape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;", "'", "&apos;").Replace(s)
+}
+
+func cloudGet(ctx context.Context, url string) (string, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := sharedClient(false).Do(req.WithContext(ctx))
+	if err != nil {
+		return "", fmt.Errorf("%s failed: %v", url, err)
+	}
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s returned status code %d", url, resp.StatusCode)
+	}
+	return string(body), nil
+}
+
+// posts to the api and fails if the credentials are rejected
+func cloudPost(ctx context.Context, url string, contentType string, body []byte) (string, error) {
+	req, err := http.NewRequest("POST", url, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", contentType)
+	resp, err := sharedClient(false).Do(req.WithContext(ctx))
+	if err != nil {
+		return "", fmt.Errorf("%s failed: %v", url, err)
+	}
+	defer resp.Body.Close()
+	content, _ := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode >= 300 {
+		return "", fmt.Errorf("%s rejected the credentials with status code %d", url, resp.StatusCode)
+	}
+	return string(content), nil
+}
+
+// fails if the api doesn't answer, any http status is fine
+func cloudReachable(ctx context.Context, url string) error {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := sharedClient(false).Do(req.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("%s is not reachable: %v", url, err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 500 {
+		return fmt.Errorf("%s returned status code %d", url, resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package probes
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Component is a stateful cluster component like the cassandra of the
+// metrics or the elasticsearch of the logging, backed by persistent volumes.
+type Component struct {
+	Name      string
+	Namespace string
+	Selector  string
+	// mount path of the persistent volume in the pods
+	Path string
+}
+
+// ComponentPods are the pods of a component with the restarts of their
+// containers.
+type ComponentPods struct {
+	Items []struct {
+		Metadata struct {
+			Name string `json:"name"`
+		} `json:"metadata"`
+		Status struct {
+			Phase             string `json:"phase"`
+			ContainerStatuses []struct {
+				Name         string `json:"name"`
+				RestartCount int    `json:"restartCount"`
+				State        struct {
+					Waiting *struct {
+						Reason string `json:"reason"`
+					} `json:"waiting"`
+				} `json:"state"`
+			} `json:"containerStatuses"`
+		} `json:"status"`
+	} `json:"items"`
+}
+
+// Pods returns the pods of the component, at least one.
+func (c Component) Pods(ctx context.Context) (ComponentPods, error) {
+	var pods ComponentPods
+	apiPath := "/api/v1/namespaces/" + c.Namespace + "/pods?labelSelector=" + url.QueryEscape(c.Selector)
+	if err := getResource(ctx, apiPath, &pods, "pods", "-n", c.Namespace, "-l", c.Selector); err != nil {
+		return pods, fmt.Errorf("Could not get %s pods: %v", c.Name, err)
+	}
+	if len(pods.Items) == 0 {
+		return pods, newMessage("component-pods-missing", c.Name, c.Namespace, c.Selector)
+	}
+	return pods, nil
+}
+
+// ComponentStorageUsage returns the usage in percent of the persistent
+// volume in every running pod of the component.
+func ComponentStorageUsage(ctx context.Context, c Component) (map[string]int, error) {
+	log.Info("Reading persistent storage usage of", c.Name)
+
+	pods, err := c.Pods(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	usages := map[string]int{}
+	for _, pod := range pods.Items {
+		if pod.Status.Phase != "Running" {
+			continue
+		}
+
+		out, err := runOc(ctx, "", "exec", pod.Metadata.Name, "-n", c.Namespace, "--", "df", "-P", c.Path)
+		if err != nil {
+			return nil, fmt.Errorf("Could not get storage usage of %s: %v", pod.Metadata.Name, err)
+		}
+		usage, err := parseDfUsage(out)
+		if err != nil {
+			return nil, fmt.Errorf("Could not parse storage usage of %s: %v", pod.Metadata.Name, err)
+		}
+		usages[pod.Metadata.Name] = usage
+	}
+	return usages, nil
+}
+
+// CheckComponentStorage fails if the persistent volume of a pod of the
+// component is used percent or more.
+func CheckComponentStorage(c Component, usages map[string]int, percent int) error {
+	var full []string
+	for pod, usage := range usages {
+		if usage >= percent {
+			full = append(full, fmt.Sprintf("%s %d%%", pod, usage))
+		}
+	}
+
+	if len(full) > 0 {
+		sort.Strings(full)
+		return newMessage("component-storage-full", c.Name, percent, strings.Join(full, ", "))
+	}
+	return nil
+}
+
+// returns the use% of the last line of df -P
+func parseDfUsage(out string) (int, error) {
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	fields := strings.Fields(lines[len(lines)-1])
+	if len(fields) < 5 || !strings.HasSuffix(fields[4], "%") {
+		return 0, fmt.Errorf("unexpected df output %q", out)
+	}
+	return strconv.Atoi(strings.TrimSuffix(fields[4], "%"))
+}
+
+// CheckComponentRestarts fails if a container of the component is in a
+// restart loop or has restarted maxRestarts times or more.
+func CheckComponentRestarts(c Component, pods ComponentPods, maxRestarts int) error {
+	var restarting []string
+	for _, pod := range pods.Items {
+		for _, status := range pod.Status.ContainerStatuses {
+			switch {
+			case status.State.Waiting != nil && status.State.Waiting.Reason == "CrashLoopBackOff":
+				restarting = append(restarting, fmt.Sprintf("%s/%s in CrashLoopBackOff", pod.Metadata.Name, status.Name))
+			case status.RestartCount >= maxRestarts:
+				restarting = append(restarting, fmt.Sprintf("%s/%s restarted %d times", pod.Metadata.Name, status.Name, status.RestartCount))
+			}
+		}
+	}
+
+	if len(restarting) > 0 {
+		return newMessage("component-pods-restarting", c.Name, strings.Join(restarting, ", "))
+	}
+	return nil
+}
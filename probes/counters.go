@@ -0,0 +1,148 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package probes
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// the client certificates for etcd, those of the master by default
+var (
+	etcdClientCert = "/etc/origin/master/master.etcd-client.crt"
+	etcdClientKey  = "/etc/origin/master/master.etcd-client.key"
+	etcdCA         = "/etc/origin/master/master.etcd-ca.crt"
+)
+
+// SetEtcdCertificates sets the client certificate, key and ca used to call
+// etcd, empty values keep those of the master.
+func SetEtcdCertificates(cert string, key string, ca string) {
+	if len(cert) > 0 {
+		etcdClientCert = cert
+	}
+	if len(key) > 0 {
+		etcdClientKey = key
+	}
+	if len(ca) > 0 {
+		etcdCA = ca
+	}
+	ResetClients()
+}
+
+// RouterRestarts returns the sum of the container restarts of all routers.
+func RouterRestarts(ctx context.Context) (float64, error) {
+	out, err := runOc(ctx, "", "get", "pods", "-n", "default", "-l", "router",
+		"-o", "jsonpath={.items[*].status.containerStatuses[*].restartCount}")
+	if err != nil {
+		return 0, fmt.Errorf("Could not get router pods: %v", err)
+	}
+
+	var sum float64
+	for _, field := range strings.Fields(out) {
+		count, err := strconv.ParseFloat(field, 64)
+		if err != nil {
+			return 0, fmt.Errorf("Could not parse restart count %s", field)
+		}
+		sum += count
+	}
+	return sum, nil
+}
+
+// DockerPoolUsage returns the data usage of the docker thin pool in percent.
+func DockerPoolUsage(ctx context.Context) (float64, error) {
+	cmd := command(ctx, "lvs", "--noheadings", "-o", "lv_name,data_percent")
+	start := time.Now()
+	out, err := cmd.Output()
+	TraceCommand(cmd, out, nil, err, time.Since(start))
+	if err != nil {
+		return 0, fmt.Errorf("lvs failed: %v", err)
+	}
+
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && strings.HasSuffix(fields[0], "docker-pool") {
+			return strconv.ParseFloat(fields[1], 64)
+		}
+	}
+	return 0, fmt.Errorf("No docker-pool logical volume found")
+}
+
+// EtcdDbSize returns the largest database size of the etcd members in
+// megabytes, read from their metrics with the client certificate of the master.
+func EtcdDbSize(ctx context.Context, urls []string) (float64, error) {
+	client, err := etcdClient()
+	if err != nil {
+		return 0, err
+	}
+
+	var largest float64
+	for _, url := range urls {
+		size, err := etcdMetric(ctx, client, strings.TrimSuffix(url, "/")+"/metrics",
+			"etcd_mvcc_db_total_size_in_bytes", "etcd_debugging_mvcc_db_total_size_in_bytes")
+		if err != nil {
+			return 0, err
+		}
+		if size > largest {
+			largest = size
+		}
+	}
+	return largest / 1024 / 1024, nil
+}
+
+// returns the samples of the metrics endpoint by their name including the
+// labels, e.g. etcd_disk_wal_fsync_duration_seconds_bucket{le="0.001"}
+func etcdMetrics(ctx context.Context, client *http.Client, url string) (map[string]float64, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("Could not get etcd metrics %s: %v", url, err)
+	}
+	defer resp.Body.Close()
+
+	values := map[string]float64{}
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && !strings.HasPrefix(fields[0], "#") {
+			if value, err := strconv.ParseFloat(fields[1], 64); err == nil {
+				values[fields[0]] = value
+			}
+		}
+	}
+	return values, scanner.Err()
+}
+
+// returns the value of the first of the metrics the endpoint exposes
+func etcdMetric(ctx context.Context, client *http.Client, url string, names ...string) (float64, error) {
+	values, err := etcdMetrics(ctx, client, url)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, name := range names {
+		if value, ok := values[name]; ok {
+			return value, nil
+		}
+	}
+	return 0, fmt.Errorf("etcd metrics %s contain no database size", url)
+}
@@ -0,0 +1,100 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package probes
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// matches the header of a finding, e.g. "WARN:  [DClu1012 from diagnostic ClusterRegistry@openshift/...]"
+var diagnosticHeader = regexp.MustCompile(`^(WARN|ERROR):\s+\[(\S+) from diagnostic (\w+)`)
+
+// RunDiagnostics runs the given oc adm diagnostics modules (all if empty) and
+// returns the reported warnings and errors. If oc itself can't be run, this
+// is returned as an error as well.
+func RunDiagnostics(ctx context.Context, modules []string, timeout time.Duration) (warnings []error, errors []error) {
+	log.Info("Running oc adm diagnostics", strings.Join(modules, " "))
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	args := append([]string{"adm", "diagnostics"}, modules...)
+	cmd := command(ctx, "oc", args...)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	// oc exits with a non zero code as soon as a diagnostic reports an error,
+	// so the result is only relevant if there is nothing to parse
+	start := time.Now()
+	runErr := cmd.Run()
+	TraceCommand(cmd, stdout.Bytes(), stderr.Bytes(), runErr, time.Since(start))
+
+	warnings, errors = parseDiagnostics(stdout.String())
+
+	if runErr != nil && len(errors) == 0 {
+		errors = append(errors, fmt.Errorf("oc adm diagnostics failed: %v", runErr))
+	}
+
+	return warnings, errors
+}
+
+func parseDiagnostics(output string) (warnings []error, errors []error) {
+	var level, id, diagnostic string
+	var message []string
+
+	flush := func() {
+		if len(level) == 0 {
+			return
+		}
+
+		err := newMessage("diagnostics-finding", diagnostic, id, strings.Join(message, " "))
+		if level == "ERROR" {
+			errors = append(errors, err)
+		} else {
+			warnings = append(warnings, err)
+		}
+		level = ""
+		message = nil
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if m := diagnosticHeader.FindStringSubmatch(line); m != nil {
+			flush()
+			level, id, diagnostic = m[1], m[2], m[3]
+			continue
+		}
+
+		// findings are continued by indented lines
+		if len(level) > 0 && strings.HasPrefix(line, " ") && len(strings.TrimSpace(line)) > 0 {
+			message = append(message, strings.TrimSpace(line))
+		} else {
+			flush()
+		}
+	}
+	flush()
+
+	return warnings, errors
+}
@@ -0,0 +1,83 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package probes
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// kernel messages of failing disks
+var ioErrorPattern = regexp.MustCompile(`(?i)I/O error|blk_update_request|medium error|failed command|critical target error|unrecovered read error`)
+
+// CheckSmartHealth fails if smartctl reports one of the devices as failing.
+func CheckSmartHealth(ctx context.Context, devices []string) error {
+	log.Info("Checking smart health of", strings.Join(devices, ", "))
+
+	var failing []string
+	for _, device := range devices {
+		if len(device) == 0 {
+			continue
+		}
+		cmd := command(ctx, "smartctl", "-H", device)
+		start := time.Now()
+		// the exit code of smartctl is a bit mask which is also set for
+		// errors in the log, so only the output counts
+		out, err := cmd.CombinedOutput()
+		TraceCommand(cmd, out, nil, err, time.Since(start))
+
+		switch output := string(out); {
+		case strings.Contains(output, "test result: PASSED") || strings.Contains(output, "SMART Health Status: OK"):
+		case strings.Contains(output, "test result: FAILED") || strings.Contains(output, "SMART Health Status:"):
+			failing = append(failing, device)
+		default:
+			return fmt.Errorf("Could not read the smart health of %s: %v %s", device, err, strings.TrimSpace(output))
+		}
+	}
+
+	if len(failing) > 0 {
+		return newMessage("disks-failing", strings.Join(failing, ", "))
+	}
+	return nil
+}
+
+// CheckKernelIoErrors fails if the kernel log contains disk i/o errors
+// since the given time.
+func CheckKernelIoErrors(ctx context.Context, since time.Time) error {
+	log.Info("Checking the kernel log for i/o errors since", since.Format("2006-01-02 15:04:05"))
+
+	cmd := command(ctx, "journalctl", "-k", "--no-pager", "-q", "--since", since.Format("2006-01-02 15:04:05"))
+	start := time.Now()
+	out, err := cmd.Output()
+	TraceCommand(cmd, out, nil, err, time.Since(start))
+	if err != nil {
+		return fmt.Errorf("journalctl -k failed: %v", err)
+	}
+
+	var errors []string
+	for _, line := range strings.Split(string(out), "\n") {
+		if ioErrorPattern.MatchString(line) {
+			errors = append(errors, strings.TrimSpace(line))
+		}
+	}
+
+	if len(errors) > 0 {
+		return newMessage("disk-io-errors", len(errors), since.Format("2006-01-02 15:04"), errors[len(errors)-1])
+	}
+	return nil
+}
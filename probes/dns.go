@@ -0,0 +1,148 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package probes
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// CheckDnsConsistency checks that every host resolves and each of its
+// addresses resolves back to it, and that the local hostname resolves to
+// nodeIp. Mismatches break the validation of certificate SANs and the
+// registration of nodes.
+func CheckDnsConsistency(ctx context.Context, hosts []string, nodeIp string) error {
+	log.Info("Checking forward and reverse dns of", strings.Join(hosts, ", "))
+
+	var problems []string
+	for _, host := range hosts {
+		if len(host) == 0 {
+			continue
+		}
+		addrs, err := net.DefaultResolver.LookupHost(ctx, host)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s does not resolve", host))
+			continue
+		}
+		for _, addr := range addrs {
+			names, err := net.DefaultResolver.LookupAddr(ctx, addr)
+			if err != nil || len(names) == 0 {
+				problems = append(problems, fmt.Sprintf("%s of %s has no reverse entry", addr, host))
+			} else if !containsHostname(names, host) {
+				problems = append(problems, fmt.Sprintf("%s of %s resolves back to %s", addr, host, strings.Join(names, ", ")))
+			}
+		}
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		return fmt.Errorf("Could not read the hostname: %v", err)
+	}
+	if addrs, err := net.DefaultResolver.LookupHost(ctx, hostname); err != nil {
+		problems = append(problems, fmt.Sprintf("the hostname %s does not resolve", hostname))
+	} else if len(nodeIp) > 0 && !contains(addrs, nodeIp) {
+		problems = append(problems, fmt.Sprintf("the hostname %s resolves to %s instead of the node ip %s", hostname, strings.Join(addrs, ", "), nodeIp))
+	}
+
+	if len(problems) > 0 {
+		return newMessage("dns-inconsistent", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// returns if one of the names of a reverse lookup is the host, a short
+// host name matches the first label of the name
+func containsHostname(names []string, host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSuffix(name, "."))
+		if name == host || !strings.Contains(host, ".") && strings.SplitN(name, ".", 2)[0] == host {
+			return true
+		}
+	}
+	return false
+}
+
+// LocalIp returns the local address used to reach the host of the url,
+// which is the node ip unless the node has several networks.
+func LocalIp(rawUrl string) (string, error) {
+	u, err := url.Parse(rawUrl)
+	if err != nil {
+		return "", err
+	}
+	port := u.Port()
+	if len(port) == 0 {
+		port = "443"
+	}
+	// nothing is sent over udp, the route is only looked up
+	conn, err := net.Dial("udp", net.JoinHostPort(u.Hostname(), port))
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	return conn.LocalAddr().(*net.UDPAddr).IP.String(), nil
+}
+
+// CheckDnsSrv checks that the srv records of the port of a headless service
+// resolve and match its ready endpoints. StatefulSets find their peers over
+// these records, plain lookups of the service keep working when they break.
+func CheckDnsSrv(ctx context.Context, namespace, service, port, domain string) error {
+	name := fmt.Sprintf("%s.%s.svc.%s", service, namespace, domain)
+	log.Info("Checking the srv records of port", port, "of", name)
+
+	var endpoints struct {
+		Subsets []struct {
+			Addresses []struct {
+				Ip       string `json:"ip"`
+				Hostname string `json:"hostname"`
+			} `json:"addresses"`
+			Ports []struct {
+				Name string `json:"name"`
+			} `json:"ports"`
+		} `json:"subsets"`
+	}
+	if err := getResource(ctx, fmt.Sprintf("/api/v1/namespaces/%s/endpoints/%s", namespace, service), &endpoints, "endpoints", service, "-n", namespace); err != nil {
+		return err
+	}
+	expected := 0
+	for _, subset := range endpoints.Subsets {
+		for _, p := range subset.Ports {
+			if p.Name == port {
+				expected += len(subset.Addresses)
+				break
+			}
+		}
+	}
+
+	_, records, err := net.DefaultResolver.LookupSRV(ctx, port, "tcp", name)
+	if err != nil {
+		if expected == 0 {
+			return nil
+		}
+		return newMessage("dns-srv-missing", port, name, expected, err)
+	}
+	if len(records) != expected {
+		var targets []string
+		for _, r := range records {
+			targets = append(targets, strings.TrimSuffix(r.Target, "."))
+		}
+		return newMessage("dns-srv-inconsistent", name, len(records), expected, strings.Join(targets, ", "))
+	}
+	return nil
+}
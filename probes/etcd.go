@@ -0,0 +1,127 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package probes
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// the disk histograms of etcd
+const (
+	WalFsyncMetric      = "etcd_disk_wal_fsync_duration_seconds"
+	BackendCommitMetric = "etcd_disk_backend_commit_duration_seconds"
+)
+
+// EtcdDiskLatency is the quantile of the disk histograms of an etcd member.
+type EtcdDiskLatency struct {
+	Member    string
+	Latencies map[string]time.Duration
+}
+
+// EtcdDiskLatencies reads the wal fsync and backend commit histograms of the
+// etcd members and returns their quantile, e.g. 0.99. The histograms count
+// since the start of the member, the quantile is the upper bound of the
+// bucket it falls into.
+func EtcdDiskLatencies(ctx context.Context, urls []string, quantile float64) ([]EtcdDiskLatency, error) {
+	log.Info("Reading etcd disk latencies of", strings.Join(urls, ", "))
+
+	client, err := etcdClient()
+	if err != nil {
+		return nil, err
+	}
+
+	var members []EtcdDiskLatency
+	for _, url := range urls {
+		values, err := etcdMetrics(ctx, client, strings.TrimSuffix(url, "/")+"/metrics")
+		if err != nil {
+			return nil, err
+		}
+
+		var member = EtcdDiskLatency{Member: url, Latencies: map[string]time.Duration{}}
+		for _, metric := range []string{WalFsyncMetric, BackendCommitMetric} {
+			latency, err := histogramQuantile(values, metric, quantile)
+			if err != nil {
+				return nil, fmt.Errorf("Could not read %s of %s: %v", metric, url, err)
+			}
+			member.Latencies[metric] = latency
+		}
+		members = append(members, member)
+	}
+	return members, nil
+}
+
+// returns the upper bound of the bucket of the histogram the quantile falls into
+func histogramQuantile(values map[string]float64, metric string, quantile float64) (time.Duration, error) {
+	type bucket struct {
+		le    float64
+		count float64
+	}
+
+	var buckets []bucket
+	prefix := metric + `_bucket{le="`
+	for name, count := range values {
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		le, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimPrefix(name, prefix), `"}`), 64)
+		if err != nil {
+			continue
+		}
+		buckets = append(buckets, bucket{le, count})
+	}
+	if len(buckets) == 0 {
+		return 0, fmt.Errorf("no buckets")
+	}
+	sort.Slice(buckets, func(i, j int) bool { return buckets[i].le < buckets[j].le })
+
+	total := buckets[len(buckets)-1].count
+	if total == 0 {
+		return 0, nil
+	}
+	for _, b := range buckets {
+		if b.count >= quantile*total {
+			if math.IsInf(b.le, 1) {
+				if len(buckets) < 2 {
+					return 0, fmt.Errorf("no finite buckets")
+				}
+				// above the largest finite bucket
+				return time.Duration(buckets[len(buckets)-2].le * float64(time.Second)), nil
+			}
+			return time.Duration(b.le * float64(time.Second)), nil
+		}
+	}
+	return 0, nil
+}
+
+// CheckEtcdDiskLatency fails if the latency of the metric of a member is above limit.
+func CheckEtcdDiskLatency(members []EtcdDiskLatency, metric string, limit time.Duration) error {
+	var slow []string
+	for _, m := range members {
+		if latency := m.Latencies[metric]; latency > limit {
+			slow = append(slow, fmt.Sprintf("%s %s", m.Member, latency))
+		}
+	}
+
+	if len(slow) > 0 {
+		return newMessage("etcd-latency-high", metric, limit, strings.Join(slow, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package probes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// CheckEtcdMemberHealth fails if one of the etcd members doesn't report
+// itself healthy on /health.
+func CheckEtcdMemberHealth(ctx context.Context, urls []string) error {
+	log.Info("Checking health of etcd members", strings.Join(urls, ", "))
+
+	client, err := etcdClient()
+	if err != nil {
+		return err
+	}
+
+	var unhealthy []string
+	for _, url := range urls {
+		var health struct {
+			Health string `json:"health"`
+		}
+		if err := getEtcdJSON(ctx, client, strings.TrimSuffix(url, "/")+"/health", &health); err != nil {
+			unhealthy = append(unhealthy, err.Error())
+		} else if health.Health != "true" {
+			unhealthy = append(unhealthy, url+" reports health "+health.Health)
+		}
+	}
+
+	if len(unhealthy) > 0 {
+		return newMessage("etcd-members-unhealthy", strings.Join(unhealthy, ", "))
+	}
+	return nil
+}
+
+// CheckEtcdMemberList fails if the etcd members don't report the same
+// members or if a member was added but never started.
+func CheckEtcdMemberList(ctx context.Context, urls []string) error {
+	log.Info("Checking member lists of etcd members", strings.Join(urls, ", "))
+
+	client, err := etcdClient()
+	if err != nil {
+		return err
+	}
+
+	var problems []string
+	lists := map[string][]string{}
+	for _, url := range urls {
+		var list struct {
+			Members []struct {
+				ID   string `json:"id"`
+				Name string `json:"name"`
+			} `json:"members"`
+		}
+		if err := getEtcdJSON(ctx, client, strings.TrimSuffix(url, "/")+"/v2/members", &list); err != nil {
+			problems = append(problems, err.Error())
+			continue
+		}
+
+		var members []string
+		for _, m := range list.Members {
+			if len(m.Name) == 0 {
+				problems = append(problems, fmt.Sprintf("member %s was never started", m.ID))
+			}
+			members = append(members, m.ID)
+		}
+		sort.Strings(members)
+		lists[url] = members
+	}
+
+	var reference string
+	for _, url := range urls {
+		members, ok := lists[url]
+		if !ok {
+			continue
+		}
+		if len(reference) == 0 {
+			reference = url
+		} else if strings.Join(members, ",") != strings.Join(lists[reference], ",") {
+			problems = append(problems, fmt.Sprintf("%s knows members %s but %s knows %s", url, strings.Join(members, ","), reference, strings.Join(lists[reference], ",")))
+		}
+	}
+
+	if len(problems) > 0 {
+		return newMessage("etcd-member-lists-inconsistent", strings.Join(problems, ", "))
+	}
+	return nil
+}
+
+func getEtcdJSON(ctx context.Context, client *http.Client, url string, v interface{}) error {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("%s failed: %v", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned status code %d", url, resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("Could not parse %s: %v", url, err)
+	}
+	return nil
+}
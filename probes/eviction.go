@@ -0,0 +1,216 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package probes
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// KubeletThresholds are the image garbage collection and disk eviction
+// thresholds of the kubelet.
+type KubeletThresholds struct {
+	// usage in percent at which images are removed and down to which
+	ImageGcHigh int
+	ImageGcLow  int
+	// the thresholds of the eviction signals, e.g. nodefs.available: 10%
+	Hard map[string]string
+	Soft map[string]string
+}
+
+// Filesystem is the size and free space of the filesystem of a path.
+type Filesystem struct {
+	Path      string
+	Size      int64
+	Available int64
+}
+
+// ReadKubeletThresholds reads the thresholds from the kubeletArguments of
+// the node config.
+func ReadKubeletThresholds(path string) (KubeletThresholds, error) {
+	var t KubeletThresholds
+
+	args, err := readKubeletArguments(path)
+	if err != nil {
+		return t, err
+	}
+	if t.ImageGcHigh, err = args.getInt("image-gc-high-threshold"); err != nil {
+		return t, err
+	}
+	if t.ImageGcLow, err = args.getInt("image-gc-low-threshold"); err != nil {
+		return t, err
+	}
+	t.Hard = parseEvictionSignals(args.get("eviction-hard"))
+	t.Soft = parseEvictionSignals(args.get("eviction-soft"))
+	return t, nil
+}
+
+// parses "nodefs.available<10%,imagefs.available<15%" into a map
+func parseEvictionSignals(arg string) map[string]string {
+	signals := map[string]string{}
+	for _, signal := range strings.Split(arg, ",") {
+		parts := strings.SplitN(strings.TrimSpace(signal), "<", 2)
+		if len(parts) == 2 {
+			signals[parts[0]] = parts[1]
+		}
+	}
+	return signals
+}
+
+// ReadFilesystem reads the size and free space of the filesystem of path
+// with df.
+func ReadFilesystem(ctx context.Context, path string) (Filesystem, error) {
+	fs := Filesystem{Path: path}
+
+	cmd := command(ctx, "df", "-P", "-k", path)
+	start := time.Now()
+	out, err := cmd.Output()
+	TraceCommand(cmd, out, nil, err, time.Since(start))
+	if err != nil {
+		return fs, fmt.Errorf("df of %s failed: %v", path, err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	fields := strings.Fields(lines[len(lines)-1])
+	if len(fields) < 4 {
+		return fs, fmt.Errorf("unexpected df output %q", out)
+	}
+	if fs.Size, err = strconv.ParseInt(fields[1], 10, 64); err != nil {
+		return fs, fmt.Errorf("unexpected df output %q", out)
+	}
+	if fs.Available, err = strconv.ParseInt(fields[3], 10, 64); err != nil {
+		return fs, fmt.Errorf("unexpected df output %q", out)
+	}
+	fs.Size *= 1024
+	fs.Available *= 1024
+	return fs, nil
+}
+
+// returns the bytes of an eviction threshold like 10% or 1Gi on a
+// filesystem of size bytes
+func evictionBytes(threshold string, size int64) (int64, error) {
+	if strings.HasSuffix(threshold, "%") {
+		percent, err := strconv.ParseFloat(strings.TrimSuffix(threshold, "%"), 64)
+		if err != nil {
+			return 0, err
+		}
+		return int64(float64(size) * percent / 100), nil
+	}
+
+	units := []struct {
+		suffix string
+		factor int64
+	}{{"Ki", 1 << 10}, {"Mi", 1 << 20}, {"Gi", 1 << 30}, {"Ti", 1 << 40}, {"k", 1e3}, {"K", 1e3}, {"M", 1e6}, {"G", 1e9}, {"T", 1e12}}
+	for _, unit := range units {
+		if strings.HasSuffix(threshold, unit.suffix) {
+			value, err := strconv.ParseFloat(strings.TrimSuffix(threshold, unit.suffix), 64)
+			return int64(value * float64(unit.factor)), err
+		}
+	}
+	return strconv.ParseInt(threshold, 10, 64)
+}
+
+// CheckKubeletThresholds fails if the thresholds contradict each other or
+// the disks: image gc has to free space before the kubelet evicts pods
+// for it, and a threshold beyond the size of the disk evicts forever.
+func CheckKubeletThresholds(t KubeletThresholds, nodefs Filesystem, imagefs Filesystem) error {
+	var problems []string
+
+	if t.ImageGcLow >= t.ImageGcHigh {
+		problems = append(problems, fmt.Sprintf("image-gc-low-threshold %d%% is not below image-gc-high-threshold %d%%", t.ImageGcLow, t.ImageGcHigh))
+	}
+
+	filesystems := map[string]Filesystem{"nodefs.available": nodefs, "imagefs.available": imagefs}
+	for _, kind := range []string{"eviction-hard", "eviction-soft"} {
+		signals := t.Hard
+		if kind == "eviction-soft" {
+			signals = t.Soft
+		}
+		for _, signal := range []string{"nodefs.available", "imagefs.available"} {
+			threshold, ok := signals[signal]
+			if !ok {
+				continue
+			}
+			fs := filesystems[signal]
+			bytes, err := evictionBytes(threshold, fs.Size)
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("%s %s<%s can't be parsed", kind, signal, threshold))
+				continue
+			}
+			if bytes >= fs.Size {
+				problems = append(problems, fmt.Sprintf("%s %s<%s is not below the size of %s (%d GiB)", kind, signal, threshold, fs.Path, fs.Size>>30))
+			}
+			gcAvailable := fs.Size * int64(100-t.ImageGcHigh) / 100
+			if signal == "imagefs.available" && bytes >= gcAvailable {
+				problems = append(problems, fmt.Sprintf("%s %s<%s evicts before image gc starts at %d%% usage", kind, signal, threshold, t.ImageGcHigh))
+			}
+		}
+	}
+
+	if hard, ok := t.Hard["nodefs.available"]; ok {
+		if soft, ok := t.Soft["nodefs.available"]; ok {
+			hardBytes, err1 := evictionBytes(hard, nodefs.Size)
+			softBytes, err2 := evictionBytes(soft, nodefs.Size)
+			if err1 == nil && err2 == nil && softBytes <= hardBytes {
+				problems = append(problems, fmt.Sprintf("eviction-soft nodefs.available<%s is not above eviction-hard nodefs.available<%s", soft, hard))
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		return newMessage("kubelet-gc-inconsistent", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// CheckEvictionHeadroom fails if the free space of the node or image
+// filesystem is less than points percentage points of the disk above the
+// eviction threshold which is reached first.
+func CheckEvictionHeadroom(t KubeletThresholds, nodefs Filesystem, imagefs Filesystem, points int) error {
+	var imminent []string
+
+	for _, check := range []struct {
+		signal string
+		fs     Filesystem
+	}{{"nodefs.available", nodefs}, {"imagefs.available", imagefs}} {
+		if check.fs.Size == 0 {
+			continue
+		}
+		var evictAt int64 = -1
+		var threshold string
+		for _, signals := range []map[string]string{t.Hard, t.Soft} {
+			if value, ok := signals[check.signal]; ok {
+				if bytes, err := evictionBytes(value, check.fs.Size); err == nil && bytes > evictAt {
+					evictAt, threshold = bytes, value
+				}
+			}
+		}
+		if evictAt < 0 {
+			continue
+		}
+		headroom := float64(check.fs.Available-evictAt) * 100 / float64(check.fs.Size)
+		if headroom < float64(points) {
+			imminent = append(imminent, fmt.Sprintf("%s %d GiB free, evicts below %s (%.1f points above)", check.fs.Path, check.fs.Available>>30, threshold, headroom))
+		}
+	}
+
+	if len(imminent) > 0 {
+		return newMessage("eviction-imminent", points, strings.Join(imminent, ", "))
+	}
+	return nil
+}
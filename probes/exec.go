@@ -0,0 +1,90 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package probes
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// paths of the external commands configured with SetCommandPaths
+var commandPaths = map[string]string{}
+
+// MissingCommandError is returned if a command a check needs is not
+// installed, so the check is skipped instead of failing.
+type MissingCommandError struct {
+	Command string
+	Path    string
+}
+
+func (e *MissingCommandError) Error() string {
+	if e.Path == e.Command {
+		return newMessage("command-missing", e.Command+" is not in the PATH").Error()
+	}
+	return newMessage("command-missing", e.Command+" not found at "+e.Path).Error()
+}
+
+// SetCommandPaths sets the paths of external commands like oc or etcdctl.
+// The directories of the paths are also put in front of the PATH, so the
+// checks of the library which run the commands themselves find them too.
+func SetCommandPaths(paths map[string]string) {
+	commandPaths = map[string]string{}
+	for name, path := range paths {
+		if len(path) == 0 {
+			continue
+		}
+		commandPaths[name] = path
+
+		dir := filepath.Dir(path)
+		if filepath.Base(path) == name && dir != "." && !contains(filepath.SplitList(os.Getenv("PATH")), dir) {
+			os.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+		}
+	}
+}
+
+// CommandPath returns the configured path of a command or its name to look
+// it up in the PATH.
+func CommandPath(name string) string {
+	if path, ok := commandPaths[name]; ok {
+		return path
+	}
+	return name
+}
+
+// MissingCommand returns a MissingCommandError if the command can't be
+// found, nil otherwise.
+func MissingCommand(name string) error {
+	if root := os.Getenv(hostRootEnv); len(root) > 0 && contains(hostCommands, name) {
+		if !hostCommandExists(root, name) {
+			return &MissingCommandError{Command: name, Path: "the host " + root}
+		}
+		return nil
+	}
+
+	path := CommandPath(name)
+	if _, err := exec.LookPath(path); err != nil {
+		return &MissingCommandError{Command: name, Path: path}
+	}
+	return nil
+}
+
+// command returns the command with the configured path of name.
+func command(ctx context.Context, name string, args ...string) *exec.Cmd {
+	log.Debug("Running", name, strings.Join(args, " "))
+	return exec.CommandContext(ctx, CommandPath(name), args...)
+}
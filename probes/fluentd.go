@@ -0,0 +1,77 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package probes
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+)
+
+// FluentdBuffer is the backlog of the file buffers of fluentd.
+type FluentdBuffer struct {
+	SizeMB int64
+	// chunks queued for sending, a chunk with .q. in its name
+	Queued int
+	Oldest time.Time
+}
+
+// ReadFluentdBuffer sums up the chunks of the file buffers in dir, fluentd
+// keeps them there until elasticsearch accepted them.
+func ReadFluentdBuffer(dir string) (FluentdBuffer, error) {
+	var buffer FluentdBuffer
+
+	files, err := ioutil.ReadDir(HostPath(dir))
+	if err != nil {
+		return buffer, fmt.Errorf("Could not read the fluentd buffer %s: %v", dir, err)
+	}
+
+	var size int64
+	for _, file := range files {
+		if file.IsDir() || !strings.HasPrefix(file.Name(), "buffer") {
+			continue
+		}
+		size += file.Size()
+		if strings.Contains(file.Name(), ".q") {
+			buffer.Queued++
+			if buffer.Oldest.IsZero() || file.ModTime().Before(buffer.Oldest) {
+				buffer.Oldest = file.ModTime()
+			}
+		}
+	}
+	buffer.SizeMB = size >> 20
+	return buffer, nil
+}
+
+// CheckFluentdBufferSize fails if the buffers hold maxMB or more.
+func CheckFluentdBufferSize(buffer FluentdBuffer, maxMB int) error {
+	if buffer.SizeMB >= int64(maxMB) {
+		return newMessage("fluentd-buffer-full", buffer.SizeMB, buffer.Queued, maxMB)
+	}
+	return nil
+}
+
+// CheckFluentdBufferAge fails if the oldest queued chunk waits for maxAge
+// or longer.
+func CheckFluentdBufferAge(buffer FluentdBuffer, maxAge time.Duration) error {
+	if buffer.Oldest.IsZero() {
+		return nil
+	}
+	if age := time.Since(buffer.Oldest); age >= maxAge {
+		return newMessage("fluentd-buffer-stuck", age-age%time.Second, maxAge)
+	}
+	return nil
+}
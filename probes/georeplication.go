@@ -0,0 +1,109 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package probes
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// GeoReplicationSession is a brick of a geo-replication session as listed by
+// gluster volume geo-replication status.
+type GeoReplicationSession struct {
+	Volume string
+	Brick  string
+	Slave  string
+	// Active, Passive, Faulty, Created, Stopped, ...
+	Status     string
+	LastSynced time.Time
+}
+
+// GeoReplicationSessions returns the bricks of all geo-replication sessions.
+func GeoReplicationSessions(ctx context.Context) ([]GeoReplicationSession, error) {
+	cmd := command(ctx, "gluster", "volume", "geo-replication", "status")
+	start := time.Now()
+	out, err := cmd.Output()
+	TraceCommand(cmd, out, nil, err, time.Since(start))
+	if err != nil {
+		return nil, fmt.Errorf("gluster volume geo-replication status failed: %v", err)
+	}
+
+	return parseGeoReplicationStatus(string(out)), nil
+}
+
+// the columns are MASTER NODE, MASTER VOL, MASTER BRICK, SLAVE USER, SLAVE,
+// SLAVE NODE, STATUS, CRAWL STATUS and LAST_SYNCED, the last two can
+// contain spaces
+func parseGeoReplicationStatus(output string) []GeoReplicationSession {
+	var sessions []GeoReplicationSession
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 8 || fields[0] == "MASTER" || strings.HasPrefix(fields[0], "---") {
+			continue
+		}
+
+		session := GeoReplicationSession{Volume: fields[1], Brick: fields[0] + ":" + fields[2], Slave: fields[4], Status: fields[6]}
+		if len(fields) >= 10 {
+			synced, err := time.ParseInLocation("2006-01-02 15:04:05", strings.Join(fields[len(fields)-2:], " "), time.Local)
+			if err == nil {
+				session.LastSynced = synced
+			}
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions
+}
+
+// CheckGeoReplication fails if a brick of a session of the volume is faulty
+// or if an active brick did not sync within maxLag. Faulty bricks are only
+// reported if faulty is set.
+func CheckGeoReplication(sessions []GeoReplicationSession, volume string, maxLag time.Duration, faulty bool) error {
+	var failures []string
+	for _, s := range sessions {
+		if s.Volume != volume {
+			continue
+		}
+		switch {
+		case s.Status == "Faulty":
+			if faulty {
+				failures = append(failures, fmt.Sprintf("%s to %s is faulty", s.Brick, s.Slave))
+			}
+		case s.Status != "Active":
+			// passive bricks don't sync themselves
+		case s.LastSynced.IsZero():
+			failures = append(failures, fmt.Sprintf("%s to %s never synced", s.Brick, s.Slave))
+		case time.Since(s.LastSynced) > maxLag:
+			failures = append(failures, fmt.Sprintf("%s to %s last synced %s ago", s.Brick, s.Slave, time.Since(s.LastSynced)/time.Minute*time.Minute))
+		}
+	}
+
+	if len(failures) > 0 {
+		return newMessage("geo-replication-degraded", volume, strings.Join(failures, ", "))
+	}
+	return nil
+}
+
+// GeoReplicationVolumes returns the volumes with geo-replication sessions.
+func GeoReplicationVolumes(sessions []GeoReplicationSession) []string {
+	var volumes []string
+	for _, s := range sessions {
+		if !contains(volumes, s.Volume) {
+			volumes = append(volumes, s.Volume)
+		}
+	}
+	return volumes
+}
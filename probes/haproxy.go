@@ -0,0 +1,151 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package probes
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HaproxyStats are the process info and the server states read from the
+// stats socket of haproxy.
+type HaproxyStats struct {
+	CurrConns int
+	Maxconn   int
+	// the states of the servers by backend, e.g. UP, DOWN or MAINT
+	Servers map[string]map[string]string
+}
+
+// ReadHaproxyStats reads the process info and the server states from the
+// stats socket, which fails if haproxy is not running.
+func ReadHaproxyStats(ctx context.Context, socket string) (HaproxyStats, error) {
+	log.Info("Reading haproxy stats from", socket)
+
+	var stats = HaproxyStats{Servers: map[string]map[string]string{}}
+
+	info, err := haproxyCommand(ctx, HostPath(socket), "show info")
+	if err != nil {
+		return stats, err
+	}
+	for _, line := range strings.Split(info, "\n") {
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		value, _ := strconv.Atoi(strings.TrimSpace(parts[1]))
+		switch parts[0] {
+		case "CurrConns":
+			stats.CurrConns = value
+		case "Maxconn":
+			stats.Maxconn = value
+		}
+	}
+
+	stat, err := haproxyCommand(ctx, HostPath(socket), "show stat")
+	if err != nil {
+		return stats, err
+	}
+	records, err := csv.NewReader(strings.NewReader(strings.TrimPrefix(stat, "# "))).ReadAll()
+	if err != nil || len(records) == 0 {
+		return stats, fmt.Errorf("Could not parse the haproxy stats: %v", err)
+	}
+	columns := map[string]int{}
+	for i, name := range records[0] {
+		columns[name] = i
+	}
+	for _, record := range records[1:] {
+		if len(record) <= columns["status"] {
+			continue
+		}
+		backend, server := record[columns["pxname"]], record[columns["svname"]]
+		if server == "FRONTEND" || server == "BACKEND" {
+			continue
+		}
+		if stats.Servers[backend] == nil {
+			stats.Servers[backend] = map[string]string{}
+		}
+		stats.Servers[backend][server] = record[columns["status"]]
+	}
+	return stats, nil
+}
+
+// sends a command to the stats socket and returns the answer
+func haproxyCommand(ctx context.Context, socket string, command string) (string, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "unix", socket)
+	if err != nil {
+		return "", newMessage("haproxy-not-running", socket, err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+
+	if _, err := conn.Write([]byte(command + "\n")); err != nil {
+		return "", fmt.Errorf("Could not send %s to haproxy: %v", command, err)
+	}
+	out, err := ioutil.ReadAll(conn)
+	if err != nil {
+		return "", fmt.Errorf("Could not read the answer of haproxy to %s: %v", command, err)
+	}
+	return string(out), nil
+}
+
+// CheckHaproxySessions fails if the current connections reach percent of
+// maxconn.
+func CheckHaproxySessions(stats HaproxyStats, percent int) error {
+	if stats.Maxconn == 0 {
+		return nil
+	}
+	if used := stats.CurrConns * 100 / stats.Maxconn; used >= percent {
+		return newMessage("haproxy-sessions-high", stats.CurrConns, stats.Maxconn, used, percent)
+	}
+	return nil
+}
+
+// CheckHaproxyBackends fails if a backend has no server up or, unless
+// allDown is set, if any server is not up.
+func CheckHaproxyBackends(stats HaproxyStats, allDown bool) error {
+	var failures []string
+	for backend, servers := range stats.Servers {
+		var down []string
+		for server, status := range servers {
+			// e.g. "UP 1/3" while a server comes up
+			if !strings.HasPrefix(status, "UP") && status != "no check" {
+				down = append(down, server+" "+status)
+			}
+		}
+		sort.Strings(down)
+
+		switch {
+		case len(down) == 0:
+		case len(down) == len(servers):
+			failures = append(failures, fmt.Sprintf("%s has no server up (%s)", backend, strings.Join(down, ", ")))
+		case !allDown:
+			failures = append(failures, fmt.Sprintf("%s has servers down (%s)", backend, strings.Join(down, ", ")))
+		}
+	}
+	sort.Strings(failures)
+
+	if len(failures) > 0 {
+		return newMessage("haproxy-backends-degraded", strings.Join(failures, "; "))
+	}
+	return nil
+}
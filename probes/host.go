@@ -0,0 +1,105 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package probes
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// the commands run on the host in the containerized mode, the checks of the
+// library run some of them themselves
+var hostCommands = []string{"oc", "etcdctl", "gluster", "nslookup", "ntpq", "lvs", "vgs", "df", "rpm", "systemctl", "ps", "smartctl", "journalctl", "docker"}
+
+const (
+	hostRootEnv    = "OPENSHIFT_MONITORING_CLI_HOST_ROOT"
+	hostPathEnv    = "OPENSHIFT_MONITORING_CLI_HOST_PATH_"
+	hostSearchPath = "/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin"
+)
+
+// where the host filesystem is mounted, see SetHostRoot
+var hostRoot = "/"
+
+// SetHostRoot sets where the filesystem of the host is mounted, "/" if
+// empty. The checked files like certificates, kubeconfigs, audit logs and
+// backups are read below it.
+func SetHostRoot(root string) {
+	if len(root) == 0 {
+		root = "/"
+	}
+	hostRoot = root
+}
+
+// HostPath returns the path of a file of the host below the host root.
+func HostPath(path string) string {
+	if hostRoot == "/" || !filepath.IsAbs(path) || strings.HasPrefix(path, hostRoot+"/") {
+		return path
+	}
+	return filepath.Join(hostRoot, path)
+}
+
+// SetContainerized runs the host commands on the host mounted at hostRoot.
+// Every host command is linked to this executable in a directory put in
+// front of the PATH, so also the checks of the library run them through
+// RunHostCommand. Configured command paths are paths on the host.
+func SetContainerized(root string) error {
+	if err := containerizedSupported(); err != nil {
+		return err
+	}
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("Could not find the executable for the host commands: %v", err)
+	}
+
+	dir := filepath.Join(os.TempDir(), "openshift-monitoring-cli-host")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("Could not create the directory of the host commands: %v", err)
+	}
+	for _, name := range hostCommands {
+		link := filepath.Join(dir, name)
+		os.Remove(link)
+		if err := os.Symlink(executable, link); err != nil {
+			return fmt.Errorf("Could not link the host command %s: %v", name, err)
+		}
+		if path, ok := commandPaths[name]; ok {
+			os.Setenv(hostPathEnv+strings.ToUpper(name), path)
+			delete(commandPaths, name)
+		}
+	}
+
+	os.Setenv(hostRootEnv, root)
+	if !contains(filepath.SplitList(os.Getenv("PATH")), dir) {
+		os.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	}
+	log.Info("Running the host commands in", root)
+	return nil
+}
+
+// returns if a host command exists below the host root, links are not
+// followed as they point into the host
+func hostCommandExists(root string, name string) bool {
+	if path := os.Getenv(hostPathEnv + strings.ToUpper(name)); len(path) > 0 {
+		_, err := os.Lstat(filepath.Join(root, path))
+		return err == nil
+	}
+	for _, dir := range filepath.SplitList(hostSearchPath) {
+		if _, err := os.Lstat(filepath.Join(root, dir, name)); err == nil {
+			return true
+		}
+	}
+	return false
+}
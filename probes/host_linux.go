@@ -0,0 +1,62 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package probes
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+func containerizedSupported() error {
+	return nil
+}
+
+// RunHostCommand replaces the process with the host command it was called
+// as in the containerized mode and returns otherwise. Call it before
+// anything else.
+func RunHostCommand() {
+	name := filepath.Base(os.Args[0])
+	root := os.Getenv(hostRootEnv)
+	if len(root) == 0 || !contains(hostCommands, name) {
+		return
+	}
+
+	if err := syscall.Chroot(root); err != nil {
+		fmt.Fprintf(os.Stderr, "Could not change the root to %s: %v\n", root, err)
+		os.Exit(127)
+	}
+	os.Chdir("/")
+
+	// the PATH of the container is meaningless on the host
+	os.Setenv("PATH", hostSearchPath)
+	os.Unsetenv(hostRootEnv)
+
+	path := os.Getenv(hostPathEnv + strings.ToUpper(name))
+	if len(path) == 0 {
+		var err error
+		if path, err = exec.LookPath(name); err != nil {
+			fmt.Fprintf(os.Stderr, "%s not found on the host: %v\n", name, err)
+			os.Exit(127)
+		}
+	}
+
+	err := syscall.Exec(path, os.Args, os.Environ())
+	fmt.Fprintf(os.Stderr, "Could not run %s on the host: %v\n", path, err)
+	os.Exit(126)
+}
@@ -0,0 +1,31 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !linux
+// +build !linux
+
+package probes
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// the host commands are run with chroot, which only linux supports
+func containerizedSupported() error {
+	return fmt.Errorf("The containerized mode is not supported on %s", runtime.GOOS)
+}
+
+// RunHostCommand returns right away, SetContainerized fails on this platform
+func RunHostCommand() {}
@@ -0,0 +1,120 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package probes
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// IngestionLag returns how far the newest document of hostname indexed by
+// the logging elasticsearch is behind the newest entry of the local journal.
+func IngestionLag(ctx context.Context, esUrl string, token string, index string, hostname string, insecure bool) (time.Duration, error) {
+	journal, err := lastJournalEntry(ctx)
+	if err != nil {
+		return 0, err
+	}
+	indexed, err := lastIndexed(ctx, esUrl, token, index, hostname, insecure)
+	if err != nil {
+		return 0, err
+	}
+	log.Debug("Newest journal entry", journal, "newest indexed", indexed)
+
+	if lag := journal.Sub(indexed); lag > 0 {
+		return lag, nil
+	}
+	return 0, nil
+}
+
+func lastJournalEntry(ctx context.Context) (time.Time, error) {
+	cmd := command(ctx, "journalctl", "-n", "1", "-o", "json", "--no-pager", "-q")
+	start := time.Now()
+	out, err := cmd.Output()
+	TraceCommand(cmd, out, nil, err, time.Since(start))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("journalctl failed: %v", err)
+	}
+
+	var entry struct {
+		Timestamp string `json:"__REALTIME_TIMESTAMP"`
+	}
+	if err := json.Unmarshal(bytes.TrimSpace(out), &entry); err != nil {
+		return time.Time{}, fmt.Errorf("Could not parse the journal entry: %v", err)
+	}
+	micros, err := strconv.ParseInt(entry.Timestamp, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("Could not parse the journal timestamp %q: %v", entry.Timestamp, err)
+	}
+	return time.Unix(0, micros*int64(time.Microsecond)), nil
+}
+
+func lastIndexed(ctx context.Context, esUrl string, token string, index string, hostname string, insecure bool) (time.Time, error) {
+	query, _ := json.Marshal(map[string]interface{}{
+		"size":    1,
+		"_source": []string{"@timestamp"},
+		"sort":    []map[string]string{{"@timestamp": "desc"}},
+		"query":   map[string]interface{}{"term": map[string]string{"hostname": hostname}},
+	})
+	req, err := http.NewRequest("POST", strings.TrimSuffix(esUrl, "/")+"/"+index+"/_search", bytes.NewReader(query))
+	if err != nil {
+		return time.Time{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if len(token) > 0 {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := sharedClient(insecure).Do(req.WithContext(ctx))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("Could not query elasticsearch: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return time.Time{}, fmt.Errorf("Elasticsearch returned status code %d: %s", resp.StatusCode, body)
+	}
+
+	var result struct {
+		Hits struct {
+			Hits []struct {
+				Source struct {
+					Timestamp time.Time `json:"@timestamp"`
+				} `json:"_source"`
+			} `json:"hits"`
+		} `json:"hits"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return time.Time{}, fmt.Errorf("Could not parse the answer of elasticsearch: %v", err)
+	}
+	if len(result.Hits.Hits) == 0 {
+		return time.Time{}, newMessage("ingestion-no-logs", hostname, index)
+	}
+	return result.Hits.Hits[0].Source.Timestamp, nil
+}
+
+// CheckIngestionLag fails if the logs are maxLag or more behind.
+func CheckIngestionLag(lag time.Duration, maxLag time.Duration) error {
+	if lag >= maxLag {
+		return newMessage("ingestion-lagging", lag-lag%time.Second, maxLag)
+	}
+	return nil
+}
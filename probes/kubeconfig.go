@@ -0,0 +1,187 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package probes
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+type kubeconfig struct {
+	Clusters []struct {
+		Name    string `yaml:"name"`
+		Cluster struct {
+			CertificateAuthorityData string `yaml:"certificate-authority-data"`
+		} `yaml:"cluster"`
+	} `yaml:"clusters"`
+	Users []struct {
+		Name string `yaml:"name"`
+		User struct {
+			ClientCertificateData string `yaml:"client-certificate-data"`
+			Token                 string `yaml:"token"`
+		} `yaml:"user"`
+	} `yaml:"users"`
+}
+
+// KubeconfigExpiries reads the expiry of the certificates and tokens
+// embedded in the given kubeconfig files. Files which don't exist are
+// skipped.
+func KubeconfigExpiries(paths []string) ([]Expiry, error) {
+	log.Info("Reading expiry of kubeconfigs", strings.Join(paths, ", "))
+
+	var expiries []Expiry
+	for _, path := range paths {
+		content, err := ioutil.ReadFile(HostPath(path))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Could not read kubeconfig %s: %v", path, err)
+		}
+
+		var config kubeconfig
+		if err := yaml.Unmarshal(content, &config); err != nil {
+			return nil, fmt.Errorf("Could not parse kubeconfig %s: %v", path, err)
+		}
+
+		for _, c := range config.Clusters {
+			if notAfter, ok := certificateExpiry(c.Cluster.CertificateAuthorityData); ok {
+				expiries = append(expiries, Expiry{fmt.Sprintf("%s ca of cluster %s", path, c.Name), notAfter})
+			}
+		}
+
+		for _, u := range config.Users {
+			if notAfter, ok := certificateExpiry(u.User.ClientCertificateData); ok {
+				expiries = append(expiries, Expiry{fmt.Sprintf("%s certificate of user %s", path, u.Name), notAfter})
+			}
+			if notAfter, ok := tokenExpiry(u.User.Token); ok {
+				expiries = append(expiries, Expiry{fmt.Sprintf("%s token of user %s", path, u.Name), notAfter})
+			}
+		}
+	}
+	return expiries, nil
+}
+
+// the secret type of the bootstrap tokens used by nodes to join the cluster
+const bootstrapTokenSecretType = "bootstrap.kubernetes.io/token"
+
+// BootstrapTokenExpiries reads the expiry of the bootstrap token secrets in
+// kube-system. Tokens without expiration never expire.
+func BootstrapTokenExpiries(ctx context.Context) ([]Expiry, error) {
+	log.Info("Reading expiry of bootstrap tokens")
+
+	var secrets struct {
+		Items []struct {
+			Metadata struct {
+				Name string `json:"name"`
+			} `json:"metadata"`
+			Type string            `json:"type"`
+			Data map[string]string `json:"data"`
+		} `json:"items"`
+	}
+	if err := getResource(ctx, "/api/v1/namespaces/kube-system/secrets?fieldSelector=type%3D"+strings.Replace(bootstrapTokenSecretType, "/", "%2F", -1),
+		&secrets, "secrets", "-n", "kube-system", "--field-selector", "type="+bootstrapTokenSecretType); err != nil {
+		return nil, fmt.Errorf("Could not read bootstrap tokens: %v", err)
+	}
+
+	var expiries []Expiry
+	for _, s := range secrets.Items {
+		if s.Type != bootstrapTokenSecretType {
+			continue
+		}
+		expiration, err := base64.StdEncoding.DecodeString(s.Data["expiration"])
+		if err != nil || len(expiration) == 0 {
+			continue
+		}
+		notAfter, err := time.Parse(time.RFC3339, string(expiration))
+		if err != nil {
+			log.Warning("Not able to parse the expiration of bootstrap token", s.Metadata.Name, err)
+			continue
+		}
+		expiries = append(expiries, Expiry{fmt.Sprintf("bootstrap token %s", s.Metadata.Name), notAfter})
+	}
+	return expiries, nil
+}
+
+// CheckKubeconfigExpiry fails if any of the kubeconfig credentials expires
+// within days.
+func CheckKubeconfigExpiry(expiries []Expiry, days int) error {
+	if expiring := expiringWithin(expiries, days); len(expiring) > 0 {
+		return newMessage("kubeconfig-expiring", days, strings.Join(expiring, ", "))
+	}
+	return nil
+}
+
+// CheckBootstrapTokenExpiry fails if any of the bootstrap tokens expires
+// within days.
+func CheckBootstrapTokenExpiry(expiries []Expiry, days int) error {
+	if expiring := expiringWithin(expiries, days); len(expiring) > 0 {
+		return newMessage("bootstrap-tokens-expiring", days, strings.Join(expiring, ", "))
+	}
+	return nil
+}
+
+// certificateExpiry returns the expiry of the first certificate in the base64 encoded pem data.
+func certificateExpiry(data string) (time.Time, bool) {
+	raw, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return time.Time{}, false
+	}
+
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	return cert.NotAfter, true
+}
+
+// tokenExpiry returns the exp claim of a jwt token. Tokens without exp
+// claim (like service account tokens) never expire.
+func tokenExpiry(token string) (time.Time, bool) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}, false
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	var claims struct {
+		Exp int64 `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Exp == 0 {
+		return time.Time{}, false
+	}
+
+	return time.Unix(claims.Exp, 0), true
+}
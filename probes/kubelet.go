@@ -0,0 +1,135 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package probes
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// the defaults of the kubelet of openshift 3.x for the arguments missing in
+// the node config
+var defaultKubeletArguments = map[string]string{
+	"image-gc-high-threshold": "85",
+	"image-gc-low-threshold":  "80",
+	"eviction-hard":           "memory.available<100Mi,nodefs.available<10%,nodefs.inodesFree<5%,imagefs.available<15%",
+	"max-pods":                "250",
+	"pods-per-core":           "10",
+}
+
+// the kubeletArguments of a node config, an argument may be given several
+// times
+type kubeletArguments map[string][]string
+
+// reads the kubeletArguments of the node config
+func readKubeletArguments(path string) (kubeletArguments, error) {
+	content, err := ioutil.ReadFile(HostPath(path))
+	if err != nil {
+		return nil, fmt.Errorf("Could not read the node config %s: %v", path, err)
+	}
+	var config struct {
+		KubeletArguments kubeletArguments `yaml:"kubeletArguments"`
+	}
+	if err := yaml.Unmarshal(content, &config); err != nil {
+		return nil, fmt.Errorf("Could not parse the node config %s: %v", path, err)
+	}
+	return config.KubeletArguments, nil
+}
+
+// returns the argument joined by commas or its default
+func (args kubeletArguments) get(name string) string {
+	if values := args[name]; len(values) > 0 {
+		return strings.Join(values, ",")
+	}
+	return defaultKubeletArguments[name]
+}
+
+// returns the argument as a number
+func (args kubeletArguments) getInt(name string) (int, error) {
+	value, err := strconv.Atoi(args.get(name))
+	if err != nil {
+		return 0, fmt.Errorf("Could not parse the kubelet argument %s: %v", name, err)
+	}
+	return value, nil
+}
+
+// PodDensity is the number of pods of the node and the limits of the
+// kubelet for it.
+type PodDensity struct {
+	Pods        int
+	MaxPods     int
+	PodsPerCore int
+	Cores       int
+}
+
+// Limit returns the number of pods the kubelet admits, pods-per-core 0
+// doesn't limit.
+func (d PodDensity) Limit() int {
+	if d.PodsPerCore > 0 && d.PodsPerCore*d.Cores < d.MaxPods {
+		return d.PodsPerCore * d.Cores
+	}
+	return d.MaxPods
+}
+
+// ReadPodDensity counts the pod sandboxes docker runs and reads max-pods
+// and pods-per-core from the node config.
+func ReadPodDensity(ctx context.Context, nodeConfig string) (PodDensity, error) {
+	var d PodDensity
+
+	args, err := readKubeletArguments(nodeConfig)
+	if err != nil {
+		return d, err
+	}
+	if d.MaxPods, err = args.getInt("max-pods"); err != nil {
+		return d, err
+	}
+	if d.PodsPerCore, err = args.getInt("pods-per-core"); err != nil {
+		return d, err
+	}
+	d.Cores = runtime.NumCPU()
+
+	// every pod has one sandbox with the infra container
+	cmd := command(ctx, "docker", "ps", "-q", "--filter", "label=io.kubernetes.docker.type=podsandbox")
+	start := time.Now()
+	out, err := cmd.Output()
+	TraceCommand(cmd, out, nil, err, time.Since(start))
+	if err != nil {
+		return d, fmt.Errorf("docker ps failed: %v", err)
+	}
+	d.Pods = len(strings.Fields(string(out)))
+	return d, nil
+}
+
+// CheckPodDensity fails if the node runs percent or more of the pods the
+// kubelet admits. The scheduler doesn't place pods on a full node, which
+// only shows as pods pending with insufficient pods.
+func CheckPodDensity(d PodDensity, percent int) error {
+	limit := d.Limit()
+	if limit > 0 && d.Pods*100 >= limit*percent {
+		limits := fmt.Sprintf("max-pods %d", d.MaxPods)
+		if d.PodsPerCore > 0 {
+			limits += fmt.Sprintf(", pods-per-core %d on %d cores", d.PodsPerCore, d.Cores)
+		}
+		return newMessage("pod-density-high", d.Pods, limit, limits, percent)
+	}
+	return nil
+}
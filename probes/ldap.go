@@ -0,0 +1,170 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package probes
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/asn1"
+	"fmt"
+	"net"
+	"net/url"
+	"time"
+)
+
+// the annotation oc adm groups sync sets on every synced group
+const ldapSyncTimeAnnotation = "openshift.io/ldap.sync-time"
+
+// CheckLdapSync fails if no group was synced from ldap within maxAge or if
+// the ldap server does not accept the bind credentials. An empty bindDN
+// binds anonymously.
+func CheckLdapSync(ctx context.Context, ldapUrl string, bindDN string, bindPassword string, insecure bool, maxAge time.Duration) error {
+	log.Info("Checking ldap group sync")
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	var groups struct {
+		Items []struct {
+			Metadata struct {
+				Annotations map[string]string `json:"annotations"`
+			} `json:"metadata"`
+		} `json:"items"`
+	}
+	if err := getResource(ctx, "/apis/user.openshift.io/v1/groups", &groups, "groups"); err != nil {
+		return fmt.Errorf("Could not get groups: %v", err)
+	}
+
+	var lastSync time.Time
+	for _, group := range groups.Items {
+		if value, ok := group.Metadata.Annotations[ldapSyncTimeAnnotation]; ok {
+			// the sync writes the time with a numeric zone, e.g. 2017-10-01T12:00:00+0200
+			for _, layout := range []string{"2006-01-02T15:04:05-0700", time.RFC3339} {
+				if t, err := time.Parse(layout, value); err == nil && t.After(lastSync) {
+					lastSync = t
+					break
+				}
+			}
+		}
+	}
+	if lastSync.IsZero() {
+		return newMessage("ldap-never-synced")
+	}
+	if age := time.Since(lastSync); age > maxAge {
+		return newMessage("ldap-sync-too-old", int(age.Hours()), lastSync.Format("2006-01-02 15:04"))
+	}
+
+	if len(ldapUrl) > 0 {
+		if err := ldapBind(ctx, ldapUrl, bindDN, bindPassword, insecure); err != nil {
+			return fmt.Errorf("Ldap bind on %s failed: %v", ldapUrl, err)
+		}
+	}
+
+	return nil
+}
+
+// sends a simple bind request and fails if the result code is not success
+func ldapBind(ctx context.Context, ldapUrl string, bindDN string, bindPassword string, insecure bool) error {
+	u, err := url.Parse(ldapUrl)
+	if err != nil {
+		return err
+	}
+
+	host := u.Host
+	if len(u.Port()) == 0 {
+		if u.Scheme == "ldaps" {
+			host = net.JoinHostPort(u.Hostname(), "636")
+		} else {
+			host = net.JoinHostPort(u.Hostname(), "389")
+		}
+	}
+
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	conn, err := dialer.DialContext(ctx, "tcp", host)
+	if err != nil {
+		return err
+	}
+	if u.Scheme == "ldaps" {
+		conn = tls.Client(conn, &tls.Config{ServerName: u.Hostname(), InsecureSkipVerify: insecure})
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	if _, err := conn.Write(ldapBindRequest(bindDN, bindPassword)); err != nil {
+		return err
+	}
+
+	buf := make([]byte, 4096)
+	n, err := conn.Read(buf)
+	if err != nil {
+		return err
+	}
+
+	code, err := ldapResultCode(buf[:n])
+	if err != nil {
+		return err
+	}
+	if code != 0 {
+		return fmt.Errorf("result code %d", code)
+	}
+	return nil
+}
+
+// encodes a BindRequest with message id 1 and ldap version 3
+func ldapBindRequest(bindDN string, bindPassword string) []byte {
+	version, _ := asn1.Marshal(3)
+	name, _ := asn1.Marshal([]byte(bindDN))
+	password := asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, Bytes: []byte(bindPassword)}
+	simple, _ := asn1.Marshal(password)
+
+	bind, _ := asn1.Marshal(asn1.RawValue{
+		Class:      asn1.ClassApplication,
+		Tag:        0,
+		IsCompound: true,
+		Bytes:      append(append(version, name...), simple...),
+	})
+	messageID, _ := asn1.Marshal(1)
+
+	message, _ := asn1.Marshal(asn1.RawValue{
+		Class:      asn1.ClassUniversal,
+		Tag:        asn1.TagSequence,
+		IsCompound: true,
+		Bytes:      append(messageID, bind...),
+	})
+	return message
+}
+
+// decodes the result code of a BindResponse
+func ldapResultCode(data []byte) (int, error) {
+	var message struct {
+		ID       int
+		Response asn1.RawValue
+	}
+	if _, err := asn1.Unmarshal(data, &message); err != nil {
+		return 0, fmt.Errorf("invalid bind response: %v", err)
+	}
+	if message.Response.Class != asn1.ClassApplication || message.Response.Tag != 1 {
+		return 0, fmt.Errorf("unexpected response tag %d", message.Response.Tag)
+	}
+
+	var code asn1.Enumerated
+	if _, err := asn1.Unmarshal(message.Response.Bytes, &code); err != nil {
+		return 0, fmt.Errorf("invalid bind response: %v", err)
+	}
+	return int(code), nil
+}
@@ -0,0 +1,118 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package probes
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// CheckApiLoadBalancer calls /healthz over the load balancer url of the
+// masters the given number of times and fails if a call failed or a healthy
+// master was never served. The masters are recognized by the certificate
+// they serve, so the load balancer must pass tls through and distribute the
+// connections round robin or at random.
+func CheckApiLoadBalancer(ctx context.Context, lbUrl string, masters []string, requests int) error {
+	log.Info("Checking the load balancer", lbUrl, "of", strings.Join(masters, ", "))
+
+	u, err := url.Parse(lbUrl)
+	if err != nil {
+		return fmt.Errorf("Invalid load balancer url %s: %v", lbUrl, err)
+	}
+	port := u.Port()
+	if len(port) == 0 {
+		port = "443"
+	}
+
+	// the certificates of the healthy masters
+	healthy := map[string]string{}
+	for _, master := range masters {
+		if len(master) == 0 {
+			continue
+		}
+		fingerprint, err := healthzCertificate(ctx, "https://"+net.JoinHostPort(master, port)+"/healthz")
+		if err != nil {
+			log.Info("Master", master, "is not healthy, it should not be served:", err)
+			continue
+		}
+		healthy[fingerprint] = master
+	}
+
+	var failures []string
+	served := map[string]bool{}
+	for i := 0; i < requests; i++ {
+		fingerprint, err := healthzCertificate(ctx, strings.TrimSuffix(lbUrl, "/")+"/healthz")
+		if err != nil {
+			failures = append(failures, err.Error())
+			continue
+		}
+		if master, ok := healthy[fingerprint]; ok {
+			served[master] = true
+		}
+	}
+
+	var missing []string
+	for _, master := range healthy {
+		if !served[master] {
+			missing = append(missing, master)
+		}
+	}
+	sort.Strings(missing)
+
+	switch {
+	case len(failures) > 0:
+		return newMessage("load-balancer-calls-failed", len(failures), requests, lbUrl, failures[0])
+	case len(missing) > 0:
+		return newMessage("load-balancer-masters-missing", lbUrl, strings.Join(missing, ", "), requests)
+	}
+	return nil
+}
+
+// calls a healthz url over a new connection and returns the fingerprint of
+// the certificate served
+func healthzCertificate(ctx context.Context, healthzUrl string) (string, error) {
+	client := &http.Client{
+		Timeout: 10 * time.Second,
+		Transport: TraceTransport(&http.Transport{
+			TLSClientConfig:   &tls.Config{InsecureSkipVerify: true},
+			DisableKeepAlives: true,
+		}),
+	}
+	req, err := http.NewRequest("GET", healthzUrl, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s returned status code %d", healthzUrl, resp.StatusCode)
+	}
+	if resp.TLS == nil || len(resp.TLS.PeerCertificates) == 0 {
+		return "", fmt.Errorf("%s served no certificate", healthzUrl)
+	}
+	return fmt.Sprintf("%x", sha256.Sum256(resp.TLS.PeerCertificates[0].Raw)), nil
+}
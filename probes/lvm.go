@@ -0,0 +1,76 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package probes
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ThinPool is the usage of an LVM thin pool in percent.
+type ThinPool struct {
+	Name     string
+	Data     float64
+	Metadata float64
+}
+
+// ThinPools returns the data and metadata usage of the LVM thin pools.
+func ThinPools(ctx context.Context) ([]ThinPool, error) {
+	cmd := command(ctx, "lvs", "--noheadings", "--separator", ",", "-o", "vg_name,lv_name,lv_attr,data_percent,metadata_percent")
+	start := time.Now()
+	out, err := cmd.Output()
+	TraceCommand(cmd, out, nil, err, time.Since(start))
+	if err != nil {
+		return nil, fmt.Errorf("lvs failed: %v", err)
+	}
+
+	var pools []ThinPool
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Split(strings.TrimSpace(line), ",")
+		// the attributes of thin pools start with t
+		if len(fields) != 5 || !strings.HasPrefix(fields[2], "t") {
+			continue
+		}
+		data, err := strconv.ParseFloat(fields[3], 64)
+		if err != nil {
+			return nil, fmt.Errorf("Could not parse the data usage of %s/%s: %v", fields[0], fields[1], err)
+		}
+		metadata, err := strconv.ParseFloat(fields[4], 64)
+		if err != nil {
+			return nil, fmt.Errorf("Could not parse the metadata usage of %s/%s: %v", fields[0], fields[1], err)
+		}
+		pools = append(pools, ThinPool{Name: fields[0] + "/" + fields[1], Data: data, Metadata: metadata})
+	}
+	return pools, nil
+}
+
+// CheckThinPoolMetadata fails if the metadata of a thin pool is used to
+// percent or more. A thin pool whose metadata is exhausted gets corrupted.
+func CheckThinPoolMetadata(pools []ThinPool, percent int) error {
+	var full []string
+	for _, p := range pools {
+		if p.Metadata >= float64(percent) {
+			full = append(full, fmt.Sprintf("%s (%.1f%%)", p.Name, p.Metadata))
+		}
+	}
+
+	if len(full) > 0 {
+		return newMessage("lv-pool-metadata-full", percent, strings.Join(full, ", "))
+	}
+	return nil
+}
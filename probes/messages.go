@@ -0,0 +1,152 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package probes
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Message is a failure with a stable id of the message catalog, so the
+// consumers of the events can route on the id instead of the wording,
+// which changes between releases or is translated.
+type Message struct {
+	ID   string
+	Text string
+}
+
+func (m *Message) Error() string {
+	return m.Text
+}
+
+// the texts of the messages by id. The arguments are filled in in order,
+// so a translation must keep the verbs of the text.
+var messages = map[string]string{
+	"run-healthy":                    "System healthy, nothing to do.",
+	"run-aborted":                    "Run was aborted by %s, %d check(s) did not finish.",
+	"run-locked":                     "Another run is active, this run was skipped.",
+	"run-locked-by":                  "Run %d is active since %s, this run was skipped.",
+	"run-circuits-open":              "%d check(s) still failing with an open circuit, already reported: %s",
+	"command-missing":                "Check skipped: dependency missing, %s",
+	"backup-not-found":               "No backup found at %s",
+	"backup-too-old":                 "Latest %s backup %s is %dh old, older than %dh",
+	"backup-too-small":               "Latest %s backup %s has only %d bytes, expected at least %d",
+	"backup-too-few-objects":         "Latest %s backup %s contains only %d objects, expected at least %d",
+	"backup-kinds-missing":           "Latest %s backup %s contains no %s",
+	"bootstrap-tokens-expiring":      "Bootstrap tokens expire within %d days: %s",
+	"baseline-drifted":               "Host drifted from the baseline of %s: %s",
+	"canary-pod-not-ready":           "Canary pod %s did not become ready in time",
+	"canary-route-unreachable":       "Canary route %s not reachable over router %s: %v",
+	"cassandra-compactions-pending":  "Cassandra has %d or more pending compactions: %s",
+	"cassandra-heap-exhausted":       "Cassandra uses %d%% or more of its heap: %s",
+	"certificates-expiring":          "Certificates expire within %d days: %s",
+	"cloud-provider-degraded":        "Cloud provider %s is degraded: %v",
+	"component-pods-missing":         "No %s pods found in %s with %s",
+	"component-storage-full":         "Persistent storage of %s is %d%% or more used: %s",
+	"component-pods-restarting":      "Pods of %s are restarting: %s",
+	"diagnostics-finding":            "Diagnostic %s reported %s: %s",
+	"disks-failing":                  "Disks are failing according to smart: %s",
+	"disk-io-errors":                 "Kernel reported %d disk i/o errors since %s, the last: %s",
+	"dns-inconsistent":               "DNS is inconsistent: %s",
+	"dns-srv-missing":                "No srv records for port %s of %s with %d ready endpoints: %v",
+	"dns-srv-inconsistent":           "%s has %d srv records but %d ready endpoints: %s",
+	"etcd-latency-high":              "Latency of etcd %s is above %s: %s",
+	"etcd-members-unhealthy":         "etcd members are not healthy: %s",
+	"etcd-member-lists-inconsistent": "etcd member lists are inconsistent: %s",
+	"fluentd-buffer-full":            "fluentd buffers %dMB in %d queued chunks, should be below %dMB",
+	"fluentd-buffer-stuck":           "fluentd has not sent a chunk queued %s ago, should be below %s",
+	"geo-replication-degraded":       "Geo-replication of volume %s is degraded: %s",
+	"haproxy-not-running":            "haproxy is not running, the stats socket %s does not answer: %v",
+	"haproxy-sessions-high":          "haproxy has %d of maxconn %d connections (%d%%), should be below %d%%",
+	"haproxy-backends-degraded":      "haproxy backends are degraded: %s",
+	"ingestion-no-logs":              "Elasticsearch has no logs of %s in %s",
+	"ingestion-lagging":              "The logs of the node are indexed %s behind the journal, should be below %s",
+	"kubelet-gc-inconsistent":        "Kubelet gc and eviction thresholds are inconsistent: %s",
+	"eviction-imminent":              "Free space is less than %d percentage points above the eviction threshold: %s",
+	"pod-density-high":               "Node runs %d of at most %d pods (%s), %d%% or more",
+	"zombie-processes":               "%d processes are defunct, should be below %d: %s",
+	"runtime-processes-stuck":        "Container runtime processes are stuck for %s or longer: %s",
+	"memory-cgroups-dying":           "%d memory cgroups are dying next to %d live ones, should be below %d",
+	"kubeconfig-expiring":            "Kubeconfig credentials expire within %d days: %s",
+	"ldap-never-synced":              "No group was ever synced from ldap",
+	"ldap-sync-too-old":              "Last ldap group sync was %d hours ago at %s",
+	"load-balancer-calls-failed":     "%d of %d calls to the load balancer %s failed: %s",
+	"load-balancer-masters-missing":  "The load balancer %s did not serve the healthy masters %s in %d calls",
+	"lv-pool-metadata-full":          "Metadata of LVM thin pools is used above %d%%: %s",
+	"nfs-mounts-unusable":            "NFS mounts are not usable: %s",
+	"ntpd-no-peers":                  "ntpd has none of its %d peers reachable",
+	"ntpd-not-synchronized":          "ntpd is not synchronized, %d of %d peers are reachable",
+	"ntpd-stratum-high":              "ntpd stratum is %d (synchronized to %s), should be below %d",
+	"clocks-skewed":                  "Clocks are %s apart (%s is behind, %s ahead), should be within %s",
+	"oauth-unhealthy":                "OAuth server is not healthy: %v",
+	"prometheus-targets-down":        "Prometheus doesn't scrape all targets: %s",
+	"prometheus-rules-failing":       "Prometheus fails to evaluate %d rules: %s",
+	"prometheus-no-alertmanager":     "Prometheus has no active alertmanager, alerts are not sent",
+	"prometheus-alerts-not-sent":     "Prometheus fails to send alerts to %s",
+	"registry-handshake-failed":      "Registry %s does not complete a tls handshake: %v",
+	"registry-ca-untrusted":          "Docker does not trust the certificate of registry %s: %s",
+	"audit-log-missing":              "Audit log %s is missing: %v",
+	"audit-log-stale":                "Audit log %s was not written for %d minutes",
+	"anonymous-access-allowed":       "Anonymous access is allowed on %s",
+	"privileged-sccs-granted":        "Privileged sccs granted outside the allowed namespaces: %s",
+	"cluster-admin-service-accounts": "Service accounts or their groups with cluster-admin: %s",
+	"datastores-full":                "Datastores are used by more than %d%%: %s",
+	"webconsole-unavailable":         "Web console is not available: %s",
+}
+
+// the translations of messages.<id> in the config
+var translations = map[string]string{}
+
+// SetMessages replaces the texts of the message ids, e.g. to translate
+// them. Unknown ids are ignored with a warning.
+func SetMessages(texts map[string]string) {
+	translations = map[string]string{}
+	for id, text := range texts {
+		if _, ok := messages[id]; !ok {
+			log.Warning("Ignoring the text of the unknown message", id)
+			continue
+		}
+		if strings.Count(text, "%") != strings.Count(messages[id], "%") {
+			log.Warning("Ignoring the text of message", id, "as it doesn't take the arguments of", messages[id])
+			continue
+		}
+		translations[id] = text
+	}
+}
+
+// NewMessage returns the message of the catalog with the arguments filled in.
+func NewMessage(id string, args ...interface{}) error {
+	return newMessage(id, args...)
+}
+
+func newMessage(id string, args ...interface{}) error {
+	text, ok := translations[id]
+	if !ok {
+		text = messages[id]
+	}
+	return &Message{ID: id, Text: fmt.Sprintf(text, args...)}
+}
+
+// MessageID returns the id of the message of err, empty if err is not from
+// the catalog.
+func MessageID(err error) string {
+	switch e := err.(type) {
+	case *Message:
+		return e.ID
+	case *MissingCommandError:
+		return "command-missing"
+	}
+	return ""
+}
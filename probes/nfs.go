@@ -0,0 +1,64 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package probes
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// CheckNfsMounts stats every nfs mount and fails if a mount has a stale
+// file handle or doesn't answer within timeout. A stat hanging on a dead
+// server can't be interrupted, it is left behind.
+func CheckNfsMounts(timeout time.Duration) error {
+	table, err := mountTable()
+	if err != nil {
+		return fmt.Errorf("Could not read the mounts: %v", err)
+	}
+
+	var failures []string
+	for _, fields := range table {
+		if !strings.HasPrefix(fields[2], "nfs") {
+			continue
+		}
+		mountPoint := fields[1]
+		log.Debug("Checking nfs mount", mountPoint)
+
+		done := make(chan error, 1)
+		go func() {
+			_, err := os.Stat(HostPath(mountPoint))
+			done <- err
+		}()
+
+		select {
+		case err := <-done:
+			if pathErr, ok := err.(*os.PathError); ok && pathErr.Err == syscall.ESTALE {
+				failures = append(failures, fmt.Sprintf("%s (%s) has a stale file handle", mountPoint, fields[0]))
+			} else if err != nil {
+				failures = append(failures, fmt.Sprintf("%s (%s) failed: %v", mountPoint, fields[0], err))
+			}
+		case <-time.After(timeout):
+			failures = append(failures, fmt.Sprintf("%s (%s) hangs", mountPoint, fields[0]))
+		}
+	}
+
+	if len(failures) > 0 {
+		return newMessage("nfs-mounts-unusable", strings.Join(failures, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,188 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package probes
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// NtpPeer is a peer of ntpd as listed by ntpq -pn.
+type NtpPeer struct {
+	Remote string
+	// the tally code, * for the peer ntpd is synchronized to
+	Tally   string
+	Stratum int
+	// the reachability register, 0 if the last 8 polls failed
+	Reach  int64
+	Offset time.Duration
+}
+
+// NtpPeers returns the peers of the local ntpd. It fails if ntpd doesn't
+// answer, e.g. because it is not running.
+func NtpPeers(ctx context.Context) ([]NtpPeer, error) {
+	cmd := command(ctx, "ntpq", "-pn")
+	start := time.Now()
+	out, err := cmd.Output()
+	TraceCommand(cmd, out, nil, err, time.Since(start))
+	if err != nil {
+		return nil, fmt.Errorf("ntpq -pn failed: %v", err)
+	}
+
+	return parseNtpPeers(string(out))
+}
+
+func parseNtpPeers(output string) ([]NtpPeer, error) {
+	var peers []NtpPeer
+	for _, line := range strings.Split(output, "\n") {
+		if len(line) < 2 || strings.HasPrefix(strings.TrimSpace(line), "remote") || strings.HasPrefix(line, "===") {
+			continue
+		}
+
+		// remote refid st t when poll reach delay offset jitter
+		fields := strings.Fields(line[1:])
+		if len(fields) < 10 {
+			continue
+		}
+		stratum, err := strconv.Atoi(fields[2])
+		if err != nil {
+			return nil, fmt.Errorf("Could not parse the stratum of ntp peer %s: %v", fields[0], err)
+		}
+		reach, err := strconv.ParseInt(fields[6], 8, 64)
+		if err != nil {
+			return nil, fmt.Errorf("Could not parse the reach of ntp peer %s: %v", fields[0], err)
+		}
+		offset, err := strconv.ParseFloat(fields[8], 64)
+		if err != nil {
+			return nil, fmt.Errorf("Could not parse the offset of ntp peer %s: %v", fields[0], err)
+		}
+
+		peers = append(peers, NtpPeer{
+			Remote:  fields[0],
+			Tally:   strings.TrimSpace(line[:1]),
+			Stratum: stratum,
+			Reach:   reach,
+			Offset:  time.Duration(offset * float64(time.Millisecond)),
+		})
+	}
+	return peers, nil
+}
+
+// CheckNtpSync fails if ntpd has no reachable peers, is not synchronized to
+// any of them or if its stratum is not below maxStratum.
+func CheckNtpSync(peers []NtpPeer, maxStratum int) error {
+	var reachable int
+	var synced *NtpPeer
+	for i, p := range peers {
+		if p.Reach != 0 {
+			reachable++
+		}
+		if p.Tally == "*" {
+			synced = &peers[i]
+		}
+	}
+
+	if reachable == 0 {
+		return newMessage("ntpd-no-peers", len(peers))
+	}
+	if synced == nil {
+		return newMessage("ntpd-not-synchronized", reachable, len(peers))
+	}
+	// ntpd is one stratum below its system peer
+	if stratum := synced.Stratum + 1; stratum >= maxStratum {
+		return newMessage("ntpd-stratum-high", stratum, synced.Remote, maxStratum)
+	}
+	return nil
+}
+
+// seconds between the ntp epoch 1900 and the unix epoch
+const ntpEpochOffset = 2208988800
+
+// ClockOffset queries the clock of the host with a single sntp request and
+// returns how far it is ahead of the local clock.
+func ClockOffset(ctx context.Context, host string) (time.Duration, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "udp", net.JoinHostPort(host, "123"))
+	if err != nil {
+		return 0, fmt.Errorf("Could not query the clock of %s: %v", host, err)
+	}
+	defer conn.Close()
+
+	deadline := time.Now().Add(5 * time.Second)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	conn.SetDeadline(deadline)
+
+	// version 3, client mode
+	request := make([]byte, 48)
+	request[0] = 0x1b
+
+	sent := time.Now()
+	if _, err := conn.Write(request); err != nil {
+		return 0, fmt.Errorf("Could not query the clock of %s: %v", host, err)
+	}
+	response := make([]byte, 48)
+	n, err := conn.Read(response)
+	if err == nil && n < len(response) {
+		err = fmt.Errorf("short response of %d bytes", n)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("Could not query the clock of %s: %v", host, err)
+	}
+	received := time.Now()
+
+	if response[0]>>6 == 3 || response[1] == 0 {
+		return 0, fmt.Errorf("The clock of %s is not synchronized", host)
+	}
+
+	// the offset is the mean of the differences on the way there and back
+	serverReceived := ntpTime(response[32:40])
+	serverSent := ntpTime(response[40:48])
+	return (serverReceived.Sub(sent) + serverSent.Sub(received)) / 2, nil
+}
+
+func ntpTime(b []byte) time.Time {
+	seconds := binary.BigEndian.Uint32(b[0:4])
+	fraction := binary.BigEndian.Uint32(b[4:8])
+	nanos := (int64(fraction) * 1e9) >> 32
+	return time.Unix(int64(seconds)-ntpEpochOffset, nanos)
+}
+
+// CheckClockSkew fails if the clocks of the hosts, given as their offsets
+// to the local clock, are more than maxSkew apart.
+func CheckClockSkew(offsets map[string]time.Duration, maxSkew time.Duration) error {
+	// the local clock takes part with offset 0
+	earliest, latest := "the local clock", "the local clock"
+	var min, max time.Duration
+	for host, offset := range offsets {
+		if offset < min {
+			min, earliest = offset, host
+		}
+		if offset > max {
+			max, latest = offset, host
+		}
+	}
+
+	if skew := max - min; skew > maxSkew {
+		return newMessage("clocks-skewed", skew, earliest, latest, maxSkew)
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package probes
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// CheckOAuthLogin checks the health and the metadata of the oauth server
+// of masterUrl. If username is set, it requests a token for the test user
+// like oc login does and deletes the token again.
+func CheckOAuthLogin(ctx context.Context, masterUrl string, username string, password string) error {
+	log.Info("Checking oauth login on", masterUrl)
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	client := &http.Client{
+		Transport: TraceTransport(&http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		}),
+		// the token is returned in the location of the redirect
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	masterUrl = strings.TrimSuffix(masterUrl, "/")
+
+	if err := oauthGet(ctx, client, masterUrl+"/healthz", nil); err != nil {
+		return newMessage("oauth-unhealthy", err)
+	}
+
+	var metadata struct {
+		AuthorizationEndpoint string `json:"authorization_endpoint"`
+		TokenEndpoint         string `json:"token_endpoint"`
+	}
+	if err := oauthGet(ctx, client, masterUrl+"/.well-known/oauth-authorization-server", &metadata); err != nil {
+		return fmt.Errorf("Could not get oauth metadata: %v", err)
+	}
+	if len(metadata.AuthorizationEndpoint) == 0 || len(metadata.TokenEndpoint) == 0 {
+		return errors.New("OAuth metadata contains no authorization or token endpoint")
+	}
+
+	if len(username) == 0 {
+		return nil
+	}
+
+	token, err := requestToken(ctx, client, metadata.AuthorizationEndpoint, username, password)
+	if err != nil {
+		return fmt.Errorf("Login of test user %s failed: %v", username, err)
+	}
+
+	req, err := http.NewRequest("DELETE", masterUrl+"/apis/oauth.openshift.io/v1/oauthaccesstokens/"+token, nil)
+	if err == nil {
+		req.Header.Set("Authorization", "Bearer "+token)
+		if resp, err := client.Do(req.WithContext(ctx)); err != nil {
+			log.Warning("Could not delete the token of the test user", err)
+		} else {
+			resp.Body.Close()
+		}
+	}
+
+	return nil
+}
+
+func oauthGet(ctx context.Context, client *http.Client, url string, v interface{}) error {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned status code %d", url, resp.StatusCode)
+	}
+	if v == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// requests a token with the challenging client over basic auth and returns
+// it from the fragment of the redirect
+func requestToken(ctx context.Context, client *http.Client, authorizeUrl string, username string, password string) (string, error) {
+	req, err := http.NewRequest("GET", authorizeUrl+"?response_type=token&client_id=openshift-challenging-client", nil)
+	if err != nil {
+		return "", err
+	}
+	req.SetBasicAuth(username, password)
+	req.Header.Set("X-CSRF-Token", "1")
+
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusFound {
+		return "", fmt.Errorf("authorize returned status code %d", resp.StatusCode)
+	}
+
+	location, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		return "", err
+	}
+	values, err := url.ParseQuery(location.Fragment)
+	if err != nil {
+		return "", err
+	}
+	if token := values.Get("access_token"); len(token) > 0 {
+		return token, nil
+	}
+	if e := values.Get("error"); len(e) > 0 {
+		return "", fmt.Errorf("%s %s", e, values.Get("error_description"))
+	}
+	return "", errors.New("redirect contains no token")
+}
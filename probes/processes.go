@@ -0,0 +1,129 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package probes
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Process is a process of the host as listed by ps.
+type Process struct {
+	Pid     int
+	Ppid    int
+	State   string
+	Age     time.Duration
+	Command string
+}
+
+// Defunct returns if the process exited and its parent didn't reap it.
+func (p Process) Defunct() bool {
+	return strings.HasPrefix(p.State, "Z")
+}
+
+// returns if the process belongs to the container runtime. runc only runs
+// while a container is created, started or exec'd into, the shims live as
+// long as their container. ps shows the name of a defunct process cut to
+// 15 characters, docker-containerd-shim becomes docker-containe.
+func (p Process) runtime() (runc bool, shim bool) {
+	switch {
+	case p.Command == "runc" || strings.HasPrefix(p.Command, "docker-runc"):
+		return true, false
+	case strings.Contains(p.Command, "containerd-shim"), p.Defunct() && p.Command == "docker-containe":
+		return false, true
+	}
+	return false, false
+}
+
+// ReadProcesses lists the processes of the host with ps.
+func ReadProcesses(ctx context.Context) ([]Process, error) {
+	cmd := command(ctx, "ps", "-e", "-o", "pid=,ppid=,stat=,etimes=,args=")
+	start := time.Now()
+	out, err := cmd.Output()
+	TraceCommand(cmd, out, nil, err, time.Since(start))
+	if err != nil {
+		return nil, fmt.Errorf("ps failed: %v", err)
+	}
+
+	var processes []Process
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 5 {
+			continue
+		}
+		pid, err1 := strconv.Atoi(fields[0])
+		ppid, err2 := strconv.Atoi(fields[1])
+		seconds, err3 := strconv.ParseInt(fields[3], 10, 64)
+		if err1 != nil || err2 != nil || err3 != nil {
+			return nil, fmt.Errorf("unexpected ps output %q", line)
+		}
+		// the args of a defunct process are "[name] <defunct>"
+		name := strings.Trim(filepath.Base(fields[4]), "[]")
+		processes = append(processes, Process{Pid: pid, Ppid: ppid, State: fields[2], Age: time.Duration(seconds) * time.Second, Command: name})
+	}
+	return processes, nil
+}
+
+// CheckZombieProcesses fails if max or more processes are defunct. Zombies
+// pile up when a runtime or an init of a container doesn't reap its
+// children, they keep their pid until the node runs out of pids.
+func CheckZombieProcesses(processes []Process, max int) error {
+	parents := map[int]int{}
+	zombies := 0
+	for _, p := range processes {
+		if p.Defunct() {
+			zombies++
+			parents[p.Ppid]++
+		}
+	}
+
+	if zombies >= max {
+		var top []string
+		for _, p := range processes {
+			if count := parents[p.Pid]; count > 0 {
+				top = append(top, fmt.Sprintf("%s (%d) has %d", p.Command, p.Pid, count))
+			}
+		}
+		return newMessage("zombie-processes", zombies, max, strings.Join(top, ", "))
+	}
+	return nil
+}
+
+// CheckRuntimeProcesses fails if a runc runs or a shim of the container
+// runtime is defunct for maxAge or longer. A hanging runc blocks its
+// container, defunct shims show a runtime losing track of its containers.
+func CheckRuntimeProcesses(processes []Process, maxAge time.Duration) error {
+	var stuck []string
+	for _, p := range processes {
+		runc, shim := p.runtime()
+		if p.Age < maxAge || !runc && !(shim && p.Defunct()) {
+			continue
+		}
+		state := "running"
+		if p.Defunct() {
+			state = "defunct"
+		}
+		stuck = append(stuck, fmt.Sprintf("%s (%d) %s for %s", p.Command, p.Pid, state, p.Age))
+	}
+
+	if len(stuck) > 0 {
+		return newMessage("runtime-processes-stuck", maxAge, strings.Join(stuck, ", "))
+	}
+	return nil
+}
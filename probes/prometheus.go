@@ -0,0 +1,173 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package probes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// Prometheus is the api of a prometheus of the cluster.
+type Prometheus struct {
+	Url      string
+	Token    string
+	Insecure bool
+}
+
+func (p Prometheus) get(ctx context.Context, path string, data interface{}) error {
+	req, err := http.NewRequest("GET", strings.TrimSuffix(p.Url, "/")+path, nil)
+	if err != nil {
+		return err
+	}
+	if len(p.Token) > 0 {
+		req.Header.Set("Authorization", "Bearer "+p.Token)
+	}
+
+	resp, err := sharedClient(p.Insecure).Do(req.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("Could not call prometheus: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("Prometheus returned status code %d for %s", resp.StatusCode, path)
+	}
+
+	var result struct {
+		Status string          `json:"status"`
+		Data   json.RawMessage `json:"data"`
+		Error  string          `json:"error"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return fmt.Errorf("Could not parse the answer of prometheus for %s: %v", path, err)
+	}
+	if result.Status != "success" {
+		return fmt.Errorf("Prometheus failed %s: %s", path, result.Error)
+	}
+	return json.Unmarshal(result.Data, data)
+}
+
+// CheckPrometheusTargets fails if an active scrape target is down or if one
+// of the expected jobs has no targets.
+func CheckPrometheusTargets(ctx context.Context, p Prometheus, jobs []string) error {
+	log.Info("Checking the scrape targets of", p.Url)
+
+	var targets struct {
+		ActiveTargets []struct {
+			ScrapeUrl string            `json:"scrapeUrl"`
+			Labels    map[string]string `json:"labels"`
+			Health    string            `json:"health"`
+			LastError string            `json:"lastError"`
+		} `json:"activeTargets"`
+	}
+	if err := p.get(ctx, "/api/v1/targets", &targets); err != nil {
+		return err
+	}
+
+	var failures []string
+	scraped := map[string]bool{}
+	for _, target := range targets.ActiveTargets {
+		scraped[target.Labels["job"]] = true
+		if target.Health == "down" {
+			failures = append(failures, fmt.Sprintf("%s of %s is down: %s", target.ScrapeUrl, target.Labels["job"], target.LastError))
+		}
+	}
+	for _, job := range jobs {
+		if len(job) > 0 && !scraped[job] {
+			failures = append(failures, fmt.Sprintf("job %s has no targets", job))
+		}
+	}
+
+	if len(failures) > 0 {
+		sort.Strings(failures)
+		return newMessage("prometheus-targets-down", strings.Join(failures, ", "))
+	}
+	return nil
+}
+
+// CheckPrometheusRules fails if the evaluation of a recording or alerting
+// rule failed.
+func CheckPrometheusRules(ctx context.Context, p Prometheus) error {
+	log.Info("Checking the rules of", p.Url)
+
+	var rules struct {
+		Groups []struct {
+			Name  string `json:"name"`
+			Rules []struct {
+				Name      string `json:"name"`
+				Health    string `json:"health"`
+				LastError string `json:"lastError"`
+			} `json:"rules"`
+		} `json:"groups"`
+	}
+	if err := p.get(ctx, "/api/v1/rules", &rules); err != nil {
+		return err
+	}
+
+	var failures []string
+	for _, group := range rules.Groups {
+		for _, rule := range group.Rules {
+			if rule.Health == "err" {
+				failures = append(failures, fmt.Sprintf("%s/%s: %s", group.Name, rule.Name, rule.LastError))
+			}
+		}
+	}
+
+	if len(failures) > 0 {
+		return newMessage("prometheus-rules-failing", len(failures), strings.Join(failures, ", "))
+	}
+	return nil
+}
+
+// CheckPrometheusAlertmanagers fails if prometheus has no alertmanager or
+// fails to send alerts to it.
+func CheckPrometheusAlertmanagers(ctx context.Context, p Prometheus) error {
+	log.Info("Checking the alertmanagers of", p.Url)
+
+	var alertmanagers struct {
+		ActiveAlertmanagers []struct {
+			Url string `json:"url"`
+		} `json:"activeAlertmanagers"`
+	}
+	if err := p.get(ctx, "/api/v1/alertmanagers", &alertmanagers); err != nil {
+		return err
+	}
+	if len(alertmanagers.ActiveAlertmanagers) == 0 {
+		return newMessage("prometheus-no-alertmanager")
+	}
+
+	var query struct {
+		Result []struct {
+			Metric map[string]string `json:"metric"`
+		} `json:"result"`
+	}
+	if err := p.get(ctx, "/api/v1/query?query="+url.QueryEscape("rate(prometheus_notifications_errors_total[5m]) > 0"), &query); err != nil {
+		return err
+	}
+	if len(query.Result) > 0 {
+		var failing []string
+		for _, result := range query.Result {
+			failing = append(failing, result.Metric["alertmanager"])
+		}
+		return newMessage("prometheus-alerts-not-sent", strings.Join(failing, ", "))
+	}
+	return nil
+}
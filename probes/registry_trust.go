@@ -0,0 +1,129 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package probes
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// the bundle update-ca-trust extracts from /etc/pki/ca-trust/source
+const systemTrustBundle = "/etc/pki/ca-trust/extracted/pem/tls-ca-bundle.pem"
+
+// CheckRegistryTrust checks that docker trusts the certificate of the
+// integrated registry for its service name and ip. Docker reads the ca of
+// a registry from the *.crt files of /etc/docker/certs.d/<host:port>,
+// builds and s2i pushes additionally go through the system trust store.
+// After a rotation of the ca pulls fail until the nodes have the new ca.
+func CheckRegistryTrust(ctx context.Context, host, ip string) error {
+	log.Info("Checking the trust of the registry certificate at", host, ip)
+
+	_, port, err := net.SplitHostPort(host)
+	if err != nil {
+		return fmt.Errorf("Registry host %s has no port: %v", host, err)
+	}
+	addrs := []string{host}
+	address := host
+	if len(ip) > 0 {
+		address = net.JoinHostPort(ip, port)
+		addrs = append(addrs, address)
+	}
+
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	if deadline, ok := ctx.Deadline(); ok {
+		dialer.Deadline = deadline
+	}
+	conn, err := tls.DialWithDialer(dialer, "tcp", address, &tls.Config{InsecureSkipVerify: true})
+	if err != nil {
+		return newMessage("registry-handshake-failed", address, err)
+	}
+	chain := conn.ConnectionState().PeerCertificates
+	conn.Close()
+	if len(chain) == 0 {
+		return newMessage("registry-handshake-failed", address, "no certificate presented")
+	}
+	intermediates := x509.NewCertPool()
+	for _, cert := range chain[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	var problems []string
+	for _, addr := range addrs {
+		dir := filepath.Join("/etc/docker/certs.d", addr)
+		roots, err := readCaFiles(filepath.Join(dir, "*.crt"))
+		if err != nil {
+			problems = append(problems, err.Error())
+			continue
+		}
+		if roots == nil {
+			problems = append(problems, fmt.Sprintf("%s has no ca", dir))
+			continue
+		}
+		name, _, _ := net.SplitHostPort(addr)
+		if _, err := chain[0].Verify(x509.VerifyOptions{DNSName: name, Roots: roots, Intermediates: intermediates}); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", dir, err))
+		}
+	}
+
+	roots, err := readCaFiles(systemTrustBundle)
+	if err != nil {
+		problems = append(problems, err.Error())
+	} else if roots == nil {
+		problems = append(problems, fmt.Sprintf("%s has no ca", systemTrustBundle))
+	} else if _, err := chain[0].Verify(x509.VerifyOptions{Roots: roots, Intermediates: intermediates}); err != nil {
+		problems = append(problems, fmt.Sprintf("%s: %v", systemTrustBundle, err))
+	}
+
+	if len(problems) > 0 {
+		return newMessage("registry-ca-untrusted", host, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// reads the pem certificates of the files matching the glob pattern into a
+// pool, returns nil if there are none
+func readCaFiles(pattern string) (*x509.CertPool, error) {
+	files, _ := filepath.Glob(HostPath(pattern))
+
+	var pool *x509.CertPool
+	for _, file := range files {
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("Could not read ca %s: %v", file, err)
+		}
+		for block, rest := pem.Decode(content); block != nil; block, rest = pem.Decode(rest) {
+			if block.Type != "CERTIFICATE" {
+				continue
+			}
+			cert, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("Could not parse ca %s: %v", file, err)
+			}
+			if pool == nil {
+				pool = x509.NewCertPool()
+			}
+			pool.AddCert(cert)
+		}
+	}
+	return pool, nil
+}
@@ -0,0 +1,191 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package probes
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// CheckAuditLogGrowing checks that the api audit log exists and was written
+// to within maxAge. A stale audit log usually means auditing was disabled.
+func CheckAuditLogGrowing(path string, maxAge time.Duration) error {
+	log.Info("Checking audit log", path)
+
+	info, err := os.Stat(HostPath(path))
+	if err != nil {
+		return newMessage("audit-log-missing", path, err)
+	}
+
+	if age := time.Since(info.ModTime()); age > maxAge {
+		return newMessage("audit-log-stale", path, int(age.Minutes()))
+	}
+
+	return nil
+}
+
+// CheckAnonymousAccess calls the given paths of the api without credentials
+// and fails if any of them is not rejected with 401 or 403.
+func CheckAnonymousAccess(ctx context.Context, apiUrl string, paths []string) error {
+	log.Info("Checking anonymous access to", apiUrl)
+
+	client := &http.Client{
+		Timeout: 10 * time.Second,
+		Transport: TraceTransport(&http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		}),
+	}
+
+	var allowed []string
+	for _, path := range paths {
+		req, err := http.NewRequest("GET", strings.TrimSuffix(apiUrl, "/")+path, nil)
+		if err != nil {
+			return err
+		}
+
+		resp, err := client.Do(req.WithContext(ctx))
+		if err != nil {
+			return fmt.Errorf("Could not call api %s: %v", apiUrl, err)
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusUnauthorized && resp.StatusCode != http.StatusForbidden {
+			allowed = append(allowed, fmt.Sprintf("%s (%d)", path, resp.StatusCode))
+		}
+	}
+
+	if len(allowed) > 0 {
+		return newMessage("anonymous-access-allowed", strings.Join(allowed, ", "))
+	}
+
+	return nil
+}
+
+// the sccs which allow to escape the restricted defaults
+var privilegedSccs = []string{"anyuid", "privileged"}
+
+// the groups which contain all service accounts or all users of the cluster
+var clusterWideGroups = []string{"system:serviceaccounts", "system:authenticated", "system:unauthenticated"}
+
+// CheckPrivilegedSccs fails if a service account or the service account
+// group of a namespace which is not in allowedNamespaces is granted one of
+// the privileged sccs. A grant to a cluster wide group always fails unless
+// the group itself is in allowedNamespaces.
+func CheckPrivilegedSccs(ctx context.Context, allowedNamespaces []string) error {
+	log.Info("Checking usage of privileged sccs")
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	var findings []string
+	for _, name := range privilegedSccs {
+		var scc struct {
+			Users  []string `json:"users"`
+			Groups []string `json:"groups"`
+		}
+		if err := getResource(ctx, "/apis/security.openshift.io/v1/securitycontextconstraints/"+name, &scc, "scc", name); err != nil {
+			return fmt.Errorf("Could not get scc %s: %v", name, err)
+		}
+
+		for _, subject := range append(scc.Users, scc.Groups...) {
+			if contains(allowedNamespaces, subject) {
+				continue
+			}
+			ns := serviceAccountNamespace(subject)
+			if contains(clusterWideGroups, subject) || len(ns) > 0 && !contains(allowedNamespaces, ns) {
+				findings = append(findings, fmt.Sprintf("%s (%s)", subject, name))
+			}
+		}
+	}
+
+	if len(findings) > 0 {
+		return newMessage("privileged-sccs-granted", strings.Join(findings, ", "))
+	}
+
+	return nil
+}
+
+// CheckClusterAdminServiceAccounts fails if a service account which is not
+// in allowed (as namespace:name) is bound to the cluster-admin role. The
+// service account group of a namespace and the cluster wide groups fail
+// unless the group is in allowed.
+func CheckClusterAdminServiceAccounts(ctx context.Context, allowed []string) error {
+	log.Info("Checking service accounts with cluster-admin")
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	var bindings struct {
+		Items []struct {
+			RoleRef struct {
+				Name string `json:"name"`
+			} `json:"roleRef"`
+			Subjects []struct {
+				Kind      string `json:"kind"`
+				Namespace string `json:"namespace"`
+				Name      string `json:"name"`
+			} `json:"subjects"`
+		} `json:"items"`
+	}
+	if err := getResource(ctx, "/apis/rbac.authorization.k8s.io/v1/clusterrolebindings", &bindings, "clusterrolebindings"); err != nil {
+		return fmt.Errorf("Could not get clusterrolebindings: %v", err)
+	}
+
+	var findings []string
+	for _, binding := range bindings.Items {
+		if binding.RoleRef.Name != "cluster-admin" {
+			continue
+		}
+		for _, subject := range binding.Subjects {
+			switch {
+			case subject.Kind == "ServiceAccount":
+				if sa := subject.Namespace + ":" + subject.Name; !contains(allowed, sa) {
+					findings = append(findings, sa)
+				}
+			case subject.Kind == "User" && strings.HasPrefix(subject.Name, "system:serviceaccount:"):
+				// system:serviceaccount:ns:name
+				if sa := strings.TrimPrefix(subject.Name, "system:serviceaccount:"); !contains(allowed, sa) {
+					findings = append(findings, sa)
+				}
+			case subject.Kind == "Group" && (contains(clusterWideGroups, subject.Name) || len(serviceAccountNamespace(subject.Name)) > 0):
+				if !contains(allowed, subject.Name) {
+					findings = append(findings, subject.Name)
+				}
+			}
+		}
+	}
+
+	if len(findings) > 0 {
+		return newMessage("cluster-admin-service-accounts", strings.Join(findings, ", "))
+	}
+
+	return nil
+}
+
+// serviceAccountNamespace returns the namespace of a service account user
+// (system:serviceaccount:ns:name) or group (system:serviceaccounts:ns).
+func serviceAccountNamespace(subject string) string {
+	parts := strings.Split(subject, ":")
+	if len(parts) >= 3 && parts[0] == "system" &&
+		(parts[1] == "serviceaccount" || parts[1] == "serviceaccounts") {
+		return parts[2]
+	}
+	return ""
+}
@@ -0,0 +1,260 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package probes
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/op/go-logging"
+)
+
+// the trace module logs every command and http request on debug level, it
+// is only enabled with the log level trace
+var traceLog = logging.MustGetLogger("trace")
+
+// TraceEntry is a command or http request recorded while tracing.
+type TraceEntry struct {
+	Time     time.Time     `json:"time"`
+	Check    string        `json:"check,omitempty"`
+	Kind     string        `json:"kind"`
+	Duration time.Duration `json:"duration"`
+	Error    string        `json:"error,omitempty"`
+
+	Args     []string `json:"args,omitempty"`
+	ExitCode int      `json:"exitCode"`
+	Stdout   string   `json:"stdout,omitempty"`
+	Stderr   string   `json:"stderr,omitempty"`
+
+	Method       string      `json:"method,omitempty"`
+	Url          string      `json:"url,omitempty"`
+	Host         string      `json:"host,omitempty"`
+	RequestBody  string      `json:"requestBody,omitempty"`
+	Status       int         `json:"status,omitempty"`
+	Header       http.Header `json:"header,omitempty"`
+	ResponseBody string      `json:"responseBody,omitempty"`
+}
+
+// the headers which carry credentials, like the location of the oauth
+// redirect with the access token. They are recorded as redacted, so a trace
+// can be shared.
+var credentialHeaders = []string{"Authorization", "Proxy-Authorization", "Location", "Set-Cookie", "Www-Authenticate", "X-Consul-Token"}
+
+const redacted = "<redacted>"
+
+var traceLock sync.Mutex
+var tracing bool
+var traceCheck string
+var traceEntries []TraceEntry
+
+// StartTrace records all following commands and http requests.
+func StartTrace() {
+	traceLock.Lock()
+	defer traceLock.Unlock()
+	tracing = true
+}
+
+// SetTraceCheck sets the check the following entries are recorded for.
+func SetTraceCheck(name string) {
+	traceLock.Lock()
+	defer traceLock.Unlock()
+	traceCheck = name
+}
+
+// TraceEntries returns the entries recorded since StartTrace.
+func TraceEntries() []TraceEntry {
+	traceLock.Lock()
+	defer traceLock.Unlock()
+	return append([]TraceEntry(nil), traceEntries...)
+}
+
+func recordTrace(entry TraceEntry) {
+	traceLock.Lock()
+	defer traceLock.Unlock()
+	if tracing {
+		entry.Check = traceCheck
+		traceEntries = append(traceEntries, entry)
+	}
+}
+
+// TraceCommand logs a finished command with its arguments and exit status
+// and records it with its output if tracing was started.
+func TraceCommand(cmd *exec.Cmd, stdout []byte, stderr []byte, err error, took time.Duration) {
+	status := "exit 0"
+	if err != nil {
+		status = err.Error()
+	}
+	traceLog.Debugf("exec %s: %s (%s)", strings.Join(cmd.Args, " "), status, took)
+
+	var entry = TraceEntry{
+		Time:     time.Now().Add(-took),
+		Kind:     "exec",
+		Duration: took,
+		Args:     cmd.Args,
+		Stdout:   string(stdout),
+		Stderr:   string(stderr),
+	}
+	if readsSecrets(cmd.Args) && len(stdout) > 0 {
+		entry.Stdout = redacted
+	}
+	if err != nil {
+		entry.Error = err.Error()
+		entry.ExitCode = -1
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			if ws, ok := exitErr.Sys().(syscall.WaitStatus); ok {
+				entry.ExitCode = ws.ExitStatus()
+			}
+		}
+	}
+	recordTrace(entry)
+}
+
+// TraceTransport wraps next (or the default transport if nil) and logs every
+// request with its response status. If tracing was started the request and
+// response bodies are recorded as well, except for the reads of secrets and
+// oauth tokens. Headers with credentials are redacted.
+func TraceTransport(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &traceTransport{next: next}
+}
+
+// SensitiveTraceTransport wraps next like TraceTransport but never records
+// the bodies, for requests which send or return credentials like a config.
+func SensitiveTraceTransport(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &traceTransport{next: next, sensitive: true}
+}
+
+type traceTransport struct {
+	next      http.RoundTripper
+	sensitive bool
+}
+
+func (t *traceTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	location := redactURL(req.URL)
+	var entry = TraceEntry{Time: time.Now(), Kind: "http", Method: req.Method, Url: location, Host: req.Host}
+
+	traceLock.Lock()
+	record := tracing
+	traceLock.Unlock()
+	bodies := record && !t.sensitive && !readsSecretsOver(req.URL)
+
+	if bodies && req.Body != nil {
+		body, err := ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		entry.RequestBody = string(body)
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	entry.Duration = time.Since(entry.Time)
+
+	if err != nil {
+		traceLog.Debugf("http %s %s (host %s): %v (%s)", req.Method, location, req.Host, err, entry.Duration)
+		entry.Error = err.Error()
+	} else {
+		traceLog.Debugf("http %s %s (host %s): %s (%s)", req.Method, location, req.Host, resp.Status, entry.Duration)
+		entry.Status = resp.StatusCode
+		entry.Header = redactHeader(resp.Header)
+
+		if bodies {
+			body, readErr := ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+			entry.ResponseBody = string(body)
+			if readErr != nil {
+				entry.Error = readErr.Error()
+			}
+			resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+		}
+	}
+
+	if record {
+		recordTrace(entry)
+	}
+	return resp, err
+}
+
+// returns a copy of header with the values of credentialHeaders redacted
+func redactHeader(header http.Header) http.Header {
+	redactedHeader := http.Header{}
+	for name, values := range header {
+		redactedHeader[name] = values
+	}
+	for _, name := range credentialHeaders {
+		if len(redactedHeader.Get(name)) > 0 {
+			redactedHeader.Set(name, redacted)
+		}
+	}
+	return redactedHeader
+}
+
+// returns the url without the oauth tokens in its path, query or fragment
+func redactURL(u *url.URL) string {
+	redactedURL := *u
+	if i := strings.Index(redactedURL.Path, "/oauthaccesstokens/"); i >= 0 {
+		redactedURL.Path = redactedURL.Path[:i] + "/oauthaccesstokens/" + redacted
+		redactedURL.RawPath = ""
+	}
+	if len(redactedURL.RawQuery) > 0 || len(redactedURL.Fragment) > 0 {
+		query := redactedURL.Query()
+		for _, name := range []string{"access_token", "token", "code"} {
+			if len(query.Get(name)) > 0 {
+				query.Set(name, redacted)
+			}
+		}
+		redactedURL.RawQuery = query.Encode()
+		redactedURL.Fragment = ""
+	}
+	return redactedURL.String()
+}
+
+// reports if the api request reads secrets or oauth tokens
+func readsSecretsOver(u *url.URL) bool {
+	return strings.Contains(u.Path, "/secrets") || strings.Contains(u.Path, "/oauthaccesstokens") || strings.HasPrefix(u.Path, "/oauth/")
+}
+
+// reports if the command prints secrets or tokens, like oc get secrets or
+// oc whoami -t
+func readsSecrets(args []string) bool {
+	whoami := false
+	for _, arg := range args {
+		arg = strings.ToLower(arg)
+		if arg == "secret" || arg == "secrets" || strings.HasPrefix(arg, "secret/") || strings.HasPrefix(arg, "secrets/") ||
+			arg == "get-token" || arg == "new-token" {
+			return true
+		}
+		if arg == "whoami" {
+			whoami = true
+		}
+		if whoami && (arg == "-t" || arg == "--show-token") {
+			return true
+		}
+	}
+	return false
+}
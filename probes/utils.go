@@ -0,0 +1,80 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package probes contains checks which are specific to this cli and not
+// (yet) part of github.com/oscp/openshift-monitoring-checks.
+package probes
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/op/go-logging"
+)
+
+var log = logging.MustGetLogger("probes")
+
+// oc commands which change the cluster and are refused in the read-only mode
+var mutatingOcCommands = []string{"create", "apply", "delete", "patch", "replace", "edit", "label", "annotate",
+	"expose", "new-app", "run", "scale", "exec", "rsh", "rsync", "cp"}
+
+var readOnly bool
+
+// SetReadOnly refuses oc commands which change the cluster, so a check
+// which doesn't declare its capabilities still can't create resources.
+func SetReadOnly(enabled bool) {
+	readOnly = enabled
+}
+
+// runOc executes oc with the given arguments and returns its trimmed stdout.
+// If stdin is not empty it is passed to the command.
+func runOc(ctx context.Context, stdin string, args ...string) (string, error) {
+	if readOnly && len(args) > 0 && contains(mutatingOcCommands, args[0]) {
+		return "", fmt.Errorf("oc %s is not allowed in read-only mode", args[0])
+	}
+
+	cmd := exec.CommandContext(ctx, CommandPath("oc"), args...)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if len(stdin) > 0 {
+		cmd.Stdin = strings.NewReader(stdin)
+	}
+
+	log.Debug("Running oc", strings.Join(args, " "))
+
+	start := time.Now()
+	err := cmd.Run()
+	TraceCommand(cmd, stdout.Bytes(), stderr.Bytes(), err, time.Since(start))
+
+	if err != nil {
+		return "", fmt.Errorf("oc %s failed: %v %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+func contains(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,123 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package probes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Datastore is the capacity of a vSphere datastore in bytes.
+type Datastore struct {
+	Name      string `json:"name"`
+	Capacity  int64  `json:"capacity"`
+	FreeSpace int64  `json:"free_space"`
+}
+
+// VsphereDatastores reads the capacity of the datastores with the rest api
+// of the vCenter, all datastores if names is empty.
+func VsphereDatastores(ctx context.Context, vcenterUrl string, username string, password string, names []string, insecure bool) ([]Datastore, error) {
+	log.Info("Reading the datastores of", vcenterUrl)
+
+	client := sharedClient(insecure)
+	base := strings.TrimSuffix(vcenterUrl, "/")
+
+	req, err := http.NewRequest("POST", base+"/rest/com/vmware/cis/session", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.SetBasicAuth(username, password)
+	var session struct {
+		Value string `json:"value"`
+	}
+	if err := vsphereDo(ctx, client, req, &session); err != nil {
+		return nil, fmt.Errorf("Could not log in to the vCenter %s: %v", vcenterUrl, err)
+	}
+	defer func() {
+		if req, err := http.NewRequest("DELETE", base+"/rest/com/vmware/cis/session", nil); err == nil {
+			req.Header.Set("vmware-api-session-id", session.Value)
+			vsphereDo(ctx, client, req, nil)
+		}
+	}()
+
+	req, err = http.NewRequest("GET", base+"/rest/vcenter/datastore", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("vmware-api-session-id", session.Value)
+	var list struct {
+		Value []Datastore `json:"value"`
+	}
+	if err := vsphereDo(ctx, client, req, &list); err != nil {
+		return nil, fmt.Errorf("Could not list the datastores of %s: %v", vcenterUrl, err)
+	}
+
+	if len(names) == 0 {
+		return list.Value, nil
+	}
+	var datastores []Datastore
+	for _, name := range names {
+		found := false
+		for _, ds := range list.Value {
+			if ds.Name == name {
+				datastores = append(datastores, ds)
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("Datastore %s not found on %s", name, vcenterUrl)
+		}
+	}
+	return datastores, nil
+}
+
+func vsphereDo(ctx context.Context, client *http.Client, req *http.Request, v interface{}) error {
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("status code %d", resp.StatusCode)
+	}
+	if v == nil {
+		return nil
+	}
+	return json.Unmarshal(body, v)
+}
+
+// CheckDatastoreCapacity fails if a datastore is used by percent or more.
+func CheckDatastoreCapacity(datastores []Datastore, percent int) error {
+	var full []string
+	for _, ds := range datastores {
+		if ds.Capacity == 0 {
+			continue
+		}
+		if used := int((ds.Capacity - ds.FreeSpace) * 100 / ds.Capacity); used >= percent {
+			full = append(full, fmt.Sprintf("%s %d%% (%d GiB free)", ds.Name, used, ds.FreeSpace>>30))
+		}
+	}
+	if len(full) > 0 {
+		sort.Strings(full)
+		return newMessage("datastores-full", percent, strings.Join(full, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+// Copyright © 2017 SBB Cloud Stack Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package probes
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// CheckWebconsole fetches the web console at consoleUrl through every router
+// (or over dns if no router ips are given) and checks that it returns the
+// page with all expected assets, each within maxLatency.
+func CheckWebconsole(ctx context.Context, consoleUrl string, routerIps []string, assets []string, maxLatency time.Duration) error {
+	log.Info("Checking web console", consoleUrl)
+
+	if len(consoleUrl) == 0 {
+		return errors.New("No web console url configured")
+	}
+	base, err := url.Parse(consoleUrl)
+	if err != nil {
+		return fmt.Errorf("Invalid web console url %s: %v", consoleUrl, err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 60*time.Second)
+	defer cancel()
+
+	if len(routerIps) == 0 {
+		routerIps = []string{""}
+	}
+
+	var failures []string
+	for _, ip := range routerIps {
+		client := webconsoleClient(ip)
+
+		via := ""
+		if len(ip) > 0 {
+			via = " over router " + ip
+		}
+
+		if err := fetchWebconsole(ctx, client, base, assets, maxLatency); err != nil {
+			failures = append(failures, err.Error()+via)
+		}
+	}
+
+	if len(failures) > 0 {
+		return newMessage("webconsole-unavailable", strings.Join(failures, ", "))
+	}
+	return nil
+}
+
+// returns a client which connects to the router ip instead of the resolved
+// host, so the host header and sni stay those of the console
+func webconsoleClient(routerIp string) *http.Client {
+	transport := &http.Transport{
+		TLSClientConfig:   &tls.Config{InsecureSkipVerify: true},
+		DisableKeepAlives: true,
+	}
+	if len(routerIp) > 0 {
+		dialer := &net.Dialer{Timeout: 10 * time.Second}
+		transport.DialContext = func(ctx context.Context, network string, addr string) (net.Conn, error) {
+			_, port, err := net.SplitHostPort(addr)
+			if err != nil {
+				return nil, err
+			}
+			return dialer.DialContext(ctx, network, net.JoinHostPort(routerIp, port))
+		}
+	}
+	return &http.Client{Transport: TraceTransport(transport)}
+}
+
+func fetchWebconsole(ctx context.Context, client *http.Client, base *url.URL, assets []string, maxLatency time.Duration) error {
+	body, err := fetchTimed(ctx, client, base.String(), maxLatency)
+	if err != nil {
+		return err
+	}
+
+	for _, asset := range assets {
+		if len(asset) == 0 {
+			continue
+		}
+		if !strings.Contains(body, asset) {
+			return fmt.Errorf("%s does not reference %s", base, asset)
+		}
+
+		ref, err := url.Parse(asset)
+		if err != nil {
+			return fmt.Errorf("Invalid asset %s: %v", asset, err)
+		}
+		if _, err := fetchTimed(ctx, client, base.ResolveReference(ref).String(), maxLatency); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// fetches url and fails if it does not return 200 within maxLatency
+func fetchTimed(ctx context.Context, client *http.Client, url string, maxLatency time.Duration) (string, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return "", err
+	}
+
+	start := time.Now()
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	took := time.Since(start)
+	if err != nil {
+		return "", err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s returned status code %d", url, resp.StatusCode)
+	}
+	if maxLatency > 0 && took > maxLatency {
+		return "", fmt.Errorf("%s took %dms", url, took/time.Millisecond)
+	}
+	return string(body), nil
+}